@@ -0,0 +1,48 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+)
+
+// HandleChain registers an ordered list of handlers for the given method and
+// pattern that are tried in turn until one of them responds, for example a
+// cache lookup followed by a handler that serves the file from disk.
+// A handler declines a request by returning without writing a body, calling
+// WriteHeader, or flushing the response, in which case the next handler is
+// called.
+// If none of the handlers respond, the NotFound handler is called.
+//
+// Headers set by a handler that declines are not removed, so they are sent
+// with the response of whichever handler responds.
+// A handler that only sets headers therefore never stops the chain, which can
+// be used to add headers to the responses of the handlers after it.
+// The request body is shared by every handler, so a handler that declines
+// should not read it.
+//
+// Options apply to the route as a whole.
+// If handlers is empty, the method or pattern is invalid, or a handler already
+// exists for the pattern, HandleChain panics.
+func HandleChain(method, pattern string, handlers []http.Handler, opts ...RouteOption) Option {
+	if len(handlers) == 0 {
+		panic(errors.New("mux: empty handler chain"))
+	}
+	handlers = append([]http.Handler(nil), handlers...)
+	// The route is validated now, but the NotFound handler is only known once
+	// the option is applied.
+	var m *ServeMux
+	register := Handle(method, pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, h := range handlers {
+			cw := &defCodeWriter{ResponseWriter: w, code: http.StatusOK}
+			h.ServeHTTP(cw, r)
+			if cw.wrote {
+				return
+			}
+		}
+		m.notFound.ServeHTTP(w, r)
+	}), opts...)
+	return func(mux *ServeMux) {
+		m = mux
+		register(mux)
+	}
+}
@@ -0,0 +1,104 @@
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var chainTests = [...]struct {
+	path   string
+	code   int
+	body   string
+	source string
+}{
+	0: {path: "/files/cached.txt", code: http.StatusOK, body: "from cache", source: "cache"},
+	1: {path: "/files/disk.txt", code: testCode, body: "from disk", source: "disk"},
+	2: {path: "/files/missing.txt", code: notFoundStatusCode},
+}
+
+func TestHandleChain(t *testing.T) {
+	var chain []string
+	m := mux.New(
+		mux.HandleChain(http.MethodGet, "/files/{p path}", []http.Handler{
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				chain = append(chain, "headers")
+				w.Header().Set("X-Chain", "1")
+			}),
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				chain = append(chain, "cache")
+				if mux.Param(r, "p").Raw == "cached.txt" {
+					w.Header().Set("Source", "cache")
+					w.Write([]byte("from cache"))
+				}
+			}),
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				chain = append(chain, "disk")
+				if mux.Param(r, "p").Raw == "disk.txt" {
+					w.Header().Set("Source", "disk")
+					w.WriteHeader(testCode)
+					w.Write([]byte("from disk"))
+				}
+			}),
+		}),
+		mux.NotFound(codeHandler(t, notFoundStatusCode)),
+	)
+	for i, tc := range chainTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			chain = nil
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+			if tc.body != "" && rec.Body.String() != tc.body {
+				t.Errorf("Unexpected body: want=%q, got=%q", tc.body, rec.Body.String())
+			}
+			if src := rec.Header().Get("Source"); src != tc.source {
+				t.Errorf("Unexpected source: want=%q, got=%q", tc.source, src)
+			}
+			if h := rec.Header().Get("X-Chain"); h != "1" {
+				t.Errorf("Expected headers from a declining handler to be kept, got %q", h)
+			}
+			if tc.source == "cache" && len(chain) != 2 {
+				t.Errorf("Expected the chain to stop at the first response, called %v", chain)
+			}
+		})
+	}
+}
+
+func TestHandleChainEmpty(t *testing.T) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatalf("Expected an empty chain to panic")
+		}
+		if _, ok := r.(*mux.PatternError); ok {
+			t.Errorf("Did not expect a pattern error for a valid pattern, got %v", r)
+		}
+	}()
+	mux.HandleChain(http.MethodGet, "/", nil)
+}
+
+func TestHandleChainInvalid(t *testing.T) {
+	chain := []http.Handler{http.NotFoundHandler()}
+	for i, tc := range [...]struct {
+		method  string
+		pattern string
+	}{
+		0: {method: "GET POST", pattern: "/"},
+		1: {method: http.MethodGet, pattern: "files"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("Expected HandleChain to panic before the option is applied")
+				}
+			}()
+			mux.HandleChain(tc.method, tc.pattern, chain)
+		})
+	}
+}
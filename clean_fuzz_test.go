@@ -0,0 +1,28 @@
+//go:build go1.18
+// +build go1.18
+
+package mux_test
+
+import (
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+func FuzzCleanPath(f *testing.F) {
+	for _, tc := range cleanPathTests {
+		f.Add(tc.path)
+	}
+	f.Fuzz(func(t *testing.T, p string) {
+		slow := mux.SlowCleanPath(p)
+		if mux.IsClean(p) && slow != p {
+			t.Errorf("Fast path accepted %q but path.Clean produced %q", p, slow)
+		}
+		if !mux.IsClean(slow) {
+			t.Errorf("Fast path rejected clean path %q", slow)
+		}
+		if got := mux.CleanPath(p); got != slow {
+			t.Errorf("Unexpected clean path for %q: want=%q, got=%q", p, slow, got)
+		}
+	})
+}
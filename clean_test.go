@@ -0,0 +1,55 @@
+package mux_test
+
+import (
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var cleanPathTests = [...]struct {
+	path  string
+	clean bool
+}{
+	0:  {path: "/", clean: true},
+	1:  {path: "/users/1/edit", clean: true},
+	2:  {path: "/users/1/edit/", clean: true},
+	3:  {path: "/a/.../b", clean: true},
+	4:  {path: "/a/.b/..c/", clean: true},
+	5:  {path: ""},
+	6:  {path: "users"},
+	7:  {path: "//users"},
+	8:  {path: "/users//1"},
+	9:  {path: "/users/./1"},
+	10: {path: "/users/../1"},
+	11: {path: "/users/."},
+	12: {path: "/users/.."},
+	13: {path: "/users/1//"},
+}
+
+func TestCleanPath(t *testing.T) {
+	for i, tc := range cleanPathTests {
+		if clean := mux.IsClean(tc.path); clean != tc.clean {
+			t.Errorf("%d: Unexpected result for %q: want=%t, got=%t", i, tc.path, tc.clean, clean)
+		}
+		if slow := mux.SlowCleanPath(tc.path); (slow == tc.path) != tc.clean {
+			t.Errorf("%d: Test case disagrees with the slow path for %q: got=%q", i, tc.path, slow)
+		}
+		if got, want := mux.CleanPath(tc.path), mux.SlowCleanPath(tc.path); got != want {
+			t.Errorf("%d: Unexpected clean path for %q: want=%q, got=%q", i, tc.path, want, got)
+		}
+	}
+}
+
+func BenchmarkCleanPath(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		mux.CleanPath("/api/v1/users/12345/settings/")
+	}
+}
+
+func BenchmarkSlowCleanPath(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		mux.SlowCleanPath("/api/v1/users/12345/settings/")
+	}
+}
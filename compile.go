@@ -0,0 +1,108 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrCompiled is returned when attempting to register a route on a
+// CompiledMux.
+var ErrCompiled = errors.New("mux: routes cannot be registered on a CompiledMux")
+
+// CompiledMux is an immutable snapshot of the routes registered on a ServeMux
+// that has been prepared for matching.
+// It routes requests in exactly the same way as the ServeMux did when it was
+// compiled, but it never observes routes that are registered on or removed
+// from the ServeMux afterwards.
+type CompiledMux struct {
+	mux  *ServeMux
+	tree *routeTree
+}
+
+// Compile freezes the routes that are currently registered on the ServeMux
+// into a CompiledMux.
+// Chains of static path components are merged, static children are indexed by
+// name, and the methods allowed for each route and the resulting Allow headers
+// are computed once instead of for each request.
+//
+// The ServeMux may still be modified after it has been compiled without
+// affecting the CompiledMux.
+// Compile returns an error if it is called before New has returned, for
+// example from within an Option.
+func (mux *ServeMux) Compile() (*CompiledMux, error) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	if !mux.published {
+		return nil, errors.New("mux: Compile called before New returned")
+	}
+	t := mux.current()
+	compact := t.compact.clone()
+	mux.precompute(compact)
+	return &CompiledMux{
+		mux:  mux,
+		tree: &routeTree{root: t.root, compact: compact},
+	}, nil
+}
+
+// precompute sets the allowed methods of n and every node below it.
+// n must not be shared with any published tree.
+func (mux *ServeMux) precompute(n *node) {
+	n.allow = mux.allowed(n)
+	if n.allow == nil {
+		n.allow = []string{}
+	}
+	n.allowValue = strings.Join(n.allow, ",")
+	if n.inner != nil {
+		n.inner = append([]node(nil), n.inner...)
+		for i := range n.inner {
+			mux.precompute(&n.inner[i])
+		}
+	}
+	// The slash and subtree nodes of a compacted node are shared with the tree
+	// it was compacted from.
+	if n.slash != nil {
+		slash := *n.slash
+		n.slash = &slash
+		mux.precompute(n.slash)
+	}
+	if n.subtree != nil {
+		subtree := *n.subtree
+		n.subtree = &subtree
+		mux.precompute(n.subtree)
+	}
+	for i := range n.child {
+		mux.precompute(&n.child[i])
+	}
+}
+
+// ServeHTTP dispatches the request to the handler whose pattern most closely
+// matches the request URL.
+func (c *CompiledMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mux.serve(c.tree, w, r)
+}
+
+// Handler returns the handler to use for the given request in the same way as
+// ServeMux.Handler.
+func (c *CompiledMux) Handler(r *http.Request) (http.Handler, *http.Request) {
+	res, newReq, _ := c.mux.handler(c.tree, r)
+	return res.Handler, newReq
+}
+
+// Lookup is like Handler except that it reports how the request was resolved.
+func (c *CompiledMux) Lookup(r *http.Request) (MatchResult, *http.Request) {
+	return c.mux.lookupTree(c.tree, r)
+}
+
+// Handle always returns ErrCompiled since the routes of a CompiledMux cannot
+// be changed.
+// Routes should be registered on the ServeMux and compiled again instead.
+func (c *CompiledMux) Handle(method, pattern string, h http.Handler, opts ...RouteOption) error {
+	return ErrCompiled
+}
+
+// HandleFunc always returns ErrCompiled since the routes of a CompiledMux
+// cannot be changed.
+func (c *CompiledMux) HandleFunc(method, pattern string, h http.HandlerFunc, opts ...RouteOption) error {
+	return ErrCompiled
+}
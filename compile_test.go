@@ -0,0 +1,156 @@
+package mux_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+// apiRoutes returns the options to register a realistic REST API with ten
+// routes for each of n resources.
+func apiRoutes(n int, h http.Handler) []mux.Option {
+	var opts []mux.Option
+	for i := 0; i < n; i++ {
+		res := "/api/v1/resource" + strconv.Itoa(i)
+		opts = append(opts,
+			mux.Handle(http.MethodGet, res, h),
+			mux.Handle(http.MethodPost, res, h),
+			mux.Handle(http.MethodGet, res+"/{id uint}", h),
+			mux.Handle(http.MethodPut, res+"/{id uint}", h),
+			mux.Handle(http.MethodPatch, res+"/{id uint}", h),
+			mux.Handle(http.MethodDelete, res+"/{id uint}", h),
+			mux.Handle(http.MethodGet, res+"/{id uint}/items", h),
+			mux.Handle(http.MethodPost, res+"/{id uint}/items", h),
+			mux.Handle(http.MethodGet, res+"/{id uint}/items/{item string}", h),
+			mux.Handle(http.MethodGet, res+"/{id uint}/files/{p path}", h),
+		)
+	}
+	return opts
+}
+
+var compiledTests = [...]struct {
+	method string
+	path   string
+}{
+	0:  {method: http.MethodGet, path: "/api/v1/resource1"},
+	1:  {method: http.MethodPost, path: "/api/v1/resource2/12/items"},
+	2:  {method: http.MethodGet, path: "/api/v1/resource3/12/items/abc"},
+	3:  {method: http.MethodGet, path: "/api/v1/resource4/12/files/a/b/c"},
+	4:  {method: http.MethodPost, path: "/api/v1/resource5/12"},
+	5:  {method: http.MethodOptions, path: "/api/v1/resource6/12"},
+	6:  {method: http.MethodGet, path: "/api/v1/resource7/abc"},
+	7:  {method: http.MethodGet, path: "/api/v1/nope"},
+	8:  {method: http.MethodGet, path: "/api/v1"},
+	9:  {method: http.MethodGet, path: "/API/v1/resource1"},
+	10: {method: http.MethodGet, path: "/api/v1/resource1/"},
+	11: {method: http.MethodGet, path: "/api/../api/v1/resource1"},
+	12: {method: http.MethodHead, path: "/api/v1/resource8"},
+}
+
+func TestCompile(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := mux.Path(r)
+		fmt.Fprintf(w, "%s %s", mux.Pattern(r), p)
+	})
+	opts := append(apiRoutes(10, h),
+		mux.RedirectFixedPath(http.StatusMovedPermanently),
+		mux.RedirectTrailingSlash(http.StatusMovedPermanently),
+		mux.AutoHead(true),
+	)
+	m := mux.New(opts...)
+	c, err := m.Compile()
+	if err != nil {
+		t.Fatalf("Unexpected error compiling: %v", err)
+	}
+	// Routes registered after compiling must not be visible.
+	m.Handle(http.MethodGet, "/api/v1/nope", h)
+
+	for i, tc := range compiledTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			want := httptest.NewRecorder()
+			ref := mux.New(opts...)
+			ref.ServeHTTP(want, httptest.NewRequest(tc.method, tc.path, nil))
+			got := httptest.NewRecorder()
+			c.ServeHTTP(got, httptest.NewRequest(tc.method, tc.path, nil))
+
+			if got.Code != want.Code {
+				t.Errorf("Unexpected code: want=%d, got=%d", want.Code, got.Code)
+			}
+			if body := got.Body.String(); body != want.Body.String() {
+				t.Errorf("Unexpected body: want=%q, got=%q", want.Body.String(), body)
+			}
+			for _, header := range []string{"Allow", "Location", "Content-Type"} {
+				if v := got.Header().Get(header); v != want.Header().Get(header) {
+					t.Errorf("Unexpected %s header: want=%q, got=%q", header, want.Header().Get(header), v)
+				}
+			}
+
+			wantRes, _ := ref.Lookup(httptest.NewRequest(tc.method, tc.path, nil))
+			gotRes, _ := c.Lookup(httptest.NewRequest(tc.method, tc.path, nil))
+			if gotRes.Kind != wantRes.Kind || gotRes.Pattern != wantRes.Pattern || len(gotRes.Params) != len(wantRes.Params) {
+				t.Errorf("Unexpected result: want=%+v, got=%+v", wantRes, gotRes)
+			}
+		})
+	}
+}
+
+func TestCompiledHandle(t *testing.T) {
+	c, err := mux.New().Compile()
+	if err != nil {
+		t.Fatalf("Unexpected error compiling: %v", err)
+	}
+	if err := c.Handle(http.MethodGet, "/", failHandler(t)); !errors.Is(err, mux.ErrCompiled) {
+		t.Errorf("Unexpected error from Handle: want=%v, got=%v", mux.ErrCompiled, err)
+	}
+	if err := c.HandleFunc(http.MethodGet, "/", failHandler(t)); !errors.Is(err, mux.ErrCompiled) {
+		t.Errorf("Unexpected error from HandleFunc: want=%v, got=%v", mux.ErrCompiled, err)
+	}
+}
+
+func TestCompileBeforeNew(t *testing.T) {
+	var err error
+	mux.New(func(m *mux.ServeMux) {
+		_, err = m.Compile()
+	})
+	if err == nil {
+		t.Errorf("Expected an error when compiling before New returns")
+	}
+}
+
+func benchmarkAPI(b *testing.B, h http.Handler) {
+	paths := []string{
+		"/api/v1/resource0",
+		"/api/v1/resource123/45",
+		"/api/v1/resource250/45/items",
+		"/api/v1/resource375/45/items/abc",
+		"/api/v1/resource499/45/files/a/b.png",
+		"/api/v1/resource500",
+	}
+	reqs := make([]*http.Request, len(paths))
+	for i, p := range paths {
+		reqs[i] = httptest.NewRequest(http.MethodGet, p, nil)
+	}
+	w := httptest.NewRecorder()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(w, reqs[i%len(reqs)])
+	}
+}
+
+func BenchmarkServeMux500(b *testing.B) {
+	benchmarkAPI(b, mux.New(apiRoutes(50, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))...))
+}
+
+func BenchmarkCompiledMux500(b *testing.B) {
+	c, err := mux.New(apiRoutes(50, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))...).Compile()
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkAPI(b, c)
+}
@@ -0,0 +1,165 @@
+package mux
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Registry holds named handlers that routes loaded using FromConfig may
+// refer to.
+// The zero value is an empty registry ready to use.
+type Registry struct {
+	handlers map[string]http.Handler
+}
+
+// Add makes h available to routes loaded using FromConfig under the given
+// name.
+// If a handler has already been added with the same name, Add panics.
+func (reg *Registry) Add(name string, h http.Handler) {
+	if _, ok := reg.handlers[name]; ok {
+		panic(fmt.Sprintf("mux: handler %q already added to registry", name))
+	}
+	if reg.handlers == nil {
+		reg.handlers = make(map[string]http.Handler)
+	}
+	reg.handlers[name] = h
+}
+
+// ConfigError is returned by FromConfig when an entry in the configuration
+// cannot be registered.
+type ConfigError struct {
+	// Entry is the index of the offending entry, starting at 0.
+	Entry int
+	// Line is the line of the configuration on which the entry starts,
+	// starting at 1.
+	Line int
+	Err  error
+}
+
+// Error satisfies the error interface for ConfigError.
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("mux: config entry %d on line %d: %v", e.Entry, e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// configEntry is a single route in the configuration read by FromConfig.
+type configEntry struct {
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+	Handler string `json:"handler"`
+	Name    string `json:"name"`
+}
+
+// FromConfig reads a JSON array of routes from r and returns the options that
+// register them using the handlers in reg.
+// Each route is an object with the fields "method", "pattern", and "handler",
+// which is the name that the handler was added to reg with, and optionally
+// "name", which is used with the Name option.
+// For example:
+//
+//	[
+//		{"method": "GET", "pattern": "/users/{id uint}", "handler": "users.show", "name": "user"}
+//	]
+//
+// Configuration is checked before any options are returned: routes with
+// unknown fields, invalid methods or patterns, unknown handlers, or that
+// conflict with or duplicate another route in the configuration result in a
+// *ConfigError that identifies the entry instead of a panic.
+// Routes that conflict with routes registered by other options still cause
+// New to panic.
+func FromConfig(r io.Reader, reg *Registry) ([]Option, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, errors.New("mux: config must be a JSON array of routes")
+	}
+
+	// Routes are registered on a scratch tree so that conflicts are reported
+	// using the same rules that New applies.
+	trial := &node{name: "/", typ: typStatic}
+	var opts []Option
+	for i := 0; dec.More(); i++ {
+		line := lineAt(data, int(dec.InputOffset()))
+		var entry configEntry
+		if err := dec.Decode(&entry); err != nil {
+			return nil, &ConfigError{Entry: i, Line: line, Err: err}
+		}
+		opt, err := entry.option(trial, reg)
+		if err != nil {
+			return nil, &ConfigError{Entry: i, Line: line, Err: err}
+		}
+		opts = append(opts, opt)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// option returns the option that registers the route described by entry after
+// registering it on trial.
+func (entry configEntry) option(trial *node, reg *Registry) (opt Option, err error) {
+	if !validMethod(entry.Method) {
+		return nil, &MethodError{Method: entry.Method, Pattern: entry.Pattern}
+	}
+	if err := ValidatePattern(entry.Pattern); err != nil {
+		return nil, err
+	}
+	h, ok := reg.handlers[entry.Handler]
+	if !ok {
+		return nil, fmt.Errorf("unknown handler %q", entry.Handler)
+	}
+	var opts []RouteOption
+	if entry.Name != "" {
+		opts = append(opts, Name(entry.Name))
+	}
+
+	method := strings.ToUpper(entry.Method)
+	r := entry.Pattern[1:]
+	ep := &endpoint{
+		handler:  h,
+		route:    r,
+		segments: parsePattern(r),
+	}
+	for _, o := range opts {
+		o(ep)
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			e, ok := v.(error)
+			if !ok {
+				panic(v)
+			}
+			opt, err = nil, e
+		}
+	}()
+	register(trial, []string{method}, r, ep)
+	return Handle(method, entry.Pattern, h, opts...), nil
+}
+
+// lineAt returns the line of data that the first token at or after offset is
+// on.
+func lineAt(data []byte, offset int) int {
+	for offset < len(data) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',':
+			offset++
+			continue
+		}
+		break
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
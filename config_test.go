@@ -0,0 +1,124 @@
+package mux_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+func testRegistry(t *testing.T) *mux.Registry {
+	reg := &mux.Registry{}
+	reg.Add("users.show", codeHandler(t, testCode))
+	reg.Add("users.list", codeHandler(t, testStatusCode))
+	return reg
+}
+
+func TestFromConfig(t *testing.T) {
+	const config = `[
+	{"method": "GET", "pattern": "/users/{id uint}", "handler": "users.show", "name": "user"},
+	{"method": "get", "pattern": "/users", "handler": "users.list"}
+]`
+	opts, err := mux.FromConfig(strings.NewReader(config), testRegistry(t))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	m := mux.New(opts...)
+	for path, code := range map[string]int{
+		"/users/1": testCode,
+		"/users":   testStatusCode,
+	} {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != code {
+			t.Errorf("Unexpected code for %s: want=%d, got=%d", path, code, rec.Code)
+		}
+	}
+	if p, err := m.PathFor("user", "id", 1); err != nil || p != "/users/1" {
+		t.Errorf("Expected the route to be named, got %q, %v", p, err)
+	}
+}
+
+var configErrorTests = [...]struct {
+	config string
+	entry  int
+	line   int
+	want   error
+}{
+	0: {
+		config: `[
+	{"method": "GET", "pattern": "/users", "handler": "users.list"},
+	{"method": "GET", "pattern": "/users/{id uint}", "handler": "users.missing"}
+]`,
+		entry: 1, line: 3,
+	},
+	1: {
+		config: `[{"method": "GET", "pattern": "/users/{id bool}", "handler": "users.show"}]`,
+		entry:  0, line: 1,
+		want: &mux.PatternError{Pattern: "/users/{id bool}", Reason: `invalid type "bool"`},
+	},
+	2: {
+		config: `[
+	{"method": "GET", "pattern": "/users", "handler": "users.list"},
+
+	{"method": "GET", "pattern": "/users", "handler": "users.show"}
+]`,
+		entry: 1, line: 4,
+		want: &mux.DuplicateError{Method: http.MethodGet, Pattern: "/users", Existing: "/users"},
+	},
+	3: {
+		config: `[
+	{"method": "GET", "pattern": "/users/{id uint}", "handler": "users.show"},
+	{"method": "GET", "pattern": "/users/me", "handler": "users.show"}
+]`,
+		entry: 1, line: 3,
+		want: &mux.ConflictError{New: "/users/me", Existing: "/users/{id uint}", Routes: []string{"GET /users/{id uint}"}},
+	},
+	4: {
+		config: `[{"method": "GET POST", "pattern": "/users", "handler": "users.list"}]`,
+		entry:  0, line: 1,
+		want: &mux.MethodError{Method: "GET POST", Pattern: "/users"},
+	},
+	5: {
+		config: `[{"method": "GET", "pattern": "/users", "handler": "users.list", "auth": "none"}]`,
+		entry:  0, line: 1,
+	},
+	6: {
+		config: `[
+	{"method": "GET", "pattern": "/a", "handler": "users.list", "name": "list"},
+	{"method": "GET", "pattern": "/b", "handler": "users.list", "name": "list"}
+]`,
+		entry: 1, line: 3,
+		want: &mux.NameError{Name: "list", Pattern: "/b", Existing: "/a"},
+	},
+}
+
+func TestFromConfigErrors(t *testing.T) {
+	for i, tc := range configErrorTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			_, err := mux.FromConfig(strings.NewReader(tc.config), testRegistry(t))
+			var configErr *mux.ConfigError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("Expected a ConfigError, got %v", err)
+			}
+			if configErr.Entry != tc.entry || configErr.Line != tc.line {
+				t.Errorf("Unexpected location: want=%d:%d, got=%d:%d", tc.entry, tc.line, configErr.Entry, configErr.Line)
+			}
+			if tc.want != nil && configErr.Err.Error() != tc.want.Error() {
+				t.Errorf("Unexpected error: want=%v, got=%v", tc.want, configErr.Err)
+			}
+		})
+	}
+}
+
+func TestFromConfigInvalid(t *testing.T) {
+	for _, config := range []string{`{}`, `[{"method": "GET"`, ``} {
+		if _, err := mux.FromConfig(strings.NewReader(config), testRegistry(t)); err == nil {
+			t.Errorf("Expected an error for config %q", config)
+		}
+	}
+}
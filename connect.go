@@ -0,0 +1,162 @@
+package mux
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// connectRoute is a route used to match CONNECT requests against the host
+// (authority) of the request instead of the path.
+type connectRoute struct {
+	pattern string
+	// key is the pattern with static labels lowercased and parameters replaced
+	// by their types so that patterns that match the same hosts are equal.
+	key    string
+	labels []node
+	// port is nil if any port is allowed.
+	port *node
+	ep   *endpoint
+}
+
+// HandleConnect registers the handler for CONNECT requests whose host matches
+// the given pattern.
+// If a handler already exists for a pattern that matches the same hosts, such
+// as one that only differs in the case of its static labels or the names of its
+// parameters, HandleConnect panics.
+// It is safe to use after New returns while the ServeMux is serving requests.
+//
+// Host patterns are made up of dot separated labels, each of which may be a
+// route parameter that matches a single label, and an optional port which may
+// also be a route parameter:
+//
+//	{sub string}.example.net:{port uint}
+//
+// Static labels are compared case insensitively.
+// If the pattern does not have a port, a request with any port matches.
+// Parameters of type path are not allowed in host patterns.
+// Routes are tried in the order they were registered.
+//
+// Once a CONNECT route has been registered, all CONNECT requests are matched
+// against the host patterns and are never matched against routes registered
+// for the CONNECT method using Handle.
+func HandleConnect(hostPattern string, h http.Handler) Option {
+	route := connectRoute{
+		pattern: hostPattern,
+		ep:      &endpoint{handler: h, host: hostPattern},
+	}
+	host, port := hostPattern, ""
+	if idx := strings.LastIndexByte(hostPattern, ':'); idx > strings.LastIndexByte(hostPattern, ']') {
+		host, port = hostPattern[:idx], hostPattern[idx+1:]
+		if port == "" {
+			panic(&PatternError{Pattern: hostPattern, Reason: "empty port in host pattern"})
+		}
+		route.port = connectNode(hostPattern, port)
+	}
+	var key strings.Builder
+	for i, label := range strings.Split(host, ".") {
+		n := connectNode(hostPattern, label)
+		route.labels = append(route.labels, *n)
+		if i > 0 {
+			key.WriteByte('.')
+		}
+		key.WriteString(n.connectKey())
+	}
+	if route.port != nil {
+		key.WriteString(":" + route.port.connectKey())
+	}
+	route.key = key.String()
+
+	return func(mux *ServeMux) {
+		mux.mu.Lock()
+		defer mux.mu.Unlock()
+		existing := mux.connectRoutes()
+		for _, c := range existing {
+			if c.key == route.key {
+				panic(&DuplicateError{Method: http.MethodConnect, Pattern: hostPattern, Existing: c.pattern})
+			}
+		}
+		// Requests may be matched against the published routes concurrently, so
+		// they are copied instead of appended to.
+		routes := make([]connectRoute, 0, len(existing)+1)
+		routes = append(routes, existing...)
+		mux.connect.Store(append(routes, route))
+	}
+}
+
+// connectRoutes returns the currently published CONNECT routes.
+func (mux *ServeMux) connectRoutes() []connectRoute {
+	routes, _ := mux.connect.Load().([]connectRoute)
+	return routes
+}
+
+// connectKey returns the form of the host pattern label n used to compare
+// patterns.
+func (n *node) connectKey() string {
+	if n.typ == typStatic {
+		return strings.ToLower(n.name)
+	}
+	return "{" + n.typ + "}"
+}
+
+func connectNode(pattern, part string) *node {
+	if part == "" {
+		panic(&PatternError{Pattern: pattern, Reason: "empty label in host pattern"})
+	}
+	name, typ := parseParam(part)
+	if typ == typWild {
+		panic(&PatternError{Pattern: pattern, Reason: "parameters of type path are not allowed in host patterns"})
+	}
+	return &node{name: name, typ: typ}
+}
+
+// connectHandler resolves a CONNECT request by matching its host against the
+// CONNECT routes.
+func (mux *ServeMux) connectHandler(routes []connectRoute, r *http.Request) (MatchResult, *http.Request) {
+	host, port, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host, port = r.Host, ""
+	}
+	labels := strings.Split(host, ".")
+
+routeloop:
+	for _, c := range routes {
+		if len(c.labels) != len(labels) {
+			continue
+		}
+		var params []ParamInfo
+		for i, label := range labels {
+			n := &c.labels[i]
+			if n.typ == typStatic {
+				if !strings.EqualFold(n.name, label) {
+					continue routeloop
+				}
+				continue
+			}
+			remain, pinfo, ok := n.match(label, uint(i), false)
+			if !ok || remain != "" {
+				continue routeloop
+			}
+			if pinfo.Name != "" {
+				params = append(params, pinfo)
+			}
+		}
+		if c.port != nil {
+			remain, pinfo, ok := c.port.match(port, uint(len(labels)), false)
+			if !ok || remain != "" {
+				continue
+			}
+			if pinfo.Name != "" {
+				params = append(params, pinfo)
+			}
+		}
+		return MatchResult{
+			Kind:    KindMatched,
+			Handler: c.ep.handler,
+			Pattern: c.pattern,
+			Params:  params,
+			Allowed: []string{http.MethodConnect},
+		}, withRoute(r, &routeContext{ep: c.ep, params: params})
+	}
+	return MatchResult{Kind: KindNotFound, Handler: mux.notFound}, r
+}
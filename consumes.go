@@ -0,0 +1,52 @@
+package mux
+
+import (
+	"mime"
+	"net/http"
+)
+
+// Consumes registers the route for requests with a body of the given media
+// type (for example "application/json").
+// The same method and pattern may be registered several times as long as each
+// registration consumes a distinct media type, and the handler is selected
+// using the Content-Type header of the request, ignoring any parameters such as
+// charset.
+// A route registered without Consumes handles every media type and may not be
+// registered alongside routes that use Consumes for the same method and
+// pattern.
+//
+// If the media type is invalid, Consumes panics.
+func Consumes(mediaType string) RouteOption {
+	typ, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		panic(err)
+	}
+	return func(ep *endpoint) {
+		ep.consumes = typ
+	}
+}
+
+// UnsupportedMediaType sets the handler to call when a request matches a route
+// that was registered using Consumes, but not for the media type of the
+// request body.
+//
+// By default, http.Error with http.StatusUnsupportedMediaType is used.
+func UnsupportedMediaType(h http.Handler) Option {
+	return func(mux *ServeMux) {
+		mux.unsupported = h
+	}
+}
+
+// mediaType returns the media type of the body of r, or the empty string if it
+// does not have a valid Content-Type header.
+func mediaType(r *http.Request) string {
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return ""
+	}
+	typ, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return ""
+	}
+	return typ
+}
@@ -0,0 +1,89 @@
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var consumesTests = [...]struct {
+	method      string
+	contentType string
+	version     string
+	code        int
+}{
+	0: {method: http.MethodPost, contentType: "application/json", code: 201},
+	1: {method: http.MethodPost, contentType: "Application/JSON; charset=utf-8", code: 201},
+	2: {method: http.MethodPost, contentType: "application/x-ndjson", code: 202},
+	3: {method: http.MethodPost, contentType: "text/plain", code: http.StatusUnsupportedMediaType},
+	4: {method: http.MethodPost, code: http.StatusUnsupportedMediaType},
+	5: {method: http.MethodPut, contentType: "text/plain", code: 203},
+	6: {method: http.MethodPatch, contentType: "application/json", version: "2", code: 204},
+	7: {method: http.MethodPatch, contentType: "application/json", version: "1", code: 205},
+	8: {method: http.MethodPatch, contentType: "text/plain", version: "1", code: 205},
+	9: {method: http.MethodPatch, contentType: "text/plain", version: "2", code: http.StatusUnsupportedMediaType},
+}
+
+func TestConsumes(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodPost, "/ingest", codeHandler(t, 201), mux.Consumes("application/json")),
+		mux.Handle(http.MethodPost, "/ingest", codeHandler(t, 202), mux.Consumes("application/x-ndjson")),
+		mux.Handle(http.MethodPut, "/ingest", codeHandler(t, 203)),
+		mux.Handle(http.MethodPatch, "/ingest", codeHandler(t, 204), mux.Version("2"), mux.Consumes("application/json")),
+		mux.Handle(http.MethodPatch, "/ingest", codeHandler(t, 205), mux.Version("1")),
+	)
+	for i, tc := range consumesTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/ingest", nil)
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			if tc.version != "" {
+				req.Header.Set("Accept-Version", tc.version)
+			}
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, req)
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+		})
+	}
+}
+
+func TestConsumesConflict(t *testing.T) {
+	for i, types := range [][2]string{{"application/json", "application/json"}, {"", "application/json"}, {"application/json", ""}} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("Expected registering media types %q to panic", types)
+				}
+			}()
+			var opts []mux.Option
+			for _, typ := range types {
+				var routeOpts []mux.RouteOption
+				if typ != "" {
+					routeOpts = append(routeOpts, mux.Consumes(typ))
+				}
+				opts = append(opts, mux.Handle(http.MethodPost, "/ingest", failHandler(t), routeOpts...))
+			}
+			mux.New(opts...)
+		})
+	}
+}
+
+func TestUnsupportedMediaType(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodPost, "/ingest", failHandler(t), mux.Consumes("application/json")),
+		mux.UnsupportedMediaType(codeHandler(t, testCode)),
+	)
+	req := httptest.NewRequest(http.MethodPost, "/ingest", nil)
+	req.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	if rec.Code != testCode {
+		t.Errorf("Unexpected status code: want=%d, got=%d", testCode, rec.Code)
+	}
+}
@@ -0,0 +1,167 @@
+package mux
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// debugTable is the response written by the handler returned from
+// DebugHandler.
+type debugTable struct {
+	Config debugConfig  `json:"config"`
+	Routes []debugRoute `json:"routes"`
+}
+
+// debugConfig describes the handlers that are used when no route handles a
+// request.
+// Each handler is described as "default", "custom", or "disabled".
+type debugConfig struct {
+	NotFound         string `json:"notFound"`
+	MethodNotAllowed string `json:"methodNotAllowed"`
+	Options          string `json:"options"`
+}
+
+// debugRoute describes every route registered for a single pattern.
+type debugRoute struct {
+	Pattern  string       `json:"pattern"`
+	Methods  []string     `json:"methods"`
+	Params   []debugParam `json:"params,omitempty"`
+	Wildcard bool         `json:"wildcard"`
+	Subtree  bool         `json:"subtree,omitempty"`
+	// Meta holds the metadata attached to the route for each method that has
+	// any.
+	Meta map[string]map[string]interface{} `json:"meta,omitempty"`
+}
+
+type debugParam struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// DebugHandler returns a handler that describes the routes registered on mux
+// for debugging, for example when mounted at /debug/routes.
+// The response lists each pattern in the same order as Routes along with the
+// methods registered for it, the names and types of its parameters, whether it
+// ends in a wildcard, and any metadata attached using Meta, as well as whether
+// custom NotFound, MethodNotAllowed, and OPTIONS handlers are installed.
+//
+// The response is JSON unless the Accept header of the request prefers
+// text/html, in which case it is an HTML table.
+// Metadata values that cannot be encoded as JSON are formatted using fmt.
+//
+// The route table may reveal details of the application, so the handler should
+// be protected by authentication.
+func DebugHandler(mux *ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		table := mux.debugTable()
+		accept := r.Header.Get("Accept")
+		if accept != "" && quality(accept, "text/html") > quality(accept, "application/json") {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			renderDebugHTML(w, table)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		enc.Encode(table)
+	})
+}
+
+// debugTable builds the route table using the public route enumeration API.
+func (mux *ServeMux) debugTable() debugTable {
+	table := debugTable{
+		Config: debugConfig{
+			NotFound:         handlerState(true, mux.customNotFound),
+			MethodNotAllowed: handlerState(mux.methodNotAllowed != nil, mux.customMethodNotAllowed),
+			Options:          handlerState(mux.options != nil, mux.customOptions),
+		},
+		Routes: []debugRoute{},
+	}
+	for _, info := range mux.Routes() {
+		n := len(table.Routes)
+		if n == 0 || table.Routes[n-1].Pattern != info.Pattern || table.Routes[n-1].Subtree != info.Subtree {
+			route := debugRoute{
+				Pattern:  info.Pattern,
+				Wildcard: info.HasWildcard,
+				Subtree:  info.Subtree,
+			}
+			for i, name := range info.ParamNames {
+				route.Params = append(route.Params, debugParam{Name: name, Type: info.ParamTypes[i]})
+			}
+			table.Routes = append(table.Routes, route)
+			n++
+		}
+		route := &table.Routes[n-1]
+		// Routes registered for several versions or media types share a method.
+		if m := len(route.Methods); m == 0 || route.Methods[m-1] != info.Method {
+			route.Methods = append(route.Methods, info.Method)
+		}
+		if len(info.Meta) > 0 {
+			if route.Meta == nil {
+				route.Meta = make(map[string]map[string]interface{})
+			}
+			route.Meta[info.Method] = debugMeta(info.Meta)
+		}
+	}
+	return table
+}
+
+// handlerState describes a handler as "default", "custom", or "disabled".
+func handlerState(enabled, custom bool) string {
+	switch {
+	case !enabled:
+		return "disabled"
+	case custom:
+		return "custom"
+	}
+	return "default"
+}
+
+// debugMeta returns a copy of meta in which values that cannot be encoded as
+// JSON are replaced by their default format.
+func debugMeta(meta map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(meta))
+	for k, v := range meta {
+		if _, err := json.Marshal(v); err != nil {
+			v = fmt.Sprint(v)
+		}
+		c[k] = v
+	}
+	return c
+}
+
+func renderDebugHTML(w io.Writer, table debugTable) {
+	io.WriteString(w, "<!DOCTYPE html>\n<html><head><title>Routes</title></head>\n<body>\n")
+	fmt.Fprintf(w, "<p>NotFound: %s, MethodNotAllowed: %s, OPTIONS: %s</p>\n",
+		table.Config.NotFound, table.Config.MethodNotAllowed, table.Config.Options)
+	io.WriteString(w, "<table>\n<tr><th>Pattern</th><th>Methods</th><th>Parameters</th><th>Wildcard</th><th>Metadata</th></tr>\n")
+	for _, route := range table.Routes {
+		var params []string
+		for _, p := range route.Params {
+			params = append(params, p.Name+" "+p.Type)
+		}
+		var meta []string
+		for method, m := range route.Meta {
+			b, _ := json.Marshal(m)
+			meta = append(meta, method+" "+string(b))
+		}
+		sort.Strings(meta)
+		pattern := route.Pattern
+		if route.Subtree {
+			pattern += "…"
+		}
+		fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%t</td><td>%s</td></tr>\n",
+			html.EscapeString(pattern),
+			html.EscapeString(strings.Join(route.Methods, ", ")),
+			html.EscapeString(strings.Join(params, ", ")),
+			route.Wildcard,
+			html.EscapeString(strings.Join(meta, "; ")),
+		)
+	}
+	io.WriteString(w, "</table>\n</body></html>\n")
+}
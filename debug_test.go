@@ -0,0 +1,127 @@
+package mux_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+type debugResponse struct {
+	Config map[string]string `json:"config"`
+	Routes []struct {
+		Pattern string   `json:"pattern"`
+		Methods []string `json:"methods"`
+		Params  []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"params"`
+		Wildcard bool                              `json:"wildcard"`
+		Meta     map[string]map[string]interface{} `json:"meta"`
+	} `json:"routes"`
+}
+
+func TestDebugHandler(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodPost, "/user/{id uint}", failHandler(t)),
+		mux.Handle(http.MethodGet, "/user/{id uint}", failHandler(t), mux.Meta("scope", "users:read"), mux.Meta("ch", make(chan int))),
+		mux.Handle(http.MethodGet, "/files/{p path}", failHandler(t)),
+		mux.Handle(http.MethodGet, "/", failHandler(t)),
+		mux.NotFound(failHandler(t)),
+		mux.MethodNotAllowed(nil),
+	)
+
+	rec := httptest.NewRecorder()
+	mux.DebugHandler(m).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/routes", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Unexpected content type: want=%q, got=%q", "application/json", ct)
+	}
+	var resp debugResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+
+	wantConfig := map[string]string{
+		"notFound":         "custom",
+		"methodNotAllowed": "disabled",
+		"options":          "default",
+	}
+	if !reflect.DeepEqual(resp.Config, wantConfig) {
+		t.Errorf("Unexpected config: want=%v, got=%v", wantConfig, resp.Config)
+	}
+
+	var patterns []string
+	for _, route := range resp.Routes {
+		patterns = append(patterns, route.Pattern)
+	}
+	wantPatterns := []string{"/", "/files/{p path}", "/user/{id uint}"}
+	if !reflect.DeepEqual(patterns, wantPatterns) {
+		t.Fatalf("Unexpected patterns: want=%v, got=%v", wantPatterns, patterns)
+	}
+
+	files := resp.Routes[1]
+	if !files.Wildcard || len(files.Params) != 1 || files.Params[0].Name != "p" || files.Params[0].Type != "path" {
+		t.Errorf("Unexpected wildcard route: %+v", files)
+	}
+	user := resp.Routes[2]
+	if want := []string{http.MethodGet, http.MethodPost}; !reflect.DeepEqual(user.Methods, want) {
+		t.Errorf("Unexpected methods: want=%v, got=%v", want, user.Methods)
+	}
+	if user.Wildcard || len(user.Params) != 1 || user.Params[0].Name != "id" || user.Params[0].Type != "uint" {
+		t.Errorf("Unexpected params: %+v", user.Params)
+	}
+	meta := user.Meta[http.MethodGet]
+	if meta["scope"] != "users:read" {
+		t.Errorf("Unexpected scope metadata: want=%q, got=%v", "users:read", meta["scope"])
+	}
+	if s, ok := meta["ch"].(string); !ok || !strings.HasPrefix(s, "0x") {
+		t.Errorf("Expected unencodable metadata to be formatted as a string, got %v", meta["ch"])
+	}
+	if _, ok := user.Meta[http.MethodPost]; ok {
+		t.Errorf("Did not expect metadata for a route without any")
+	}
+}
+
+func TestDebugHandlerDefaults(t *testing.T) {
+	rec := httptest.NewRecorder()
+	mux.DebugHandler(mux.New()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	var resp debugResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	wantConfig := map[string]string{
+		"notFound":         "default",
+		"methodNotAllowed": "default",
+		"options":          "default",
+	}
+	if !reflect.DeepEqual(resp.Config, wantConfig) {
+		t.Errorf("Unexpected config: want=%v, got=%v", wantConfig, resp.Config)
+	}
+	if resp.Routes == nil || len(resp.Routes) != 0 {
+		t.Errorf("Expected an empty list of routes, got %v", resp.Routes)
+	}
+}
+
+func TestDebugHandlerHTML(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/user/{name string}", failHandler(t), mux.Meta("summary", "<b>")),
+	)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	rec := httptest.NewRecorder()
+	mux.DebugHandler(m).ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Unexpected content type: want=text/html, got=%q", ct)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "<td>/user/{name string}</td>") {
+		t.Errorf("Expected the pattern in the HTML table, got %q", body)
+	}
+	if strings.Contains(body, "<b>") {
+		t.Errorf("Expected metadata to be escaped, got %q", body)
+	}
+}
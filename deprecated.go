@@ -0,0 +1,128 @@
+package mux
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// deprecation is the configuration set using Deprecated.
+type deprecation struct {
+	sunset time.Time
+	// successor is an endpoint for the successor pattern that is only used to
+	// render its path, or nil if there is no successor.
+	successor *endpoint
+}
+
+// Deprecated marks a route as deprecated.
+// Every response from the route has the Deprecation header set to "true"
+// (draft-ietf-httpapi-deprecation-header), the Sunset header set to sunset
+// (RFC 8594) unless it is the zero time, and, if successorPattern is not
+// empty, a Link header pointing to the successor-version of the route.
+// The headers are set before the handler is called so that it can still
+// change them.
+//
+// The successor link is rendered from successorPattern using the values of
+// the parameters of the same name that were matched by the request, for
+// example a route /v1/user/{id uint} might have the successor
+// /v2/users/{id uint}.
+// If successorPattern is invalid or has a parameter that the route does not
+// have, registering the route panics.
+// If a value cannot be rendered as the type of its parameter in
+// successorPattern, the Link header is omitted.
+//
+// Deprecated routes are marked as Deprecated in the output of Routes and
+// OpenAPIPaths.
+func Deprecated(sunset time.Time, successorPattern string) RouteOption {
+	var successor *endpoint
+	if successorPattern != "" {
+		if err := ValidatePattern(successorPattern); err != nil {
+			panic(err)
+		}
+		route := successorPattern[1:]
+		successor = &endpoint{route: route, segments: parsePattern(route)}
+	}
+	return func(ep *endpoint) {
+		if successor != nil {
+			for _, name := range successor.paramNames() {
+				if !hasName(ep.paramNames(), name) {
+					panic(&PatternError{Pattern: "/" + ep.route, Reason: "no parameter named " + strconv.Quote(name) + " for successor " + successorPattern})
+				}
+			}
+		}
+		ep.deprecation = &deprecation{sunset: sunset, successor: successor}
+	}
+}
+
+// paramNames returns the names of the named parameters of ep, including its
+// format extension parameter if it has one.
+func (ep *endpoint) paramNames() []string {
+	var names []string
+	for _, seg := range ep.segments {
+		if seg.typ != typStatic && seg.name != "" {
+			names = append(names, seg.name)
+		}
+	}
+	if _, ext := trimExt(ep.route); ext != "" {
+		names = append(names, ext)
+	}
+	return names
+}
+
+// hasParams reports whether ep has any named parameters, including a format
+// extension parameter.
+// Unlike paramNames it does not allocate.
+func (ep *endpoint) hasParams() bool {
+	for _, seg := range ep.segments {
+		if seg.typ != typStatic && seg.name != "" {
+			return true
+		}
+	}
+	route, _ := trimEnd(ep.route)
+	_, ext := trimExt(route)
+	return ext != ""
+}
+
+// hasName reports whether names contains name.
+func hasName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// deprecated returns a handler that sets the deprecation headers configured
+// by d before calling h.
+func (mux *ServeMux) deprecated(d *deprecation, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("Deprecation", "true")
+		if !d.sunset.IsZero() {
+			header.Set("Sunset", d.sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.successor != nil {
+			if path, ok := mux.successorPath(d.successor, r); ok {
+				header.Add("Link", "<"+path+`>; rel="successor-version"`)
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// successorPath renders the path of successor using the parameters that were
+// matched by r.
+func (mux *ServeMux) successorPath(successor *endpoint, r *http.Request) (string, bool) {
+	values := make(map[string]string)
+	if rc := routeFrom(r); rc != nil {
+		names := successor.paramNames()
+		for _, pinfo := range rc.values() {
+			if hasName(names, pinfo.Name) {
+				values[pinfo.Name] = pinfo.Raw
+			}
+		}
+	}
+	path, err := mux.renderPath(successor, values)
+	return path, err == nil
+}
@@ -0,0 +1,71 @@
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"code.soquee.net/mux"
+)
+
+var sunset = time.Date(2027, time.January, 2, 15, 4, 5, 0, time.UTC)
+
+var deprecatedTests = [...]struct {
+	path   string
+	sunset string
+	link   string
+}{
+	0: {path: "/v1/users/12", sunset: "Sat, 02 Jan 2027 15:04:05 GMT", link: `</v2/users/12>; rel="successor-version"`},
+	1: {path: "/v1/users/12/orders/7", sunset: "Sat, 02 Jan 2027 15:04:05 GMT", link: `</v2/orders/7>; rel="successor-version"`},
+	2: {path: "/v1/ping"},
+}
+
+func TestDeprecated(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/v1/users/{id uint}", codeHandler(t, testCode), mux.Deprecated(sunset, "/v2/users/{id uint}")),
+		mux.Handle(http.MethodGet, "/v1/users/{id uint}/orders/{order uint}", codeHandler(t, testCode), mux.Deprecated(sunset, "/v2/orders/{order uint}")),
+		mux.Handle(http.MethodGet, "/v1/ping", codeHandler(t, testCode), mux.Deprecated(time.Time{}, "")),
+		mux.Handle(http.MethodGet, "/v2/users/{id uint}", codeHandler(t, testCode)),
+	)
+	for i, tc := range deprecatedTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rec.Code != testCode {
+				t.Errorf("Unexpected status code: want=%d, got=%d", testCode, rec.Code)
+			}
+			if dep := rec.Header().Get("Deprecation"); dep != "true" {
+				t.Errorf("Unexpected Deprecation header: want=%q, got=%q", "true", dep)
+			}
+			if s := rec.Header().Get("Sunset"); s != tc.sunset {
+				t.Errorf("Unexpected Sunset header: want=%q, got=%q", tc.sunset, s)
+			}
+			if link := rec.Header().Get("Link"); link != tc.link {
+				t.Errorf("Unexpected Link header: want=%q, got=%q", tc.link, link)
+			}
+		})
+	}
+
+	for _, route := range m.Routes() {
+		if want := route.Pattern != "/v2/users/{id uint}"; route.Deprecated != want {
+			t.Errorf("Unexpected deprecated for %s: want=%t, got=%t", route.Pattern, want, route.Deprecated)
+		}
+		if route.Pattern == "/v1/users/{id uint}" && (!route.Sunset.Equal(sunset) || route.Successor != "/v2/users/{id uint}") {
+			t.Errorf("Unexpected sunset or successor: %v %q", route.Sunset, route.Successor)
+		}
+	}
+	if op := m.OpenAPIPaths()["/v1/users/{id}"].Get; op == nil || !op.Deprecated {
+		t.Errorf("Expected route to be deprecated, got %+v", op)
+	}
+}
+
+func TestDeprecatedUnknownParam(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected a successor with an unknown parameter to panic")
+		}
+	}()
+	mux.Handle(http.MethodGet, "/v1/users/{id uint}", failHandler(t), mux.Deprecated(sunset, "/v2/users/{name string}"))
+}
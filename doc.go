@@ -25,12 +25,25 @@
 //     string eg. anything ({string} is the same as {})
 //     path   eg. files/123.png (must be the last path component)
 //
+// The type "..." is an alias for path, so {p ...} is the same as {p path} and
+// {...} is the same as {path}.
+//
+// To match a static component containing literal braces, escape them by
+// doubling them: /legacy/{{id}} matches the path /legacy/{id}.
+//
 // All numeric types are 64 bits wide.
 // Parameters of type "path" match the remainder of the input path and therefore
 // may only appear as the final component of a route:
 //
 //     /file/{p path}
 //
+// A path parameter may require every segment of the remainder to have one of
+// the other types by appending it after a colon.
+// The route is not matched if any segment is empty or cannot be parsed as the
+// type, and the value of the parameter is a []string of the segments:
+//
+//     /blobs/{p path:uint}
+//
 // Two paths with different typed variable parameters (including static routes)
 // in the same position are not allowed.
 // Attempting to register any two of the following routes will panic:
@@ -50,6 +63,26 @@
 // Disallowing conflicting routes keeps things simple and eliminates this class
 // of issues.
 //
+// A trailing slash is significant: /about and /about/ are different routes and
+// may be registered with different handlers.
+// A request for one does not match a route registered as the other unless the
+// RedirectTrailingSlash option is used to redirect between them.
+// Wildcards match the remainder of the path including any trailing slash.
+// The final component of a route may be written as {$} to match only the path
+// ending in a slash: /docs/{$} is the same as /docs/, but it may not be
+// registered alongside a wildcard in the same position.
+// To route every path below a pattern such as /images/ to the same handler,
+// register it using Subtree.
+//
+// The last component of a route may end in a format extension parameter such
+// as {.format} following a static component.
+// The route /report{.format} matches /report, in which case the format
+// parameter is not set, as well as /report.json and /report.csv, in which case
+// it is set to the text after the last dot.
+// Format extensions may not be used in any other component, and a route with
+// a format extension conflicts with static routes such as /report.json that it
+// would also match.
+//
 // When a route is matched, the value of each named path parameter is stored on
 // the request context.
 // To retrieve the value of named path parameters from within a handler, the
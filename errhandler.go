@@ -0,0 +1,73 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+)
+
+// StatusError is an error that results in a specific HTTP status code when it
+// is returned from a handler registered with HandleErr.
+type StatusError struct {
+	Code int
+	Err  error
+}
+
+// Error satisfies the error interface for StatusError.
+func (e *StatusError) Error() string {
+	if e.Err == nil {
+		return http.StatusText(e.Code)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorHandler sets the function used to respond to errors returned from
+// handlers registered with HandleErr.
+//
+// By default, if the error is or wraps a *StatusError its code is used as the
+// response status, otherwise 500 Internal Server Error is used.
+// The error message is never written to the client by the default handler.
+func ErrorHandler(f func(w http.ResponseWriter, r *http.Request, err error)) Option {
+	return func(mux *ServeMux) {
+		if f == nil {
+			f = defErrorHandler
+		}
+		mux.errorHandler = f
+	}
+}
+
+// HandleErr registers a handler that may return an error for the given pattern.
+// If h returns a non-nil error and has not already written a response, the
+// error is passed to the handler configured using ErrorHandler.
+// Otherwise the error is discarded.
+// If the method or pattern is invalid or a handler already exists for pattern,
+// HandleErr panics.
+func HandleErr(method, r string, h func(http.ResponseWriter, *http.Request) error, opts ...RouteOption) Option {
+	// The route is validated now, but the error handler is only known once the
+	// option is applied.
+	var m *ServeMux
+	register := Handle(method, r, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cw := &defCodeWriter{ResponseWriter: w, code: http.StatusOK}
+		err := h(cw, req)
+		if err != nil && !cw.wrote {
+			m.errorHandler(w, req, err)
+		}
+	}), opts...)
+	return func(mux *ServeMux) {
+		m = mux
+		register(mux)
+	}
+}
+
+func defErrorHandler(w http.ResponseWriter, _ *http.Request, err error) {
+	code := http.StatusInternalServerError
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		code = statusErr.Code
+	}
+	http.Error(w, http.StatusText(code), code)
+}
@@ -0,0 +1,95 @@
+package mux_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var errHandlerTests = [...]struct {
+	err      error
+	write    bool
+	flush    bool
+	custom   bool
+	wantCode int
+	wantBody string
+}{
+	0: {wantCode: http.StatusOK},
+	1: {err: errors.New("secret"), wantCode: http.StatusInternalServerError, wantBody: "Internal Server Error\n"},
+	2: {err: &mux.StatusError{Code: http.StatusForbidden}, wantCode: http.StatusForbidden, wantBody: "Forbidden\n"},
+	3: {err: fmt.Errorf("wrapped: %w", &mux.StatusError{Code: http.StatusConflict, Err: errors.New("secret")}), wantCode: http.StatusConflict, wantBody: "Conflict\n"},
+	4: {err: errors.New("ignored"), write: true, wantCode: testCode, wantBody: testBody},
+	5: {err: errors.New("custom"), custom: true, wantCode: http.StatusTeapot, wantBody: "custom"},
+	6: {err: errors.New("ignored"), flush: true, wantCode: http.StatusOK},
+}
+
+func TestHandleErr(t *testing.T) {
+	for i, tc := range errHandlerTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			opts := []mux.Option{
+				mux.HandleErr(http.MethodGet, "/", func(w http.ResponseWriter, r *http.Request) error {
+					if tc.write {
+						w.WriteHeader(testCode)
+						fmt.Fprint(w, testBody)
+					}
+					if tc.flush {
+						// Streaming handlers must be able to flush the response and to
+						// reach the underlying writer, for example to hijack it.
+						if _, ok := w.(interface{ Unwrap() http.ResponseWriter }); !ok {
+							t.Errorf("Expected the response writer to support Unwrap")
+						}
+						f, ok := w.(http.Flusher)
+						if !ok {
+							t.Fatalf("Expected the response writer to be an http.Flusher")
+						}
+						f.Flush()
+					}
+					return tc.err
+				}),
+			}
+			if tc.custom {
+				opts = append(opts, mux.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+					w.WriteHeader(http.StatusTeapot)
+					fmt.Fprint(w, err)
+				}))
+			}
+			m := mux.New(opts...)
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != tc.wantCode {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.wantCode, w.Code)
+			}
+			if body := w.Body.String(); body != tc.wantBody {
+				t.Errorf("Unexpected body: want=%q, got=%q", tc.wantBody, body)
+			}
+			if w.Flushed != tc.flush {
+				t.Errorf("Unexpected flush: want=%t, got=%t", tc.flush, w.Flushed)
+			}
+		})
+	}
+}
+
+func TestHandleErrInvalid(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) error { return nil }
+	for i, tc := range [...]struct {
+		method  string
+		pattern string
+	}{
+		0: {method: "GET POST", pattern: "/"},
+		1: {method: http.MethodGet, pattern: "files"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("Expected HandleErr to panic before the option is applied")
+				}
+			}()
+			mux.HandleErr(tc.method, tc.pattern, h)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package mux
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConflictError is the value passed to panic when a route cannot be registered
+// because one of its components conflicts with an existing route.
+// For example, /user/{id int} conflicts with /user/{name string} and with
+// /user/me.
+type ConflictError struct {
+	// New is the pattern that was being registered.
+	New string
+	// Existing is the pattern up to and including the component of an existing
+	// route that New conflicts with.
+	Existing string
+	// Routes are the complete patterns of the existing routes that New
+	// conflicts with, each preceded by the methods they were registered for
+	// (for example "GET,POST /user/{id int}/edit").
+	Routes []string
+}
+
+// maxConflictRoutes is the maximum number of conflicting routes listed in the
+// message of a ConflictError.
+const maxConflictRoutes = 5
+
+// Error satisfies the error interface for ConflictError.
+func (e *ConflictError) Error() string {
+	msg := fmt.Sprintf("route %q conflicts with existing registration of %q", e.New, e.Existing)
+	if len(e.Routes) == 0 {
+		return msg
+	}
+	routes := e.Routes
+	if len(routes) > maxConflictRoutes {
+		routes = routes[:maxConflictRoutes]
+	}
+	msg += " used by " + strings.Join(routes, ", ")
+	if more := len(e.Routes) - len(routes); more > 0 {
+		msg += fmt.Sprintf(", and %d more", more)
+	}
+	return msg
+}
+
+// DuplicateError is the value passed to panic when a handler is registered for
+// a method and pattern that already has a handler.
+type DuplicateError struct {
+	Method  string
+	Pattern string
+	// Existing is the complete pattern of the route that was already registered
+	// for Method, which may be written differently from Pattern (for example
+	// /files/{p ...} and /files/{p path}).
+	Existing string
+}
+
+// Error satisfies the error interface for DuplicateError.
+func (e *DuplicateError) Error() string {
+	if e.Existing == "" || e.Existing == e.Pattern {
+		return fmt.Sprintf("route already registered for %s %s", e.Method, e.Pattern)
+	}
+	return fmt.Sprintf("route already registered for %s %s by %s", e.Method, e.Pattern, e.Existing)
+}
+
+// RouteNotFoundError is the value passed to panic when a handler is replaced
+// for a method and pattern that does not have a handler.
+type RouteNotFoundError struct {
+	Method  string
+	Pattern string
+}
+
+// Error satisfies the error interface for RouteNotFoundError.
+func (e *RouteNotFoundError) Error() string {
+	return fmt.Sprintf("no route registered for %s %s", e.Method, e.Pattern)
+}
+
+// MethodError is the value passed to panic when a route is registered with an
+// invalid method or without any methods.
+type MethodError struct {
+	// Method is the invalid method, or the empty string if no methods were
+	// provided.
+	Method  string
+	Pattern string
+}
+
+// Error satisfies the error interface for MethodError.
+func (e *MethodError) Error() string {
+	if e.Method == "" {
+		return fmt.Sprintf("no methods provided for route %q", e.Pattern)
+	}
+	return fmt.Sprintf("invalid method %q for route %q", e.Method, e.Pattern)
+}
+
+// NameError is the value passed to panic when a route is registered with a
+// name that is already used by a route with a different pattern.
+type NameError struct {
+	Name    string
+	Pattern string
+	// Existing is the pattern of the route that already uses Name.
+	Existing string
+}
+
+// Error satisfies the error interface for NameError.
+func (e *NameError) Error() string {
+	return fmt.Sprintf("route name %q for %s is already used by %s", e.Name, e.Pattern, e.Existing)
+}
+
+// PatternError is the value passed to panic when a pattern is malformed.
+type PatternError struct {
+	// Pattern is the malformed pattern.
+	// If the pattern could not be parsed, Pattern may only contain the
+	// component that was malformed.
+	Pattern string
+	// Reason describes what is wrong with the pattern.
+	Reason string
+}
+
+// Error satisfies the error interface for PatternError.
+func (e *PatternError) Error() string {
+	return fmt.Sprintf("invalid pattern %q: %s", e.Pattern, e.Reason)
+}
+
+const uncleanReason = "route is unclean, make sure it is rooted and remove any ., .., or //"
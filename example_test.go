@@ -3,6 +3,7 @@ package mux_test
 import (
 	"crypto/sha256"
 	"fmt"
+	"html/template"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -70,3 +71,23 @@ func Example_normalization() {
 	// Output:
 	// Profile for the user "me"
 }
+
+func ExampleTemplateFuncs() {
+	serveMux := mux.New(
+		mux.Handle(http.MethodGet, "/user/{id uint}/edit", http.NotFoundHandler(), mux.Name("user_edit")),
+		mux.Handle(http.MethodGet, "/files/{p path}", http.NotFoundHandler(), mux.Name("files")),
+	)
+
+	tmpl := template.Must(template.New("").Funcs(mux.TemplateFuncs(serveMux)).Parse(
+		`<a href="{{ url "user_edit" "id" .ID }}">Edit</a> <a href="{{ url "files" "p" .Avatar }}">Avatar</a>`,
+	))
+	err := tmpl.Execute(os.Stdout, struct {
+		ID     uint
+		Avatar string
+	}{ID: 10, Avatar: "avatars/my photo.png"})
+	if err != nil {
+		panic(err)
+	}
+	// Output:
+	// <a href="/user/10/edit">Edit</a> <a href="/files/avatars/my%20photo.png">Avatar</a>
+}
@@ -0,0 +1,189 @@
+package mux
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Explanation is a trace of the steps taken while matching a path against the
+// routes registered on a ServeMux.
+// It is returned by Explain.
+type Explanation struct {
+	Method string
+	Path   string
+
+	// Steps are the nodes of the route tree that were tried, in order.
+	Steps []Step
+
+	// Kind is the outcome of matching the path.
+	// Only KindMatched, KindMethodNotAllowed, KindNotFound, KindRedirect, and
+	// KindLimitExceeded are reported.
+	Kind MatchKind
+
+	// Pattern is the pattern of the route that matched, if any.
+	Pattern string
+
+	// Params contains the route parameters that were matched against the path.
+	Params []ParamInfo
+}
+
+// Step is a single step taken while matching a path.
+type Step struct {
+	// Node is the pattern that leads to the node of the route tree that was
+	// tried.
+	Node string
+
+	// Component is the part of the path that was matched against the node, if
+	// any.
+	Component string
+
+	// Matched reports whether the node matched.
+	Matched bool
+
+	// Reason explains why the node did or did not match.
+	Reason string
+}
+
+// String returns a single line describing the step.
+func (s Step) String() string {
+	verdict := "rejected"
+	if s.Matched {
+		verdict = "matched"
+	}
+	var b strings.Builder
+	b.WriteString(s.Node)
+	if s.Component != "" {
+		fmt.Fprintf(&b, " %q", s.Component)
+	}
+	b.WriteString(": ")
+	b.WriteString(verdict)
+	if s.Reason != "" {
+		b.WriteString(": ")
+		b.WriteString(s.Reason)
+	}
+	return b.String()
+}
+
+// String returns a multi-line description of the steps that were taken and
+// the outcome.
+func (e Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", e.Method, e.Path)
+	for _, s := range e.Steps {
+		fmt.Fprintf(&b, "\t%s\n", s)
+	}
+	fmt.Fprintf(&b, "result: %s", e.Kind)
+	if e.Pattern != "" {
+		fmt.Fprintf(&b, " %s", e.Pattern)
+	}
+	for _, pinfo := range e.Params {
+		fmt.Fprintf(&b, " %s=%q", pinfo.Name, pinfo.Raw)
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// Explain matches method and path against the registered routes in the same
+// way as Match and records each step that was taken, which is useful for
+// debugging requests that did not match the expected route.
+// It is slow and should not be used to route requests.
+//
+// Explain only reports how the path is matched against the route tree:
+// redirects to a path with or without a trailing slash and the selection of a
+// version or media type are not described.
+// If path is not clean, the only step describes the path that the request
+// would be redirected to.
+func (mux *ServeMux) Explain(method, path string) Explanation {
+	e := Explanation{
+		Method: method,
+		Path:   path,
+		Kind:   KindNotFound,
+	}
+	if clean := cleanPath(path); clean != path {
+		e.Kind = KindRedirect
+		e.Steps = append(e.Steps, Step{
+			Node:      "/",
+			Component: path,
+			Reason:    "the path is not clean and would be redirected to " + clean,
+		})
+		return e
+	}
+	res := mux.find(mux.compact(), method, path[1:], nil, func(s Step) {
+		e.Steps = append(e.Steps, s)
+	})
+	switch res.kind {
+	case kindMatched:
+		e.Kind = KindMatched
+	case kindMethodNotAllowed:
+		e.Kind = KindMethodNotAllowed
+	case kindTooLong, kindTooMany:
+		e.Kind = KindLimitExceeded
+	}
+	if res.node != nil {
+		e.Pattern = "/" + res.node.route
+		if res.ep != nil {
+			e.Pattern = "/" + res.ep.route
+		}
+	}
+	e.Params = withValues(res.params)
+	return e
+}
+
+// tracer records the steps taken while matching a path for Explain.
+// A nil tracer records nothing, so that matching requests does not pay for
+// tracing.
+type tracer func(Step)
+
+// step records that n was tried against component.
+func (t tracer) step(n *node, component string, matched bool, reason string) {
+	if t == nil {
+		return
+	}
+	t(Step{
+		Node:      "/" + n.route,
+		Component: component,
+		Matched:   matched,
+		Reason:    reason,
+	})
+}
+
+// static records the result of matching the static node n against path,
+// leaving remain.
+func (t tracer) static(n *node, path, remain string, ok bool) {
+	if t == nil {
+		return
+	}
+	if !ok {
+		// Compacted nodes are compared against as many components as they have.
+		end := len(path)
+		for i, c := 0, strings.Count(n.name, "/")+1; i < len(path); i++ {
+			if path[i] == '/' {
+				if c--; c == 0 {
+					end = i
+					break
+				}
+			}
+		}
+		t.step(n, path[:end], false, "the path does not match")
+		return
+	}
+	t.step(n, strings.TrimSuffix(path[:len(path)-len(remain)], "/"), true, "")
+}
+
+// param records the result of matching the variable node n against path,
+// which produced pinfo.
+func (t tracer) param(n *node, path string, pinfo ParamInfo, ok bool) {
+	if t == nil {
+		return
+	}
+	if ok {
+		t.step(n, pinfo.Raw, true, "")
+		return
+	}
+	if pinfo.Raw == "" {
+		part, _ := nextPart(path)
+		t.step(n, part, false, "the component is empty or cannot be unescaped")
+		return
+	}
+	t.step(n, pinfo.Raw, false, fmt.Sprintf("the component cannot be parsed as %s", n.typ))
+}
@@ -0,0 +1,113 @@
+package mux_test
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var explainTests = [...]struct {
+	method  string
+	path    string
+	kind    mux.MatchKind
+	pattern string
+	step    mux.Step
+}{
+	0: {
+		method:  http.MethodGet,
+		path:    "/user/12",
+		kind:    mux.KindMatched,
+		pattern: "/user/{id uint}",
+		step:    mux.Step{Node: "/user/{id uint}", Matched: true, Reason: "a route is registered for the method"},
+	},
+	1: {
+		method:  http.MethodPost,
+		path:    "/user/12",
+		kind:    mux.KindMethodNotAllowed,
+		pattern: "/user/{id uint}",
+		step:    mux.Step{Node: "/user/{id uint}", Reason: "no route is registered for POST"},
+	},
+	2: {
+		method: http.MethodGet,
+		path:   "/user/abc",
+		kind:   mux.KindNotFound,
+		step:   mux.Step{Node: "/user/{id uint}", Component: "abc", Reason: "the component cannot be parsed as uint"},
+	},
+	3: {
+		method: http.MethodGet,
+		path:   "/user/13",
+		kind:   mux.KindNotFound,
+		step:   mux.Step{Node: "/user/{id uint}", Reason: `the parameter "id" does not satisfy the constraint on the GET route`},
+	},
+	4: {
+		method: http.MethodGet,
+		path:   "/admin/users/list",
+		kind:   mux.KindNotFound,
+		step:   mux.Step{Node: "/admin/users/new", Component: "admin/users/list", Reason: "the path does not match"},
+	},
+	5: {
+		method:  http.MethodGet,
+		path:    "/files/a/b",
+		kind:    mux.KindMatched,
+		pattern: "/files/{p path}",
+		step:    mux.Step{Node: "/files/{p path}", Matched: true, Reason: "a route is registered for the method"},
+	},
+	6: {
+		method: http.MethodGet,
+		path:   "/user/../files",
+		kind:   mux.KindRedirect,
+		step:   mux.Step{Node: "/", Component: "/user/../files", Reason: "the path is not clean and would be redirected to /files"},
+	},
+}
+
+func TestExplain(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/user/{id uint}", failHandler(t), mux.Constrain("id", func(v interface{}) bool {
+			return v.(uint64)%2 == 0
+		})),
+		mux.Handle(http.MethodGet, "/admin/users/new", failHandler(t)),
+		mux.Handle(http.MethodGet, "/files/{p path}", failHandler(t)),
+	)
+	for i, tc := range explainTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			e := m.Explain(tc.method, tc.path)
+			if e.Kind != tc.kind {
+				t.Errorf("Unexpected kind: want=%v, got=%v", tc.kind, e.Kind)
+			}
+			if e.Kind != mux.KindNotFound && e.Pattern != tc.pattern {
+				t.Errorf("Unexpected pattern: want=%q, got=%q", tc.pattern, e.Pattern)
+			}
+			var found bool
+			for _, step := range e.Steps {
+				found = found || step == tc.step
+			}
+			if !found {
+				t.Errorf("Expected step %q, got:\n%s", tc.step, e)
+			}
+
+			// The explanation must agree with the result of routing the path.
+			_, pattern, _, ok := m.Match(tc.method, tc.path)
+			if ok != (e.Kind == mux.KindMatched) || ok && pattern != e.Pattern {
+				t.Errorf("Explanation disagrees with Match: matched=%t pattern=%q, explained %v %q", ok, pattern, e.Kind, e.Pattern)
+			}
+		})
+	}
+}
+
+func TestExplainString(t *testing.T) {
+	m := mux.New(mux.Handle(http.MethodGet, "/user/{id uint}", failHandler(t)))
+	s := m.Explain(http.MethodGet, "/user/12").String()
+	for _, want := range []string{
+		"GET /user/12\n",
+		"\t/user \"user\": matched\n",
+		"\t/user/{id uint} \"12\": matched\n",
+		"result: matched /user/{id uint} id=\"12\"\n",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Expected explanation to contain %q, got:\n%s", want, s)
+		}
+	}
+}
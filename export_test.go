@@ -0,0 +1,39 @@
+package mux
+
+import (
+	"time"
+)
+
+// Export unexported functions for testing.
+var (
+	CleanPath     = cleanPath
+	SlowCleanPath = slowCleanPath
+	IsClean       = isClean
+)
+
+// ParamOffset returns the offset of pinfo in the route it was matched against.
+func ParamOffset(pinfo ParamInfo) uint {
+	return pinfo.offset
+}
+
+// Limiter exposes a rate limiter so that it can be tested with a fake clock.
+type Limiter struct {
+	l *limiter
+}
+
+// NewLimiter returns the rate limiter that Limit would use.
+func NewLimiter(rate float64, burst int) Limiter {
+	return Limiter{l: &limiter{rate: rate, burst: float64(burst), buckets: make(map[string]*bucket)}}
+}
+
+// Take takes a token from the bucket for key at time now.
+func (l Limiter) Take(key string, now time.Time) (bool, time.Duration) {
+	return l.l.take(key, now)
+}
+
+// Len returns the number of buckets held by the limiter.
+func (l Limiter) Len() int {
+	l.l.mu.Lock()
+	defer l.l.mu.Unlock()
+	return len(l.l.buckets)
+}
@@ -0,0 +1,49 @@
+package mux
+
+import (
+	"net/http"
+)
+
+// Fallback sets a handler that is passed requests that do not match any route,
+// for example to hand them to another router while migrating to this one.
+//
+// Unlike the NotFound handler, the fallback handler receives the request
+// exactly as it was passed to the ServeMux: its URL is unchanged and its
+// context does not contain any information about routing, so it may be routed
+// again from scratch.
+// Redirects are not affected: requests with paths that are not clean are
+// still redirected to the clean path, and the redirects configured using
+// RedirectTrailingSlash and RedirectFixedPath are still issued for paths that
+// would match a route.
+//
+// If NotFound is also used, the NotFound handler takes precedence and the
+// fallback handler is only used for requests that match the path of a route
+// but not its method, if FallbackMethodNotAllowed is enabled.
+// If h is nil, no fallback handler is used.
+func Fallback(h http.Handler) Option {
+	return func(mux *ServeMux) {
+		mux.fallback = h
+	}
+}
+
+// FallbackMethodNotAllowed configures whether requests that match the path of
+// a route but not its method are passed to the handler configured using
+// Fallback instead of the MethodNotAllowed handler.
+// By default they are not.
+func FallbackMethodNotAllowed(enabled bool) Option {
+	return func(mux *ServeMux) {
+		mux.fallbackMethods = enabled
+	}
+}
+
+// fallsBack reports whether requests that resolved to kind are passed to the
+// fallback handler.
+func (mux *ServeMux) fallsBack(kind MatchKind) bool {
+	switch kind {
+	case KindNotFound:
+		return !mux.customNotFound
+	case KindMethodNotAllowed:
+		return mux.fallbackMethods
+	}
+	return false
+}
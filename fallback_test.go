@@ -0,0 +1,65 @@
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var fallbackTests = [...]struct {
+	method   string
+	path     string
+	opts     []mux.Option
+	code     int
+	fellBack bool
+}{
+	0: {method: http.MethodGet, path: "/user/1", code: testCode},
+	1: {method: http.MethodGet, path: "/legacy/page", code: testStatusCode, fellBack: true},
+	2: {method: http.MethodGet, path: "/user/abc", code: testStatusCode, fellBack: true},
+	3: {method: http.MethodPost, path: "/user/1", code: http.StatusMethodNotAllowed},
+	4: {method: http.MethodPost, path: "/user/1", opts: []mux.Option{mux.FallbackMethodNotAllowed(true)}, code: testStatusCode, fellBack: true},
+	5: {method: http.MethodGet, path: "/legacy/page", opts: []mux.Option{mux.NotFound(codeHandler(nil, notFoundStatusCode))}, code: notFoundStatusCode},
+	6: {method: http.MethodPost, path: "/user/1", opts: []mux.Option{mux.NotFound(codeHandler(nil, notFoundStatusCode)), mux.FallbackMethodNotAllowed(true)}, code: testStatusCode, fellBack: true},
+	7: {method: http.MethodGet, path: "/user/../legacy", code: http.StatusPermanentRedirect},
+}
+
+func TestFallback(t *testing.T) {
+	for i, tc := range fallbackTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var got *http.Request
+			opts := append([]mux.Option{
+				mux.Handle(http.MethodGet, "/user/{id uint}", codeHandler(t, testCode)),
+				mux.Fallback(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					got = r
+					w.WriteHeader(testStatusCode)
+				})),
+			}, tc.opts...)
+			m := mux.New(opts...)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, req)
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected code: want=%d, got=%d", tc.code, rec.Code)
+			}
+			switch {
+			case tc.fellBack && got != req:
+				t.Errorf("Expected the fallback handler to receive the original request")
+			case !tc.fellBack && got != nil:
+				t.Errorf("Did not expect the fallback handler to be called")
+			}
+			if got == nil {
+				return
+			}
+			if p := mux.Pattern(got); p != "" {
+				t.Errorf("Did not expect a pattern on the fallback request, got %q", p)
+			}
+			if _, ok := mux.TypeMismatch(got); ok {
+				t.Errorf("Did not expect a type mismatch on the fallback request")
+			}
+		})
+	}
+}
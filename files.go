@@ -0,0 +1,118 @@
+package mux
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Files registers a handler for GET and HEAD requests that serves files from
+// fsys.
+// The pattern must end in a named parameter of type path, the value of which is
+// used as the name of the file to serve.
+// If the file does not exist, or its name is not valid (for example because it
+// would escape the root of fsys), the ServeMux's NotFound handler is used.
+// Requests for directories serve the file "index.html" from the directory if it
+// exists, directory listings are never served.
+func Files(pattern string, fsys fs.FS) Option {
+	_, name := wildPattern(pattern)
+
+	return func(mux *ServeMux) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !serveFile(w, r, fsys, Param(r, name).Raw) {
+				mux.notFound.ServeHTTP(w, r)
+			}
+		})
+		HandleMethods([]string{http.MethodGet, http.MethodHead}, pattern, h)(mux)
+	}
+}
+
+// SPA registers handlers for GET and HEAD requests that serve a single page
+// application from fsys.
+// The pattern must end in a named parameter of type path.
+// If the value of the parameter names a file in fsys it is served in the same
+// way as the Files option, otherwise the index document is served instead with
+// caching disabled.
+// The index is also served for requests to the pattern with the final path
+// parameter removed (for example /app/ for the pattern /app/{p path}).
+// If the index document does not exist, the ServeMux's NotFound handler is
+// used.
+func SPA(pattern string, fsys fs.FS, index string) Option {
+	prefix, name := wildPattern(pattern)
+	if !fs.ValidPath(index) {
+		panic(&PatternError{Pattern: index, Reason: "invalid index document"})
+	}
+
+	return func(mux *ServeMux) {
+		serveIndex := func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-store")
+			if !serveFile(w, r, fsys, index) {
+				w.Header().Del("Cache-Control")
+				mux.notFound.ServeHTTP(w, r)
+			}
+		}
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !serveFile(w, r, fsys, Param(r, name).Raw) {
+				serveIndex(w, r)
+			}
+		})
+		HandleMethods([]string{http.MethodGet, http.MethodHead}, pattern, h)(mux)
+		HandleMethods([]string{http.MethodGet, http.MethodHead}, prefix, http.HandlerFunc(serveIndex))(mux)
+	}
+}
+
+// wildPattern checks that pattern ends in a named path parameter and returns
+// the pattern without the final parameter along with the parameter name.
+func wildPattern(pattern string) (prefix, name string) {
+	idx := strings.LastIndexByte(pattern, '/')
+	typ := ""
+	if idx != -1 && idx < len(pattern)-1 {
+		name, typ = parseParam(pattern[idx+1:])
+	}
+	if typ != typWild || name == "" {
+		panic(&PatternError{Pattern: pattern, Reason: "route must end in a named parameter of type path"})
+	}
+	return pattern[:idx+1], name
+}
+
+// serveFile serves the named file from fsys and reports whether it was found.
+func serveFile(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string) bool {
+	name = strings.TrimSuffix(name, "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
+		return false
+	}
+
+	stat, err := fs.Stat(fsys, name)
+	if err != nil {
+		return false
+	}
+	if stat.IsDir() {
+		name = path.Join(name, "index.html")
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	stat, err = f.Stat()
+	if err != nil || stat.IsDir() {
+		return false
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return false
+		}
+		content = bytes.NewReader(b)
+	}
+	http.ServeContent(w, r, stat.Name(), stat.ModTime(), content)
+	return true
+}
@@ -0,0 +1,139 @@
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"testing/fstest"
+
+	"code.soquee.net/mux"
+)
+
+var filesTests = [...]struct {
+	method string
+	path   string
+	code   int
+	body   string
+	// escaped is set for paths that would be canonicalized with a redirect if
+	// the decoded path were used for matching.
+	escaped bool
+}{
+	0: {path: "/static/a.txt", code: http.StatusOK, body: "a"},
+	1: {path: "/static/dir/b.txt", code: http.StatusOK, body: "b"},
+	2: {path: "/static/dir/", code: http.StatusOK, body: "index"},
+	3: {path: "/static/dir", code: http.StatusOK, body: "index"},
+	4: {path: "/static/missing.txt", code: notFoundStatusCode},
+	5: {path: "/static/empty", code: notFoundStatusCode},
+	6: {path: "/static/%2E%2E/secret.txt", code: notFoundStatusCode, escaped: true},
+	7: {path: "/static/dir%2F..%2F..%2Fsecret.txt", code: notFoundStatusCode, escaped: true},
+	8: {method: http.MethodHead, path: "/static/a.txt", code: http.StatusOK},
+	9: {method: http.MethodPost, path: "/static/a.txt", code: http.StatusMethodNotAllowed, body: "Method Not Allowed\n"},
+}
+
+func TestFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":          {Data: []byte("a")},
+		"dir/b.txt":      {Data: []byte("b")},
+		"dir/index.html": {Data: []byte("index")},
+		"empty":          {Mode: 0755 | 1<<31},
+	}
+	for _, escaped := range []bool{false, true} {
+		opts := []mux.Option{
+			mux.Files("/static/{file path}", fsys),
+			mux.NotFound(codeHandler(t, notFoundStatusCode)),
+		}
+		if escaped {
+			opts = append(opts, mux.UseEscapedPath())
+		}
+		m := mux.New(opts...)
+		for i, tc := range filesTests {
+			if tc.escaped && !escaped {
+				continue
+			}
+			t.Run(strconv.FormatBool(escaped)+"/"+strconv.Itoa(i), func(t *testing.T) {
+				if tc.method == "" {
+					tc.method = http.MethodGet
+				}
+				rec := httptest.NewRecorder()
+				m.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+				if rec.Code != tc.code {
+					t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+				}
+				if body := rec.Body.String(); body != tc.body {
+					t.Errorf("Unexpected body: want=%q, got=%q", tc.body, body)
+				}
+			})
+		}
+	}
+}
+
+func TestFilesBadPattern(t *testing.T) {
+	for _, pattern := range []string{"/static/{file string}", "/static/{path}", "/static/"} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("Expected pattern %q to panic", pattern)
+				}
+			}()
+			mux.Files(pattern, fstest.MapFS{})
+		}()
+	}
+}
+
+var spaTests = [...]struct {
+	path  string
+	code  int
+	body  string
+	cache string
+}{
+	0: {path: "/app/", code: http.StatusOK, body: "index", cache: "no-store"},
+	1: {path: "/app/main.js", code: http.StatusOK, body: "js"},
+	2: {path: "/app/users/123", code: http.StatusOK, body: "index", cache: "no-store"},
+	3: {path: "/app/assets/missing.js", code: http.StatusOK, body: "index", cache: "no-store"},
+	4: {path: "/api/missing", code: notFoundStatusCode},
+	5: {path: "/api/users", code: 201},
+	6: {path: "/other", code: notFoundStatusCode},
+}
+
+func TestSPA(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("index")},
+		"main.js":    {Data: []byte("js")},
+	}
+	m := mux.New(
+		mux.SPA("/app/{p path}", fsys, "index.html"),
+		mux.Handle(http.MethodGet, "/api/users", codeHandler(t, 201)),
+		mux.NotFound(codeHandler(t, notFoundStatusCode)),
+	)
+	for i, tc := range spaTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+			if body := rec.Body.String(); body != tc.body {
+				t.Errorf("Unexpected body: want=%q, got=%q", tc.body, body)
+			}
+			if cache := rec.Header().Get("Cache-Control"); cache != tc.cache {
+				t.Errorf("Unexpected Cache-Control: want=%q, got=%q", tc.cache, cache)
+			}
+			if tc.body == "index" {
+				if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+					t.Errorf("Unexpected Content-Type: %q", ct)
+				}
+			}
+		})
+	}
+
+	m = mux.New(
+		mux.SPA("/{p path}", fstest.MapFS{}, "index.html"),
+		mux.NotFound(codeHandler(t, notFoundStatusCode)),
+	)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != notFoundStatusCode || rec.Header().Get("Cache-Control") != "" {
+		t.Errorf("Expected missing index to use NotFound handler, got code %d and headers %v", rec.Code, rec.Header())
+	}
+}
@@ -1,3 +1,3 @@
 module code.soquee.net/mux
 
-go 1.11
+go 1.16
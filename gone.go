@@ -0,0 +1,48 @@
+package mux
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Gone registers a route for an endpoint that has been retired which responds
+// with 410 (Gone) and the plain text body.
+// Any occurrence of a parameter name in braces in body, for example {id}, is
+// replaced by the value of the parameter from the request path so that the
+// body can point to the replacement of a specific resource.
+//
+// Retired routes conflict with other routes in the same way as any other
+// route, which prevents their paths from being reused by accident, and they
+// are marked as Gone in the output of Routes.
+func Gone(method, pattern, body string, opts ...RouteOption) Option {
+	return GoneHandler(method, pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg := body
+		if rc := routeFrom(r); rc != nil {
+			for _, pinfo := range rc.values() {
+				msg = strings.ReplaceAll(msg, "{"+pinfo.Name+"}", pinfo.Raw)
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusGone)
+		w.Write([]byte(msg))
+	}), opts...)
+}
+
+// GoneHandler is like Gone except that the response is written by h, for
+// example to set a Location header.
+// If h does not set the status code, it is set to 410 (Gone) instead of 200.
+// The route parameters can be retrieved from within h using Param.
+func GoneHandler(method, pattern string, h http.Handler, opts ...RouteOption) Option {
+	opts = append(opts[:len(opts):len(opts)], func(ep *endpoint) {
+		ep.gone = true
+	})
+	return Handle(method, pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dw := &defCodeWriter{ResponseWriter: w, code: http.StatusGone}
+		h.ServeHTTP(dw, r)
+		// Handlers that only set headers must still respond with 410.
+		if !dw.wrote {
+			dw.WriteHeader(http.StatusGone)
+		}
+	}), opts...)
+}
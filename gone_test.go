@@ -0,0 +1,72 @@
+package mux_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var goneTests = [...]struct {
+	method   string
+	path     string
+	code     int
+	body     string
+	location string
+}{
+	0: {method: http.MethodGet, path: "/v1/users/12", code: http.StatusGone, body: "Use /v2/users/12 instead."},
+	1: {method: http.MethodGet, path: "/v1/orders/7", code: http.StatusGone, location: "/v2/orders/7"},
+	2: {method: http.MethodGet, path: "/v1/orders/x", code: http.StatusNotFound},
+	3: {method: http.MethodGet, path: "/v2/users/12", code: testCode},
+}
+
+func TestGone(t *testing.T) {
+	m := mux.New(
+		mux.Gone(http.MethodGet, "/v1/users/{id uint}", "Use /v2/users/{id} instead."),
+		mux.GoneHandler(http.MethodGet, "/v1/orders/{id uint}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Location", "/v2/orders/"+mux.Param(r, "id").Raw)
+		})),
+		mux.Handle(http.MethodGet, "/v2/users/{id uint}", codeHandler(t, testCode)),
+	)
+	for i, tc := range goneTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+			if tc.body != "" && rec.Body.String() != tc.body {
+				t.Errorf("Unexpected body: want=%q, got=%q", tc.body, rec.Body.String())
+			}
+			if loc := rec.Header().Get("Location"); loc != tc.location {
+				t.Errorf("Unexpected location: want=%q, got=%q", tc.location, loc)
+			}
+		})
+	}
+
+	for _, route := range m.Routes() {
+		if want := route.Pattern != "/v2/users/{id uint}"; route.Gone != want {
+			t.Errorf("Unexpected gone for %s %s: want=%t, got=%t", route.Method, route.Pattern, want, route.Gone)
+		}
+	}
+	if op := m.OpenAPIPaths()["/v1/users/{id}"].Get; op == nil || !op.Deprecated {
+		t.Errorf("Expected retired route to be deprecated, got %+v", op)
+	}
+}
+
+func TestGoneConflict(t *testing.T) {
+	defer func() {
+		err, _ := recover().(error)
+		var conflict *mux.ConflictError
+		if !errors.As(err, &conflict) {
+			t.Errorf("Expected a conflict error, got %v", err)
+		}
+	}()
+	mux.New(
+		mux.Gone(http.MethodGet, "/v1/users/{id uint}", "Gone"),
+		mux.Handle(http.MethodGet, "/v1/users/{name string}", failHandler(t)),
+	)
+}
@@ -0,0 +1,159 @@
+package mux
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strings"
+)
+
+// gorillaTypes are the regular expressions that are converted to parameter
+// types by FromGorillaPattern.
+// Expressions are compared after parsing, so other spellings of the same
+// expression such as \d+ or [[:digit:]]+ for [0-9]+ are also recognized.
+var gorillaTypes = [...]struct {
+	re  string
+	typ string
+}{
+	{re: `[^/]+`, typ: typString},
+	{re: `[0-9]+`, typ: typUint},
+	{re: `-?[0-9]+`, typ: typInt},
+	{re: `[-+]?[0-9]+`, typ: typInt},
+	{re: `[0-9]+(?:\.[0-9]+)?`, typ: typFloat},
+	{re: `-?[0-9]+(?:\.[0-9]+)?`, typ: typFloat},
+	{re: `[0-9]*\.?[0-9]+`, typ: typFloat},
+	{re: `-?[0-9]*\.?[0-9]+`, typ: typFloat},
+	{re: `.*`, typ: typWild},
+	{re: `.+`, typ: typWild},
+}
+
+// FromGorillaPattern converts a path template in the syntax used by
+// github.com/gorilla/mux (for example "/articles/{category}/{id:[0-9]+}") into
+// a pattern that can be registered with this package.
+//
+// Variables without a regular expression such as {category} are converted to
+// string parameters.
+// Variables with a regular expression are converted to the type of parameter
+// that matches the same path segments: [0-9]+ to uint, -?[0-9]+ to int,
+// -?[0-9]+(?:\.[0-9]+)? to float, [^/]+ to string, and .* or .+ to path, which
+// is only allowed in the last segment.
+// Other ways of writing the same expressions, such as \d+, are also converted.
+// Unlike the regular expressions, the numeric types do not match numbers that
+// are too large to fit in 64 bits.
+//
+// Host templates, variables that are only part of a path segment, and other
+// regular expressions are not supported.
+// If the template cannot be converted the returned error is a *PatternError
+// that names the unsupported construct.
+func FromGorillaPattern(p string) (string, error) {
+	if !strings.HasPrefix(p, "/") {
+		return "", &PatternError{Pattern: p, Reason: "host templates and relative paths are not supported"}
+	}
+	segments, err := splitGorilla(p)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for i, part := range segments {
+		b.WriteByte('/')
+		if !isGorillaVar(part) {
+			if strings.ContainsAny(part, "{}") {
+				return "", &PatternError{Pattern: p, Reason: fmt.Sprintf("variables must be a full path segment, found %q", part)}
+			}
+			b.WriteString(part)
+			continue
+		}
+		name, re, hasRe := cut(part[1:len(part)-1], ":")
+		if name == "" {
+			return "", &PatternError{Pattern: p, Reason: fmt.Sprintf("variable %s has no name", part)}
+		}
+		if strings.ContainsAny(name, " {}") {
+			return "", &PatternError{Pattern: p, Reason: fmt.Sprintf("invalid variable name %q", name)}
+		}
+		typ := typString
+		if hasRe {
+			typ, err = gorillaType(p, name, re)
+			if err != nil {
+				return "", err
+			}
+		}
+		if typ == typWild && i != len(segments)-1 {
+			return "", &PatternError{Pattern: p, Reason: fmt.Sprintf("variable %q matches the rest of the path but is not the last segment", name)}
+		}
+		b.WriteString("{" + name + " " + typ + "}")
+	}
+
+	pattern := b.String()
+	if err := ValidatePattern(pattern); err != nil {
+		return "", err
+	}
+	return pattern, nil
+}
+
+// splitGorilla splits the path template p after its leading slash at each
+// slash that is not inside a variable.
+func splitGorilla(p string) ([]string, error) {
+	var segments []string
+	depth, start := 0, 1
+	for i := 1; i < len(p); i++ {
+		switch p[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth < 0 {
+				return nil, &PatternError{Pattern: p, Reason: "unbalanced braces"}
+			}
+		case '/':
+			if depth == 0 {
+				segments = append(segments, p[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, &PatternError{Pattern: p, Reason: "unbalanced braces"}
+	}
+	return append(segments, p[start:]), nil
+}
+
+// isGorillaVar reports whether the segment part, which must have balanced
+// braces, consists of a single variable.
+func isGorillaVar(part string) bool {
+	if !strings.HasPrefix(part, "{") {
+		return false
+	}
+	depth := 0
+	for i := 0; i < len(part); i++ {
+		switch part[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i == len(part)-1
+			}
+		}
+	}
+	return false
+}
+
+// gorillaType returns the parameter type that matches the same path segments as
+// the regular expression re of the variable name in the template p.
+func gorillaType(p, name, re string) (string, error) {
+	parsed, err := syntax.Parse(re, syntax.Perl)
+	if err != nil {
+		return "", &PatternError{Pattern: p, Reason: fmt.Sprintf("invalid regular expression %q for variable %q", re, name)}
+	}
+	parsed = parsed.Simplify()
+	for _, t := range gorillaTypes {
+		known, err := syntax.Parse(t.re, syntax.Perl)
+		if err != nil {
+			panic(err)
+		}
+		if parsed.Equal(known.Simplify()) {
+			return t.typ, nil
+		}
+	}
+	return "", &PatternError{Pattern: p, Reason: fmt.Sprintf("unsupported regular expression %q for variable %q", re, name)}
+}
@@ -0,0 +1,59 @@
+package mux_test
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var fromGorillaTests = [...]struct {
+	gorilla string
+	pattern string
+	err     string
+}{
+	0:  {gorilla: "/", pattern: "/"},
+	1:  {gorilla: "/articles/{category}/{id:[0-9]+}", pattern: "/articles/{category string}/{id uint}"},
+	2:  {gorilla: `/users/{id:\d+}/`, pattern: "/users/{id uint}/"},
+	3:  {gorilla: "/offsets/{n:-?[0-9]+}", pattern: "/offsets/{n int}"},
+	4:  {gorilla: `/prices/{p:[0-9]+(?:\.[0-9]+)?}`, pattern: "/prices/{p float}"},
+	5:  {gorilla: "/tags/{tag:[^/]+}", pattern: "/tags/{tag string}"},
+	6:  {gorilla: "/files/{rest:.*}", pattern: "/files/{rest path}"},
+	7:  {gorilla: "/codes/{c:[0-9]{3}}", err: `unsupported regular expression "[0-9]{3}" for variable "c"`},
+	8:  {gorilla: "/slugs/{slug:[a-z-]+}", err: `unsupported regular expression "[a-z-]+" for variable "slug"`},
+	9:  {gorilla: "/files/{rest:.*}/raw", err: `variable "rest" matches the rest of the path`},
+	10: {gorilla: "/articles/{id}.json", err: "variables must be a full path segment"},
+	11: {gorilla: "/{a}-{b}", err: "variables must be a full path segment"},
+	12: {gorilla: "{sub}.example.com/", err: "host templates"},
+	13: {gorilla: "/users/{id", err: "unbalanced braces"},
+	14: {gorilla: "/users/{:[0-9]+}", err: "has no name"},
+	15: {gorilla: "/users/{id:[0-9}", err: `invalid regular expression "[0-9" for variable "id"`},
+	16: {gorilla: "/a//b", err: "unclean"},
+	17: {gorilla: "/codes/{c:[[:digit:]]+}", pattern: "/codes/{c uint}"},
+}
+
+func TestFromGorillaPattern(t *testing.T) {
+	for i, tc := range fromGorillaTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			pattern, err := mux.FromGorillaPattern(tc.gorilla)
+			if tc.err != "" {
+				var patternErr *mux.PatternError
+				if !errors.As(err, &patternErr) {
+					t.Fatalf("Expected PatternError for %q, got=%v", tc.gorilla, err)
+				}
+				if !strings.Contains(patternErr.Reason, tc.err) {
+					t.Errorf("Unexpected reason: want=%q, got=%q", tc.err, patternErr.Reason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error converting %q: %v", tc.gorilla, err)
+			}
+			if pattern != tc.pattern {
+				t.Errorf("Unexpected pattern: want=%q, got=%q", tc.pattern, pattern)
+			}
+		})
+	}
+}
@@ -1,8 +1,12 @@
 package mux
 
 import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
-	"strings"
+	"strconv"
 )
 
 // defCodeWriter is an http.ResponseWriter that writes the given status code by
@@ -26,22 +30,191 @@ func (w *defCodeWriter) WriteHeader(statusCode int) {
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
+func (w *defCodeWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.wrote {
+			w.WriteHeader(w.code)
+		}
+		f.Flush()
+	}
+}
+
+// ReadFrom copies src to the response body, using the ReadFrom method of the
+// underlying http.ResponseWriter if it has one.
+func (w *defCodeWriter) ReadFrom(src io.Reader) (int64, error) {
+	if !w.wrote {
+		w.WriteHeader(w.code)
+	}
+	return io.Copy(w.ResponseWriter, src)
+}
+
+// Hijack lets the caller take over the connection if the underlying
+// http.ResponseWriter supports it.
+// A hijacked connection counts as a response.
+func (w *defCodeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := hijack(w.ResponseWriter)
+	if err == nil {
+		w.wrote = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter.
+func (w *defCodeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// hijack hijacks the connection of w or returns http.ErrNotSupported if w is
+// not an http.Hijacker.
+func hijack(w http.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return h.Hijack()
+}
+
+// HeadWriter is an http.ResponseWriter that discards the response body while
+// counting the number of bytes written to it.
+// It can be used to implement a handler for HEAD requests using an existing
+// handler for GET requests.
+//
+// Headers and the status code are not sent until Finish or Flush is called.
+// If Finish is called and no Content-Length header has been set, it is set to
+// the number of bytes that were discarded.
+type HeadWriter struct {
+	w     http.ResponseWriter
+	n     int64
+	code  int
+	wrote bool
+}
+
+// NewHeadWriter returns a HeadWriter that writes headers to w.
+func NewHeadWriter(w http.ResponseWriter) *HeadWriter {
+	return &HeadWriter{w: w}
+}
+
+// Header returns the header map of the underlying http.ResponseWriter.
+func (w *HeadWriter) Header() http.Header {
+	return w.w.Header()
+}
+
+// Write discards p and always reports success.
+func (w *HeadWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// WriteHeader records the status code to be sent when the headers are written.
+// Only the first call to WriteHeader has any effect.
+func (w *HeadWriter) WriteHeader(statusCode int) {
+	if w.code == 0 {
+		w.code = statusCode
+	}
+}
+
+// Flush sends the headers if they have not yet been sent and flushes the
+// underlying http.ResponseWriter if it supports flushing.
+func (w *HeadWriter) Flush() {
+	w.writeHeader()
+	if f, ok := w.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Finish sends the headers if they have not yet been sent, setting the
+// Content-Length header if it has not already been set.
+func (w *HeadWriter) Finish() {
+	if !w.wrote {
+		h := w.w.Header()
+		if h.Get("Content-Length") == "" && h.Get("Transfer-Encoding") == "" && w.n > 0 {
+			h.Set("Content-Length", strconv.FormatInt(w.n, 10))
+		}
+	}
+	w.writeHeader()
+}
+
+// Written returns the number of bytes that have been written and discarded.
+func (w *HeadWriter) Written() int64 {
+	return w.n
+}
+
+func (w *HeadWriter) writeHeader() {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	w.w.WriteHeader(w.code)
+}
+
+// headHandler returns a handler that calls h with a HeadWriter.
+func headHandler(h http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hw := NewHeadWriter(w)
+		h.ServeHTTP(hw, r)
+		hw.Finish()
+	}
+}
+
+// traceExclude is the set of request headers that are likely to contain
+// sensitive data and are therefore never reflected in TRACE responses.
+var traceExclude = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+}
+
+// defTrace responds to TRACE requests by reflecting the request line and
+// headers back to the client as described in RFC 9110 § 9.3.8.
+func defTrace(w http.ResponseWriter, r *http.Request) {
+	uri := r.RequestURI
+	if uri == "" {
+		uri = r.URL.RequestURI()
+	}
+	w.Header().Set("Content-Type", "message/http")
+	fmt.Fprintf(w, "%s %s %s\r\n", r.Method, uri, r.Proto)
+	if r.Host != "" {
+		fmt.Fprintf(w, "Host: %s\r\n", r.Host)
+	}
+	r.Header.WriteSubset(w, traceExclude)
+	io.WriteString(w, "\r\n")
+}
+
 func notFoundHandler(h http.Handler) http.HandlerFunc {
+	return defCodeHandler(h, http.StatusNotFound)
+}
+
+// defCodeHandler returns a handler that calls h with the status code defaulting
+// to code instead of 200.
+func defCodeHandler(h http.Handler, code int) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		h.ServeHTTP(&defCodeWriter{
 			ResponseWriter: w,
-			code:           http.StatusNotFound,
+			code:           code,
 		}, r)
 	}
 }
 
-func defOptions(node node) http.Handler {
+// defOptions is the default OPTIONS handler.
+// The Allow header lists every method that can be used with the route,
+// including OPTIONS itself and any other methods that are handled
+// automatically.
+func (mux *ServeMux) defOptions(r *http.Request, n *node) http.Handler {
+	_, allow := mux.allowedFor(n, r)
+	code := mux.optionsCode
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var verbs []string
-		for v := range node.handlers {
-			verbs = append(verbs, v)
+		w.Header().Add("Allow", allow)
+		if code == 0 {
+			w.Write(nil)
+			return
 		}
-		w.Header().Add("Allow", strings.Join(verbs, ","))
-		w.Write(nil)
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(code)
 	})
 }
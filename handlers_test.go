@@ -1,11 +1,13 @@
 package mux_test
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"code.soquee.net/mux"
@@ -13,7 +15,7 @@ import (
 
 const (
 	testBody = "Test"
-	testCode = 123
+	testCode = 223
 )
 
 func successHandler(writeCode, writeBody bool) http.HandlerFunc {
@@ -30,6 +32,12 @@ func successHandler(writeCode, writeBody bool) http.HandlerFunc {
 	}
 }
 
+func allowedHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, strings.Join(mux.Allowed(r), ","))
+	}
+}
+
 func panicHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		panic("called panic handler with route: " + r.URL.String())
@@ -84,14 +92,14 @@ var handlerTests = [...]struct {
 		method: http.MethodOptions,
 		code:   http.StatusOK,
 		header: map[string][]string{
-			"Allow": {"GET,POST"},
+			"Allow": {"GET,OPTIONS,POST"},
 		},
 	},
 	4: {
 		method: http.MethodOptions,
 		code:   http.StatusOK,
 		header: map[string][]string{
-			"Allow": {""},
+			"Allow": {"OPTIONS"},
 		},
 	},
 	5: {
@@ -194,7 +202,7 @@ var handlerTests = [...]struct {
 		req:    "/test/",
 		code:   http.StatusOK,
 		header: map[string][]string{
-			"Allow": {"GET"},
+			"Allow": {"GET,OPTIONS"},
 		},
 	},
 	14: {
@@ -222,6 +230,223 @@ var handlerTests = [...]struct {
 		req:    "/test",
 		code:   testCode,
 	},
+	16: {
+		opts: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.HandleMethods([]string{http.MethodGet, http.MethodHead}, "/", failHandler(t)),
+			}
+		},
+		method: http.MethodOptions,
+		code:   http.StatusOK,
+		header: map[string][]string{
+			"Allow": {"GET,HEAD,OPTIONS"},
+		},
+	},
+	17: {
+		opts: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.Handle(http.MethodGet, "/user/{id uint}", failHandler(t)),
+				mux.OptionsHandler(func(r *http.Request, pattern string, methods []string) http.Handler {
+					return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+						fmt.Fprintf(w, "%s %s %s", r.URL.Path, pattern, strings.Join(methods, ","))
+					})
+				}),
+			}
+		},
+		method:   http.MethodOptions,
+		req:      "/user/123",
+		code:     http.StatusOK,
+		respBody: "/user/123 /user/{id uint} GET",
+	},
+	18: {
+		opts: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.Handle(http.MethodGet, "/user/{id uint}", failHandler(t)),
+				mux.OptionsHandler(nil),
+				mux.MethodNotAllowed(nil),
+			}
+		},
+		method:   http.MethodOptions,
+		req:      "/user/123",
+		code:     http.StatusNotFound,
+		respBody: "404 page not found\n",
+	},
+	19: {
+		opts: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.HandleMethods([]string{http.MethodPost, http.MethodGet}, "/user", failHandler(t)),
+				mux.MethodNotAllowed(allowedHandler()),
+			}
+		},
+		method:   http.MethodPut,
+		req:      "/user",
+		code:     http.StatusOK,
+		respBody: "GET,OPTIONS,POST",
+	},
+	20: {
+		opts: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.HandleMethods([]string{http.MethodPost, http.MethodGet}, "/{id int}", failHandler(t)),
+				mux.Options(nil),
+				mux.MethodNotAllowed(allowedHandler()),
+			}
+		},
+		method:   http.MethodPut,
+		req:      "/1",
+		code:     http.StatusOK,
+		respBody: "GET,POST",
+	},
+	21: {
+		opts: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.HandleMethods([]string{http.MethodOptions, http.MethodGet}, "/", failHandler(t)),
+				mux.MethodNotAllowed(allowedHandler()),
+			}
+		},
+		method:   http.MethodPut,
+		code:     http.StatusOK,
+		respBody: "GET,OPTIONS",
+	},
+	22: {
+		opts: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.Handle(http.MethodGet, "/a/b", failHandler(t)),
+			}
+		},
+		method:   http.MethodTrace,
+		req:      "/a/b",
+		code:     http.StatusMethodNotAllowed,
+		respBody: http.StatusText(http.StatusMethodNotAllowed) + "\n",
+		header: map[string][]string{
+			"Allow": {"GET,OPTIONS"},
+		},
+	},
+	23: {
+		opts: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.Handle(http.MethodGet, "/a/b", failHandler(t)),
+				mux.Options(nil),
+				mux.MethodNotAllowed(nil),
+			}
+		},
+		method:   http.MethodTrace,
+		req:      "/a",
+		code:     http.StatusMethodNotAllowed,
+		respBody: http.StatusText(http.StatusMethodNotAllowed) + "\n",
+		header: map[string][]string{
+			"Allow": {""},
+		},
+	},
+	24: {
+		opts: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.Handle(http.MethodGet, "/{id int}", failHandler(t)),
+				mux.Trace(true),
+			}
+		},
+		method:   http.MethodTrace,
+		req:      "/1?q=a",
+		code:     http.StatusOK,
+		respBody: "TRACE /1?q=a HTTP/1.1\r\nHost: example.com\r\n\r\n",
+		header: map[string][]string{
+			"Content-Type": {"message/http"},
+		},
+	},
+	25: {
+		opts: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.Trace(true),
+				mux.Handle(http.MethodTrace, "/", successHandler(true, false)),
+			}
+		},
+		method: http.MethodTrace,
+		code:   testCode,
+	},
+	26: {
+		opts: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.HandleMethods([]string{http.MethodPost, http.MethodGet}, "/", failHandler(t)),
+				mux.AutoHead(true),
+			}
+		},
+		method: http.MethodOptions,
+		code:   http.StatusOK,
+		header: map[string][]string{
+			"Allow": {"GET,HEAD,OPTIONS,POST"},
+		},
+	},
+	27: {
+		opts: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.HandleMethods([]string{http.MethodPost, http.MethodGet}, "/", failHandler(t)),
+			}
+		},
+		method:   http.MethodPut,
+		code:     http.StatusMethodNotAllowed,
+		respBody: http.StatusText(http.StatusMethodNotAllowed) + "\n",
+		header: map[string][]string{
+			"Allow": {"GET,OPTIONS,POST"},
+		},
+	},
+	28: {
+		opts: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.Handle(http.MethodGet, "/", failHandler(t)),
+				mux.OptionsStatus(http.StatusNoContent),
+			}
+		},
+		method: http.MethodOptions,
+		code:   http.StatusNoContent,
+		header: map[string][]string{
+			"Allow":          {"GET,OPTIONS"},
+			"Content-Length": {"0"},
+		},
+	},
+	29: {
+		opts: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.Handle(http.MethodGet, "/", failHandler(t)),
+				mux.OptionsStatus(http.StatusNoContent),
+				mux.Options(func([]string) http.Handler {
+					return successHandler(true, true)
+				}),
+			}
+		},
+		method:   http.MethodOptions,
+		code:     testCode,
+		respBody: testBody,
+		header: map[string][]string{
+			"Content-Length": {""},
+		},
+	},
+	30: {
+		opts: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.Handle(http.MethodGet, "/", failHandler(t)),
+				mux.MethodNotAllowed(successHandler(true, false)),
+			}
+		},
+		method: http.MethodPost,
+		code:   testCode,
+		header: map[string][]string{
+			"Allow": {"GET,OPTIONS"},
+		},
+	},
+	31: {
+		opts: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.HandleMethods([]string{http.MethodGet, http.MethodDelete}, "/user/{id int}", failHandler(t)),
+				mux.MethodNotAllowed(successHandler(true, false)),
+				mux.Options(nil),
+			}
+		},
+		method: http.MethodPost,
+		req:    "/user/1",
+		code:   testCode,
+		header: map[string][]string{
+			"Allow": {"DELETE,GET"},
+		},
+	},
 }
 
 func TestHandlers(t *testing.T) {
@@ -246,21 +471,8 @@ func TestHandlers(t *testing.T) {
 				t.Errorf("Unexpected response body: want=%q, got=%q", tc.respBody, s)
 			}
 			for k := range tc.header {
-				var v, vv string
-				if k == "Allow" {
-					// Sort "Allow" headers as a special case so that we don't have to do
-					// a sort or anything in the actual handler.
-					methods := strings.Split(tc.header.Get(k), ",")
-					sort.Strings(methods)
-					v = strings.Join(methods, ",")
-
-					methods = strings.Split(rec.HeaderMap.Get(k), ",")
-					sort.Strings(methods)
-					vv = strings.Join(methods, ",")
-				} else {
-					v = tc.header.Get(k)
-					vv = rec.HeaderMap.Get(k)
-				}
+				v := tc.header.Get(k)
+				vv := rec.HeaderMap.Get(k)
 				if vv != v {
 					t.Errorf("Unexpected value for header %q: want=%q, got=%q", k, v, vv)
 				}
@@ -301,3 +513,605 @@ func TestCanonicalization(t *testing.T) {
 		}
 	})
 }
+
+func TestCanonicalizationEscaped(t *testing.T) {
+	for _, escaped := range []bool{false, true} {
+		opts := []mux.Option{mux.Subtree(http.MethodGet, "/", successHandler(true, false))}
+		if escaped {
+			opts = append(opts, mux.UseEscapedPath())
+		}
+		m := mux.New(opts...)
+		for i, tc := range []struct {
+			path     string
+			location string
+		}{
+			0: {path: "/a/b%20c//d", location: "/a/b%20c/d"},
+			1: {path: "/a%2Fb/../c", location: "/c"},
+			2: {path: "/a%2F..%2Fb//c", location: "/a%2F..%2Fb/c"},
+			3: {path: "/a%2Fb"},
+			4: {path: "/a%20b/c"},
+			5: {path: "/a%2F%2Fb"},
+			6: {path: "/a%2F..%2Fb"},
+		} {
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			code := testCode
+			if tc.location != "" {
+				code = http.StatusPermanentRedirect
+			}
+			if rec.Code != code {
+				t.Errorf("%t/%d: unexpected status code: want=%d, got=%d", escaped, i, code, rec.Code)
+			}
+			if loc := rec.Header().Get("Location"); loc != tc.location {
+				t.Errorf("%t/%d: unexpected location: want=%q, got=%q", escaped, i, tc.location, loc)
+			}
+		}
+	}
+}
+
+func TestRedirectFixedPath(t *testing.T) {
+	m := mux.New(
+		mux.RedirectFixedPath(0),
+		mux.Handle(http.MethodGet, "/About/Team", failHandler(t)),
+		mux.Handle(http.MethodGet, "/Docs/", failHandler(t)),
+		mux.Handle(http.MethodGet, "/users/{name string}/Profile", failHandler(t)),
+		mux.Handle(http.MethodGet, "/files/{p path}", failHandler(t)),
+		mux.Handle(http.MethodGet, "/Dupe", failHandler(t)),
+		mux.Handle(http.MethodGet, "/dUPE", failHandler(t)),
+		mux.Handle(http.MethodPost, "/Post", failHandler(t)),
+		mux.NotFound(codeHandler(t, notFoundStatusCode)),
+	)
+	for i, tc := range []struct {
+		path     string
+		code     int
+		location string
+	}{
+		0: {path: "/about/team", code: http.StatusPermanentRedirect, location: "/About/Team"},
+		1: {path: "/DOCS/", code: http.StatusPermanentRedirect, location: "/Docs/"},
+		2: {path: "/Users/MixedCase/profile?q=1", code: http.StatusPermanentRedirect, location: "/users/MixedCase/Profile?q=1"},
+		3: {path: "/FILES/A/b%20c", code: http.StatusPermanentRedirect, location: "/files/A/b%20c"},
+		4: {path: "/dupe", code: notFoundStatusCode},
+		5: {path: "/post", code: notFoundStatusCode},
+		6: {path: "/about/nope", code: notFoundStatusCode},
+		7: {path: "/about/team/", code: notFoundStatusCode},
+		8: {path: "/docs", code: notFoundStatusCode},
+	} {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.code {
+			t.Errorf("%d: unexpected status code: want=%d, got=%d", i, tc.code, rec.Code)
+		}
+		if loc := rec.Header().Get("Location"); loc != tc.location {
+			t.Errorf("%d: unexpected location: want=%q, got=%q", i, tc.location, loc)
+		}
+	}
+}
+
+func TestRedirectTrailingSlash(t *testing.T) {
+	m := mux.New(
+		mux.RedirectTrailingSlash(0),
+		mux.Handle(http.MethodGet, "/about", failHandler(t)),
+		mux.Handle(http.MethodGet, "/docs/", failHandler(t)),
+		mux.Handle(http.MethodGet, "/both", failHandler(t)),
+		mux.Handle(http.MethodGet, "/both/", failHandler(t)),
+		mux.Handle(http.MethodGet, "/users/{id uint}/", failHandler(t)),
+		mux.Handle(http.MethodPost, "/post", failHandler(t)),
+		mux.NotFound(codeHandler(t, notFoundStatusCode)),
+	)
+	for i, tc := range []struct {
+		path     string
+		code     int
+		location string
+	}{
+		0: {path: "/about/", code: http.StatusPermanentRedirect, location: "/about"},
+		1: {path: "/docs?q=1", code: http.StatusPermanentRedirect, location: "/docs/?q=1"},
+		2: {path: "/users/1", code: http.StatusPermanentRedirect, location: "/users/1/"},
+		3: {path: "/users/a%20b", code: notFoundStatusCode},
+		4: {path: "/post/", code: notFoundStatusCode},
+		5: {path: "/nope/", code: notFoundStatusCode},
+	} {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.code {
+			t.Errorf("%d: unexpected status code: want=%d, got=%d", i, tc.code, rec.Code)
+		}
+		if loc := rec.Header().Get("Location"); loc != tc.location {
+			t.Errorf("%d: unexpected location: want=%q, got=%q", i, tc.location, loc)
+		}
+	}
+}
+
+func TestConnect(t *testing.T) {
+	var sub, port mux.ParamInfo
+	m := mux.New(
+		mux.HandleConnect("{sub string}.Example.net:{port uint}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sub = mux.Param(r, "sub")
+			port = mux.Param(r, "port")
+			if pattern := mux.Pattern(r); pattern != "{sub string}.Example.net:{port uint}" {
+				t.Errorf("Unexpected pattern: %q", pattern)
+			}
+			w.WriteHeader(testStatusCode)
+		})),
+		mux.HandleConnect("example.org", codeHandler(t, 201)),
+		mux.NotFound(codeHandler(t, notFoundStatusCode)),
+	)
+
+	for i, tc := range []struct {
+		host string
+		code int
+	}{
+		0: {host: "www.example.net:8443", code: testStatusCode},
+		1: {host: "example.net:443", code: notFoundStatusCode},
+		2: {host: "a.b.example.net:443", code: notFoundStatusCode},
+		3: {host: "www.example.net:https", code: notFoundStatusCode},
+		4: {host: "EXAMPLE.org:443", code: 201},
+		5: {host: "example.org:80", code: 201},
+	} {
+		req := httptest.NewRequest(http.MethodConnect, "/", nil)
+		req.Host = tc.host
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("%d: unexpected status code: want=%d, got=%d", i, tc.code, rec.Code)
+		}
+	}
+	if sub.Raw != "www" || port.Value != uint64(8443) {
+		t.Errorf("Unexpected parameters: sub=%+v, port=%+v", sub, port)
+	}
+
+	for _, pattern := range []string{"example.net:", "a..example.net", "{p path}.example.net", "{bad}.example.net"} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("Expected host pattern %q to panic", pattern)
+				}
+			}()
+			mux.HandleConnect(pattern, failHandler(t))
+		}()
+	}
+
+	// Patterns that match the same hosts are duplicates.
+	for _, pattern := range []string{"EXAMPLE.org", "{name string}.example.net:{p uint}"} {
+		func() {
+			defer func() {
+				var dup *mux.DuplicateError
+				if err, _ := recover().(error); !errors.As(err, &dup) {
+					t.Errorf("Expected host pattern %q to panic with a DuplicateError, got %v", pattern, err)
+				}
+			}()
+			mux.HandleConnect(pattern, failHandler(t))(m)
+		}()
+	}
+}
+
+func TestConnectConcurrent(t *testing.T) {
+	m := mux.New()
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				req := httptest.NewRequest(http.MethodConnect, "/", nil)
+				req.Host = "0.example.net:443"
+				m.ServeHTTP(httptest.NewRecorder(), req)
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		mux.HandleConnect(strconv.Itoa(i)+".example.net", codeHandler(t, 200))(m)
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestHeadWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := mux.NewHeadWriter(rec)
+	w.Header().Set("X-Test", "yes")
+	w.WriteHeader(http.StatusAccepted)
+	n, err := w.Write([]byte(testBody))
+	if n != len(testBody) || err != nil {
+		t.Errorf("Unexpected result from write: n=%d, err=%v", n, err)
+	}
+	if rec.Code != http.StatusOK || rec.Flushed {
+		t.Errorf("Did not expect headers to be written before finishing")
+	}
+	w.Finish()
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("Unexpected status code: want=%d, got=%d", http.StatusAccepted, rec.Code)
+	}
+	if l := rec.Header().Get("Content-Length"); l != strconv.Itoa(len(testBody)) {
+		t.Errorf("Unexpected Content-Length: want=%d, got=%q", len(testBody), l)
+	}
+	if rec.Header().Get("X-Test") != "yes" || rec.Body.Len() != 0 || w.Written() != int64(len(testBody)) {
+		t.Errorf("Unexpected response: headers=%v, body=%q, written=%d", rec.Header(), rec.Body, w.Written())
+	}
+
+	rec = httptest.NewRecorder()
+	w = mux.NewHeadWriter(rec)
+	w.Write([]byte(testBody))
+	w.Flush()
+	w.Finish()
+	if !rec.Flushed || rec.Code != http.StatusOK || rec.Header().Get("Content-Length") != "" {
+		t.Errorf("Unexpected response after flush: flushed=%t, code=%d, headers=%v", rec.Flushed, rec.Code, rec.Header())
+	}
+}
+
+func TestAutoHead(t *testing.T) {
+	get := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Method", r.Method)
+		fmt.Fprint(w, testBody)
+	})
+	for i, tc := range []struct {
+		opts    []mux.Option
+		method  string
+		code    int
+		allowed string
+	}{
+		0: {opts: []mux.Option{mux.AutoHead(true)}, method: http.MethodHead, code: http.StatusOK},
+		1: {method: http.MethodHead, code: http.StatusMethodNotAllowed, allowed: "GET,OPTIONS"},
+		2: {opts: []mux.Option{mux.AutoHead(true)}, method: http.MethodPut, code: http.StatusMethodNotAllowed, allowed: "GET,HEAD,OPTIONS"},
+		3: {opts: []mux.Option{mux.AutoHead(true), mux.Handle(http.MethodHead, "/user", codeHandler(t, 201))}, method: http.MethodHead, code: 201},
+	} {
+		opts := append([]mux.Option{
+			mux.Handle(http.MethodGet, "/user", get),
+			mux.MethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Allowed", strings.Join(mux.Allowed(r), ","))
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			})),
+		}, tc.opts...)
+		m := mux.New(opts...)
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(tc.method, "/user", nil))
+		if rec.Code != tc.code {
+			t.Errorf("%d: unexpected status code: want=%d, got=%d", i, tc.code, rec.Code)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("%d: unexpected body: %q", i, rec.Body)
+		}
+		if tc.code == http.StatusOK && rec.Header().Get("Content-Length") != strconv.Itoa(len(testBody)) {
+			t.Errorf("%d: unexpected headers: %v", i, rec.Header())
+		}
+		if allowed := rec.Header().Get("X-Allowed"); allowed != tc.allowed {
+			t.Errorf("%d: unexpected allowed methods: want=%q, got=%q", i, tc.allowed, allowed)
+		}
+	}
+}
+
+func TestTraceExcludesCredentials(t *testing.T) {
+	m := mux.New(mux.Trace(true))
+	req := httptest.NewRequest(http.MethodTrace, "/", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Cookie", "session=secret")
+	req.Header.Set("X-Test", "visible")
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	body := rec.Body.String()
+	if strings.Contains(body, "secret") || !strings.Contains(body, "X-Test: visible\r\n") {
+		t.Errorf("Unexpected TRACE response body: %q", body)
+	}
+}
+
+func TestOptionsAllowUpdated(t *testing.T) {
+	m := mux.New(
+		mux.HandleMethods([]string{http.MethodPost, http.MethodGet, http.MethodDelete}, "/a", http.NotFoundHandler()),
+	)
+	allow := func() string {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/a", nil))
+		return rec.Header().Get("Allow")
+	}
+	if a := allow(); a != "DELETE,GET,OPTIONS,POST" {
+		t.Errorf("Unexpected Allow header: want=%q, got=%q", "DELETE,GET,OPTIONS,POST", a)
+	}
+	m.Remove(http.MethodPost, "/a")
+	if a := allow(); a != "DELETE,GET,OPTIONS" {
+		t.Errorf("Unexpected Allow header after removal: want=%q, got=%q", "DELETE,GET,OPTIONS", a)
+	}
+	m.Handle(http.MethodPut, "/a", http.NotFoundHandler())
+	if a := allow(); a != "DELETE,GET,OPTIONS,PUT" {
+		t.Errorf("Unexpected Allow header after registration: want=%q, got=%q", "DELETE,GET,OPTIONS,PUT", a)
+	}
+}
+
+var methodNotAllowedTests = [...]struct {
+	routes []string
+	method string
+	req    string
+	code   int
+	allow  string
+}{
+	0: {routes: []string{"/"}, method: http.MethodPost, req: "/", code: http.StatusMethodNotAllowed, allow: "GET"},
+	1: {routes: []string{"/a/b"}, method: http.MethodGet, req: "/", code: http.StatusNotFound},
+	2: {routes: []string{"/a"}, method: http.MethodPost, req: "/a", code: http.StatusMethodNotAllowed, allow: "GET"},
+	3: {routes: []string{"/", "/a/b"}, method: http.MethodGet, req: "/a", code: http.StatusNotFound},
+	4: {routes: []string{"/", "/a/b"}, method: http.MethodPost, req: "/a/b", code: http.StatusMethodNotAllowed, allow: "GET"},
+	5: {routes: []string{"/{id int}"}, method: http.MethodPost, req: "/1", code: http.StatusMethodNotAllowed, allow: "GET"},
+	6: {routes: []string{"/", "/{id int}/a"}, method: http.MethodGet, req: "/1", code: http.StatusNotFound},
+	7: {routes: []string{"/a/b/c/d/e"}, method: http.MethodPost, req: "/a/b/c/d/e", code: http.StatusMethodNotAllowed, allow: "GET"},
+	8: {routes: []string{"/", "/a/b/c/d/e"}, method: http.MethodGet, req: "/a/b/c", code: http.StatusNotFound},
+}
+
+func TestMethodNotAllowedNode(t *testing.T) {
+	for i, tc := range methodNotAllowedTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			opts := []mux.Option{
+				mux.Options(nil),
+				mux.MethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					allowedHandler()(w, r)
+				})),
+			}
+			for _, route := range tc.routes {
+				opts = append(opts, mux.Handle(http.MethodGet, route, http.NotFoundHandler()))
+			}
+			m := mux.New(opts...)
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.req, nil))
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+			if allow := rec.Body.String(); tc.code == http.StatusMethodNotAllowed && allow != tc.allow {
+				t.Errorf("Unexpected allowed methods: want=%q, got=%q", tc.allow, allow)
+			}
+		})
+	}
+}
+
+var badRequestTests = [...]struct {
+	path  string
+	code  int
+	raw   string
+	name  string
+	typ   string
+	unset bool
+}{
+	0: {path: "/orders/abc", code: http.StatusBadRequest, raw: "abc", name: "id", typ: "uint"},
+	1: {path: "/orders/1/items/x", code: http.StatusBadRequest, raw: "x", name: "item", typ: "int"},
+	2: {path: "/orders/1", code: testCode},
+	3: {path: "/orders/1/other", code: http.StatusNotFound},
+	4: {path: "/files/abc", code: 201},
+	5: {path: "/orders/abc", code: http.StatusNotFound, unset: true},
+}
+
+func TestBadRequestOnTypeMismatch(t *testing.T) {
+	for i, tc := range badRequestTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var pinfo mux.ParamInfo
+			var ok bool
+			opts := []mux.Option{
+				mux.Handle(http.MethodGet, "/orders/{id uint}", codeHandler(t, testCode)),
+				mux.Handle(http.MethodGet, "/orders/{id uint}/items/{item int}", failHandler(t)),
+				mux.Handle(http.MethodGet, "/files/{id uint}", failHandler(t)),
+				mux.Subtree(http.MethodGet, "/files/", codeHandler(t, 201)),
+			}
+			if !tc.unset {
+				opts = append(opts, mux.BadRequestOnTypeMismatch(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					pinfo, ok = mux.TypeMismatch(r)
+					w.Write([]byte(pinfo.Raw))
+				})))
+			}
+			m := mux.New(opts...)
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			res, _ := m.Lookup(req)
+			if kind := res.Kind; (kind == mux.KindBadRequest) != (tc.code == http.StatusBadRequest) {
+				t.Errorf("Unexpected match kind: %v", kind)
+			}
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, req)
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+			if ok != (tc.code == http.StatusBadRequest) {
+				t.Fatalf("Unexpected type mismatch: %+v", pinfo)
+			}
+			if pinfo.Raw != tc.raw || pinfo.Name != tc.name || pinfo.Type != tc.typ {
+				t.Errorf("Unexpected type mismatch: want=%q %q %q, got=%q %q %q", tc.raw, tc.name, tc.typ, pinfo.Raw, pinfo.Name, pinfo.Type)
+			}
+		})
+	}
+}
+
+var defaultMethodTests = [...]struct {
+	method string
+	path   string
+	code   int
+	allow  string
+}{
+	0: {method: http.MethodDelete, path: "/user/1", code: http.StatusMethodNotAllowed, allow: "GET,HEAD,OPTIONS,PUT"},
+	1: {method: "PROPFIND", path: "/user/1", code: http.StatusNotImplemented, allow: "GET,HEAD,OPTIONS,PUT"},
+	2: {method: http.MethodPost, path: "/user/1", code: http.StatusMethodNotAllowed, allow: "GET,HEAD,OPTIONS,PUT"},
+	3: {method: http.MethodPut, path: "/user/1", code: testCode},
+	4: {method: http.MethodDelete, path: "/files/a", code: testStatusCode},
+	5: {method: http.MethodDelete, path: "/nope", code: http.StatusNotFound},
+	6: {method: http.MethodOptions, path: "/user/1", code: http.StatusOK, allow: "GET,HEAD,OPTIONS,PUT"},
+}
+
+func TestDefaultMethod(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/user/{id uint}", failHandler(t)),
+		mux.Handle(http.MethodPut, "/user/{id uint}", codeHandler(t, testCode)),
+		mux.Handle(http.MethodDelete, "/files/{p path}", codeHandler(t, testStatusCode)),
+		mux.DefaultMethod(http.MethodDelete, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if got := strings.Join(mux.Allowed(r), ","); got != "GET,HEAD,OPTIONS,PUT" {
+				t.Errorf("Unexpected allowed methods: want=%q, got=%q", "GET,HEAD,OPTIONS,PUT", got)
+			}
+			http.Error(w, "deletion is done via the async API", http.StatusMethodNotAllowed)
+		})),
+		mux.DefaultMethod("propfind", codeHandler(t, http.StatusNotImplemented)),
+		mux.DefaultMethod(http.MethodPost, codeHandler(t, http.StatusTeapot)),
+		mux.DefaultMethod(http.MethodPost, nil),
+		mux.AutoHead(true),
+	)
+	for i, tc := range defaultMethodTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+			if allow := rec.Header().Get("Allow"); allow != tc.allow {
+				t.Errorf("Unexpected Allow header: want=%q, got=%q", tc.allow, allow)
+			}
+		})
+	}
+}
+
+func TestOnMethodNotAllowed(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodPut, "/upload/{id uint}", failHandler(t), mux.OnMethodNotAllowed(codeHandler(t, testStatusCode))),
+		mux.Handle(http.MethodPatch, "/upload/{id uint}", failHandler(t)),
+		mux.Handle(http.MethodGet, "/files/{id uint}", failHandler(t), mux.OnMethodNotAllowed(codeHandler(t, testCode))),
+		mux.Handle(http.MethodPut, "/files/{id uint}", failHandler(t), mux.OnMethodNotAllowed(allowedHandler())),
+		mux.Handle(http.MethodGet, "/user/{id uint}", failHandler(t)),
+		mux.MethodNotAllowed(codeHandler(t, notFoundStatusCode)),
+		mux.DefaultMethod(http.MethodDelete, codeHandler(t, http.StatusNotImplemented)),
+	)
+	for i, tc := range [...]struct {
+		update func()
+		method string
+		path   string
+		code   int
+		allow  string
+	}{
+		0: {method: http.MethodPost, path: "/upload/1", code: testStatusCode, allow: "OPTIONS,PATCH,PUT"},
+		1: {method: http.MethodPost, path: "/files/1", code: http.StatusOK, allow: "GET,OPTIONS,PUT"},
+		2: {method: http.MethodPost, path: "/user/1", code: notFoundStatusCode, allow: "GET,OPTIONS"},
+		3: {method: http.MethodDelete, path: "/upload/1", code: http.StatusNotImplemented, allow: "OPTIONS,PATCH,PUT"},
+		4: {
+			update: func() { mux.Override(http.MethodPut, "/upload/{id uint}", failHandler(t))(m) },
+			method: http.MethodPost, path: "/upload/1", code: notFoundStatusCode, allow: "OPTIONS,PATCH,PUT",
+		},
+		5: {
+			update: func() { m.Remove(http.MethodPut, "/files/{id uint}") },
+			method: http.MethodPost, path: "/files/1", code: testCode, allow: "GET,OPTIONS",
+		},
+		6: {
+			update: func() { m.Remove(http.MethodGet, "/files/{id uint}") },
+			method: http.MethodPost, path: "/files/1", code: http.StatusNotFound,
+		},
+		7: {
+			update: func() {
+				mux.Override(http.MethodGet, "/user/{id uint}", failHandler(t), mux.OnMethodNotAllowed(codeHandler(t, testCode)))(m)
+			},
+			method: http.MethodPost, path: "/user/1", code: testCode, allow: "GET,OPTIONS",
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if tc.update != nil {
+				tc.update()
+			}
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+			if allow := rec.Header().Get("Allow"); allow != tc.allow {
+				t.Errorf("Unexpected Allow header: want=%q, got=%q", tc.allow, allow)
+			}
+		})
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	var flag bool
+	enabled := mux.Enabled(func(*http.Request) bool {
+		return flag
+	})
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/beta", codeHandler(t, testStatusCode), enabled),
+		mux.Handle(http.MethodPost, "/beta", codeHandler(t, testCode)),
+		mux.Handle(http.MethodGet, "/new/{id uint}", codeHandler(t, testStatusCode), enabled),
+		mux.Handle(http.MethodGet, "/api/v2", codeHandler(t, testStatusCode), enabled),
+		mux.Subtree(http.MethodGet, "/api/", codeHandler(t, testCode)),
+		mux.NotFound(codeHandler(t, notFoundStatusCode)),
+	)
+	for i, tc := range [...]struct {
+		flag   bool
+		method string
+		path   string
+		code   int
+		allow  string
+	}{
+		0: {flag: true, method: http.MethodGet, path: "/beta", code: testStatusCode},
+		1: {method: http.MethodGet, path: "/beta", code: http.StatusMethodNotAllowed, allow: "OPTIONS,POST"},
+		2: {flag: true, method: http.MethodOptions, path: "/beta", code: http.StatusOK, allow: "GET,OPTIONS,POST"},
+		3: {method: http.MethodOptions, path: "/beta", code: http.StatusOK, allow: "OPTIONS,POST"},
+		4: {flag: true, method: http.MethodGet, path: "/new/1", code: testStatusCode},
+		5: {method: http.MethodGet, path: "/new/1", code: notFoundStatusCode},
+		6: {flag: true, method: http.MethodGet, path: "/api/v2", code: testStatusCode},
+		7: {method: http.MethodGet, path: "/api/v2", code: testCode},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			flag = tc.flag
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+			if allow := rec.Header().Get("Allow"); allow != tc.allow {
+				t.Errorf("Unexpected Allow header: want=%q, got=%q", tc.allow, allow)
+			}
+		})
+	}
+}
+
+func TestEnabledAllocs(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/a/{a int}", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/b/{a int}", http.NotFoundHandler(), mux.Enabled(func(r *http.Request) bool {
+			return r.Header.Get("Beta") != ""
+		})),
+	)
+	allocs := func(path string) float64 {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Beta", "1")
+		return testing.AllocsPerRun(100, func() {
+			m.Handler(req)
+		})
+	}
+	if plain, gated := allocs("/a/1"), allocs("/b/1"); gated != plain {
+		t.Errorf("Expected gated routes not to allocate: plain=%v, gated=%v", plain, gated)
+	}
+}
+
+func TestAllowAllocs(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/a/{id int}", http.NotFoundHandler()),
+		mux.Handle(http.MethodPost, "/a/{id int}", http.NotFoundHandler()),
+		mux.AutoHead(true),
+	)
+	// Routes registered after New must be precomputed as well.
+	m.Handle(http.MethodGet, "/b/", http.NotFoundHandler())
+	c, err := m.Compile()
+	if err != nil {
+		t.Fatalf("Unexpected error compiling: %v", err)
+	}
+	for _, tc := range []struct {
+		method string
+		path   string
+	}{
+		{method: http.MethodOptions, path: "/a/1"},
+		{method: http.MethodDelete, path: "/a/1"},
+		{method: http.MethodOptions, path: "/b/"},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		served := testing.AllocsPerRun(100, func() {
+			m.Handler(req)
+		})
+		compiled := testing.AllocsPerRun(100, func() {
+			c.Handler(req)
+		})
+		if served != compiled {
+			t.Errorf("Expected the Allow header for %s %s to be computed in advance: served=%v, compiled=%v", tc.method, tc.path, served, compiled)
+		}
+	}
+}
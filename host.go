@@ -0,0 +1,96 @@
+package mux
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// HostMux is an HTTP handler that routes requests to a ServeMux based on the
+// host of the request.
+//
+// It is simpler than using host patterns in routes: each host is served by an
+// independent ServeMux with its own routes and options.
+type HostMux struct {
+	hosts map[string]*ServeMux
+	// suffixes holds the wildcard hosts, keyed by the suffix that they match
+	// (including the leading dot).
+	suffixes map[string]*ServeMux
+	fallback *ServeMux
+}
+
+// NewHostMux returns a HostMux that routes requests for each of the hosts to the
+// corresponding ServeMux and any other request to fallback.
+//
+// Hosts are matched case insensitively and without any port or trailing dot,
+// both in the keys of hosts and in requests.
+// A host beginning with "*." (for example *.example.com) matches any subdomain
+// of the rest of the host, but not the host itself.
+// Exact matches take precedence over wildcards and longer wildcards take
+// precedence over shorter ones.
+// Requests without a Host header are routed to fallback.
+// If fallback is nil, requests that do not match any host receive a 404 Not
+// Found response.
+func NewHostMux(hosts map[string]*ServeMux, fallback *ServeMux) *HostMux {
+	h := &HostMux{
+		hosts:    make(map[string]*ServeMux),
+		suffixes: make(map[string]*ServeMux),
+		fallback: fallback,
+	}
+	for host, mux := range hosts {
+		host = normalizeHost(host)
+		if strings.HasPrefix(host, "*.") {
+			h.suffixes[host[1:]] = mux
+			continue
+		}
+		h.hosts[host] = mux
+	}
+	return h
+}
+
+// Lookup returns the ServeMux that requests for host are routed to.
+// host may include a port.
+// If no ServeMux is registered for host the fallback is returned, which may be
+// nil.
+func (h *HostMux) Lookup(host string) *ServeMux {
+	host = normalizeHost(host)
+	if host == "" {
+		return h.fallback
+	}
+	if mux, ok := h.hosts[host]; ok {
+		return mux
+	}
+	// Try each parent domain in turn so that the longest suffix wins.
+	for i := strings.IndexByte(host, '.'); i != -1; {
+		if mux, ok := h.suffixes[host[i:]]; ok {
+			return mux
+		}
+		next := strings.IndexByte(host[i+1:], '.')
+		if next == -1 {
+			break
+		}
+		i += 1 + next
+	}
+	return h.fallback
+}
+
+// normalizeHost lowercases host and removes any port, the brackets around an
+// IPv6 address, and the trailing dot of a fully qualified domain name.
+func normalizeHost(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	} else if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// ServeHTTP dispatches the request to the ServeMux for the request host.
+func (h *HostMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mux := h.Lookup(r.Host)
+	if mux == nil {
+		http.NotFound(w, r)
+		return
+	}
+	mux.ServeHTTP(w, r)
+}
@@ -0,0 +1,98 @@
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var hostMuxTests = [...]struct {
+	host string
+	code int
+}{
+	0:  {host: "example.com", code: 201},
+	1:  {host: "EXAMPLE.com:8080", code: 201},
+	2:  {host: "example.com.", code: 201},
+	3:  {host: "www.example.com", code: 202},
+	4:  {host: "a.b.example.com", code: 202},
+	5:  {host: "api.example.com", code: 203},
+	6:  {host: "v1.api.example.com", code: 204},
+	7:  {host: "example.net", code: 205},
+	8:  {host: "", code: 205},
+	9:  {host: "[::1]:8080", code: 206},
+	10: {host: "notexample.com", code: 205},
+	11: {host: "[::1]", code: 206},
+}
+
+func TestHostMux(t *testing.T) {
+	muxFor := func(code int) *mux.ServeMux {
+		return mux.New(mux.Handle(http.MethodGet, "/", codeHandler(t, code)))
+	}
+	h := mux.NewHostMux(map[string]*mux.ServeMux{
+		"Example.com":       muxFor(201),
+		"*.example.com":     muxFor(202),
+		"api.example.com":   muxFor(203),
+		"*.api.example.com": muxFor(204),
+		"::1":               muxFor(206),
+	}, muxFor(205))
+	for i, tc := range hostMuxTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = tc.host
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code for host %q: want=%d, got=%d", tc.host, tc.code, rec.Code)
+			}
+		})
+	}
+}
+
+func TestHostMuxNoFallback(t *testing.T) {
+	m := mux.New()
+	h := mux.NewHostMux(map[string]*mux.ServeMux{"example.com": m}, nil)
+	if got := h.Lookup("example.com:443"); got != m {
+		t.Errorf("Unexpected mux for known host: want=%p, got=%p", m, got)
+	}
+	if got := h.Lookup("example.net"); got != nil {
+		t.Errorf("Expected no mux for unknown host, got %p", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "example.net"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Unexpected status code: want=%d, got=%d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestHostMuxKeys(t *testing.T) {
+	a, b, c := mux.New(), mux.New(), mux.New()
+	h := mux.NewHostMux(map[string]*mux.ServeMux{
+		"Example.COM.:8443": a,
+		"*.Example.org.":    b,
+		"[::2]:80":          c,
+	}, nil)
+	for i, tc := range [...]struct {
+		host string
+		want *mux.ServeMux
+	}{
+		0: {host: "example.com", want: a},
+		1: {host: "EXAMPLE.com.:443", want: a},
+		2: {host: "www.example.org", want: b},
+		3: {host: "WWW.example.ORG.:8080", want: b},
+		4: {host: "example.org"},
+		5: {host: "[::2]", want: c},
+		6: {host: "::2", want: c},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if got := h.Lookup(tc.host); got != tc.want {
+				t.Errorf("Unexpected mux for host %q: want=%p, got=%p", tc.host, tc.want, got)
+			}
+		})
+	}
+}
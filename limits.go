@@ -0,0 +1,99 @@
+package mux
+
+import (
+	"net/http"
+	"strings"
+)
+
+// The default limits on the size of request paths.
+// They are far larger than any path that a well behaved client sends, but
+// small enough that a single request cannot make the ServeMux parse and store
+// an arbitrarily large parameter.
+const (
+	defMaxParamLength    = 4096
+	defMaxWildcardLength = 16384
+	defMaxSegments       = 1024
+)
+
+// MaxParamLength sets the maximum length in bytes of a path component that is
+// matched against a route parameter other than a wildcard.
+// Requests with a longer component in the position of a parameter are not
+// matched against the rest of the tree and are passed to the LimitExceeded
+// handler.
+// If n is 0 there is no limit. The default is 4096.
+func MaxParamLength(n int) Option {
+	return func(mux *ServeMux) {
+		mux.maxParamLen = n
+	}
+}
+
+// MaxWildcardLength sets the maximum length in bytes of the remainder of the
+// path that is matched against a parameter of type path.
+// Requests with a longer remainder are passed to the LimitExceeded handler.
+// If n is 0 there is no limit. The default is 16384.
+func MaxWildcardLength(n int) Option {
+	return func(mux *ServeMux) {
+		mux.maxWildLen = n
+	}
+}
+
+// MaxSegments sets the maximum number of components in a request path.
+// Requests with more components are passed to the LimitExceeded handler
+// without being matched against the tree.
+// If n is 0 there is no limit. The default is 1024.
+func MaxSegments(n int) Option {
+	return func(mux *ServeMux) {
+		mux.maxSegments = n
+	}
+}
+
+// LimitExceeded sets the handler to call when a request path exceeds one of
+// the limits set by MaxParamLength, MaxWildcardLength, or MaxSegments.
+//
+// If the provided handler does not set the status code, it is set to 414 (URI
+// Too Long) by default if a parameter was too long, or 404 (Not Found) if the
+// path had too many components.
+// By default, or if h is nil, requests with a parameter that is too long
+// receive a plain 414 response and requests with too many components are
+// handled by the NotFound handler.
+func LimitExceeded(h http.Handler) Option {
+	return func(mux *ServeMux) {
+		mux.limitExceeded = h
+	}
+}
+
+// tooLong reports whether the part of path that would be matched against the
+// variable node n exceeds the configured limits.
+func (mux *ServeMux) tooLong(n *node, path string) bool {
+	if n.typ == typWild {
+		return mux.maxWildLen > 0 && len(path) > mux.maxWildLen
+	}
+	if mux.maxParamLen <= 0 || len(path) <= mux.maxParamLen {
+		return false
+	}
+	return strings.IndexByte(path[:mux.maxParamLen+1], '/') == -1
+}
+
+// tooMany reports whether path, which has had its leading slash removed, has
+// more components than the configured limit.
+func (mux *ServeMux) tooMany(path string) bool {
+	return mux.maxSegments > 0 && strings.Count(path, "/") >= mux.maxSegments
+}
+
+// limitHandler returns the handler to use for a request that exceeded one of
+// the limits on the size of the request path.
+func (mux *ServeMux) limitHandler(kind matchKind) http.Handler {
+	code := http.StatusRequestURITooLong
+	if kind == kindTooMany {
+		code = http.StatusNotFound
+	}
+	switch {
+	case mux.limitExceeded != nil:
+		return defCodeHandler(mux.limitExceeded, code)
+	case kind == kindTooMany:
+		return mux.notFound
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(code), code)
+	})
+}
@@ -0,0 +1,88 @@
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var limitsTests = [...]struct {
+	path string
+	code int
+	kind mux.MatchKind
+}{
+	0: {path: "/user/" + strings.Repeat("a", 8), code: testCode, kind: mux.KindMatched},
+	1: {path: "/user/" + strings.Repeat("a", 9), code: http.StatusRequestURITooLong, kind: mux.KindLimitExceeded},
+	2: {path: "/user/" + strings.Repeat("a", 9) + "/x", code: http.StatusRequestURITooLong, kind: mux.KindLimitExceeded},
+	3: {path: "/files/a/" + strings.Repeat("a", 14), code: testCode, kind: mux.KindMatched},
+	4: {path: "/files/a/" + strings.Repeat("a", 15), code: http.StatusRequestURITooLong, kind: mux.KindLimitExceeded},
+	5: {path: "/a/b/c/d", code: notFoundStatusCode, kind: mux.KindNotFound},
+	6: {path: "/a/b/c/d/e", code: notFoundStatusCode, kind: mux.KindLimitExceeded},
+	7: {path: "/" + strings.Repeat("a", 100), code: notFoundStatusCode, kind: mux.KindNotFound},
+}
+
+func TestLimits(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/user/{name string}", codeHandler(t, testCode)),
+		mux.Handle(http.MethodGet, "/user/{name string}/x", codeHandler(t, testCode)),
+		mux.Handle(http.MethodGet, "/files/{p path}", codeHandler(t, testCode)),
+		mux.MaxParamLength(8),
+		mux.MaxWildcardLength(16),
+		mux.MaxSegments(4),
+		mux.NotFound(codeHandler(t, notFoundStatusCode)),
+	)
+	for i, tc := range limitsTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			res, _ := m.Lookup(req)
+			if res.Kind != tc.kind {
+				t.Errorf("Unexpected kind: want=%v, got=%v", tc.kind, res.Kind)
+			}
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, req)
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected code: want=%d, got=%d", tc.code, rec.Code)
+			}
+		})
+	}
+}
+
+func TestLimitExceeded(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/user/{name string}", failHandler(t)),
+		mux.MaxParamLength(4),
+		mux.MaxSegments(2),
+		mux.LimitExceeded(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("limit"))
+		})),
+	)
+	for path, code := range map[string]int{
+		"/user/alice": http.StatusRequestURITooLong,
+		"/a/b/c":      http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != code {
+			t.Errorf("Unexpected code for %s: want=%d, got=%d", path, code, rec.Code)
+		}
+		if body := rec.Body.String(); body != "limit" {
+			t.Errorf("Unexpected body for %s: want=%q, got=%q", path, "limit", body)
+		}
+	}
+}
+
+func TestLimitsDisabled(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/user/{name string}", codeHandler(t, testCode)),
+		mux.MaxParamLength(0),
+	)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user/"+strings.Repeat("a", 1<<16), nil))
+	if rec.Code != testCode {
+		t.Errorf("Unexpected code: want=%d, got=%d", testCode, rec.Code)
+	}
+}
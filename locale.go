@@ -0,0 +1,71 @@
+package mux
+
+import (
+	"net/http"
+	"strings"
+)
+
+// LocalePrefix causes a first path component that matches one of the supported
+// locales (for example /de/about) to be removed from the path before it is
+// matched against routes, so that the same routes are used for every locale.
+// Locales are compared case insensitively.
+// The locale can be retrieved from within handlers as a string parameter with
+// the given name using Param, and it is included in the path returned by Path.
+//
+// Requests without a supported locale are matched against the routes as they
+// are unless DefaultLocale is used.
+// Paths passed to Match must not include the locale.
+func LocalePrefix(supported []string, param string) Option {
+	return func(mux *ServeMux) {
+		mux.locales = make(map[string]bool, len(supported))
+		for _, locale := range supported {
+			mux.locales[strings.ToLower(locale)] = true
+		}
+		mux.localeParam = param
+	}
+}
+
+// DefaultLocale causes requests that do not start with one of the locales
+// configured using LocalePrefix to be redirected to the same path prefixed
+// with locale, preserving the query string.
+// If code is 0, http.StatusFound is used.
+func DefaultLocale(locale string, code int) Option {
+	if code == 0 {
+		code = http.StatusFound
+	}
+	return func(mux *ServeMux) {
+		mux.localeDefault = locale
+		mux.localeCode = code
+	}
+}
+
+// splitLocale removes a supported locale from the start of path and returns it
+// as a parameter along with the rest of the path.
+// If path does not start with a supported locale, it is returned unaltered and
+// ok is false.
+func (mux *ServeMux) splitLocale(path string) (locale ParamInfo, rest string, ok bool) {
+	part, remain := nextPart(strings.TrimPrefix(path, "/"))
+	if part == "" || !mux.locales[strings.ToLower(part)] {
+		return ParamInfo{}, path, false
+	}
+	locale = ParamInfo{
+		Value:   part,
+		Raw:     part,
+		Encoded: part,
+		Name:    mux.localeParam,
+		Type:    typString,
+
+		tail: len(path) - 1,
+	}
+	return locale, "/" + remain, true
+}
+
+// defaultLocalePath returns the location to redirect r to when it does not
+// have a locale prefix.
+func (mux *ServeMux) defaultLocalePath(r *http.Request) string {
+	loc := "/" + mux.localeDefault + r.URL.EscapedPath()
+	if r.URL.RawQuery != "" {
+		loc += "?" + r.URL.RawQuery
+	}
+	return loc
+}
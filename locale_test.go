@@ -0,0 +1,86 @@
+package mux_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+func localeHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, err := mux.Path(r)
+		if err != nil {
+			t.Errorf("Error while generating canonical path: %v", err)
+		}
+		fmt.Fprintf(w, "%s %s", mux.Param(r, "lang").Raw, p)
+	}
+}
+
+var localeTests = [...]struct {
+	path     string
+	code     int
+	body     string
+	location string
+}{
+	0: {path: "/en/users/5", code: http.StatusOK, body: "en /en/users/5"},
+	1: {path: "/pt-BR/users/5/", code: http.StatusOK, body: "pt-BR /pt-BR/users/5/"},
+	2: {path: "/users/5", code: http.StatusOK, body: " /users/5"},
+	3: {path: "/fr/users/5", code: http.StatusNotFound},
+	4: {path: "/de/Users/5", code: http.StatusPermanentRedirect, location: "/de/users/5"},
+	5: {path: "/de/users/5/files", code: http.StatusPermanentRedirect, location: "/de/users/5/files/"},
+	6: {path: "/de//users/5", code: http.StatusPermanentRedirect, location: "/de/users/5"},
+}
+
+func TestLocalePrefix(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/users/{id uint}", localeHandler(t)),
+		mux.Handle(http.MethodGet, "/users/{id uint}/", localeHandler(t)),
+		mux.Handle(http.MethodGet, "/users/{id uint}/files/", localeHandler(t)),
+		mux.LocalePrefix([]string{"en", "de", "pt-br"}, "lang"),
+		mux.RedirectFixedPath(0),
+		mux.RedirectTrailingSlash(0),
+	)
+	for i, tc := range localeTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+			if tc.code == http.StatusOK {
+				if body := rec.Body.String(); body != tc.body {
+					t.Errorf("Unexpected body: want=%q, got=%q", tc.body, body)
+				}
+			}
+			if loc := rec.Header().Get("Location"); loc != tc.location {
+				t.Errorf("Unexpected location: want=%q, got=%q", tc.location, loc)
+			}
+		})
+	}
+}
+
+func TestDefaultLocale(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/users/{id uint}", localeHandler(t)),
+		mux.LocalePrefix([]string{"en", "de"}, "lang"),
+		mux.DefaultLocale("en", 0),
+	)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/5?a=b", nil))
+	if rec.Code != http.StatusFound {
+		t.Errorf("Unexpected status code: want=%d, got=%d", http.StatusFound, rec.Code)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/en/users/5?a=b" {
+		t.Errorf("Unexpected location: want=%q, got=%q", "/en/users/5?a=b", loc)
+	}
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/de/users/5", nil))
+	if body := rec.Body.String(); rec.Code != http.StatusOK || body != "de /de/users/5" {
+		t.Errorf("Unexpected response: %d %q", rec.Code, body)
+	}
+}
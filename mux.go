@@ -4,14 +4,99 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	neturl "net/url"
 	"path"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// ctxRoute is a type used as the context key when storing a route on the HTTP
-// context for future use.
+// ctxRoute is a type used as the context key when storing the matched route on
+// the HTTP context for future use as a *routeContext.
 type ctxRoute struct{}
 
+// routeContext is the information about a matched route that is stored on the
+// request context.
+type routeContext struct {
+	// ep is the endpoint that was matched, if any.
+	ep *endpoint
+	// params are the named route parameters that were matched against the
+	// request path.
+	// Their values are not parsed until they are first needed, after which the
+	// slice is never modified.
+	// It must only be accessed using values.
+	params []ParamInfo
+	// rest is the remainder of the path below the subtree that matched, if the
+	// endpoint was registered using Subtree.
+	rest string
+	// locale is the name of the parameter holding the locale prefix that was
+	// removed from the path before it was matched, if any.
+	locale string
+	// arm is the arm of the split endpoint that the request was sent to, if
+	// any.
+	arm string
+	// escaped is the escaped form of the request path if the parameters were
+	// matched against its unescaped form, which has length pathLen.
+	// It is used to set the Encoded field of each parameter.
+	escaped string
+	pathLen int
+	once    sync.Once
+}
+
+// values returns the route parameters with their values set.
+func (rc *routeContext) values() []ParamInfo {
+	rc.once.Do(func() {
+		withValues(rc.params)
+		if rc.escaped != "" {
+			withEncoded(rc.params, rc.pathLen, rc.escaped)
+		}
+	})
+	return rc.params
+}
+
+// routeFrom returns the route information stored on the context of r, or nil
+// if r was not routed.
+func routeFrom(r *http.Request) *routeContext {
+	rc, _ := r.Context().Value(ctxRoute{}).(*routeContext)
+	return rc
+}
+
+// withRoute returns a shallow copy of r with the route information rc set on
+// its context.
+// If rc does not hold anything that can be retrieved from the request, r is
+// returned unaltered.
+func withRoute(r *http.Request, rc *routeContext) *http.Request {
+	if rc.empty() {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), ctxRoute{}, rc))
+}
+
+// empty reports whether rc has no parameters and its endpoint, if any, has no
+// values that handlers or the ServeMux retrieve from the request.
+func (rc *routeContext) empty() bool {
+	if len(rc.params) > 0 || rc.rest != "" || rc.locale != "" || rc.arm != "" {
+		return false
+	}
+	ep := rc.ep
+	if ep == nil {
+		return true
+	}
+	return ep.meta == nil && ep.name == "" && !ep.subtree && ep.shadow == nil && len(ep.values) == 0 && !ep.hasParams()
+}
+
+// ctxMismatch is a type used as the context key when storing the parameter
+// that could not be parsed on the HTTP context before calling the bad request
+// handler.
+type ctxMismatch struct{}
+
+// ctxAllowed is a type used as the context key when storing the methods that
+// may be used with a route on the HTTP context before calling the method not
+// allowed handler.
+type ctxAllowed struct{}
+
 const (
 	typStatic = "static"
 	typWild   = "path"
@@ -19,44 +104,272 @@ const (
 	typUint   = "uint"
 	typInt    = "int"
 	typFloat  = "float"
+	// typEnd is the type of the {$} component, which may only appear at the end
+	// of a route and is equivalent to a trailing slash.
+	typEnd = "$"
 )
 
 // ServeMux is an HTTP request multiplexer.
 // It matches the URL of each incoming request against a list of registered
 // patterns and calls the handler for the pattern that most closely matches the
 // URL.
+//
+// Routes may be registered after the ServeMux has been created and while it is
+// serving requests.
+// Each request is routed using the set of routes that was registered when it
+// began routing: in-flight requests never observe routes added after they
+// started, and requests that begin after a call to Handle returns always do.
 type ServeMux struct {
-	node             node
+	// mu serializes changes to the tree and to the CONNECT routes.
+	mu sync.Mutex
+	// tree holds the *routeTree that is currently published.
+	// Once the ServeMux is returned from New the tree is never modified in place;
+	// instead a modified copy is stored.
+	tree      atomic.Value
+	published bool
+	// connect holds the []connectRoute that is currently published, which is
+	// likewise replaced instead of modified.
+	connect atomic.Value
+
 	notFound         http.Handler
+	badRequest       http.Handler
 	methodNotAllowed http.Handler
-	options          func(node) http.Handler
+	options          func(*http.Request, *node) http.Handler
+	optionsCode      int
+	escaped          bool
+	fixedPathCode    int
+	slashCode        int
+	autoHead         bool
+	emptyWild        bool
+	maxParamLen      int
+	maxWildLen       int
+	maxSegments      int
+	limitExceeded    http.Handler
+	trace            bool
+	protoHeader      string
+	locales          map[string]bool
+	localeParam      string
+	localeDefault    string
+	localeCode       int
+	versionFunc      func(*http.Request) string
+	defaultVersion   string
+	renderers        []renderer
+	notAcceptable    http.Handler
+	unsupported      http.Handler
+	errorHandler     func(http.ResponseWriter, *http.Request, error)
+	observe          func(Observation)
+	// logRoute is called after a request has been served if the outcome of
+	// routing it should be logged.
+	// It is nil unless a logger was configured.
+	logRoute func(http.ResponseWriter, *http.Request, MatchResult)
+
+	// customNotFound, customMethodNotAllowed, and customOptions record whether
+	// the corresponding handlers were replaced using options.
+	customNotFound         bool
+	customMethodNotAllowed bool
+	customOptions          bool
+
+	// onRegister are the functions called for each route that is registered.
+	// Until New returns, routes are recorded in registered instead so that every
+	// function sees them regardless of the order of the options.
+	onRegister []func(method, pattern string, h http.Handler)
+	registered []registration
+
+	// fallback is the handler that requests that do not match a route are
+	// passed to unaltered, if any, and fallbackMethods is whether requests that
+	// only match the path of a route are passed to it as well.
+	fallback        http.Handler
+	fallbackMethods bool
+
+	// defaultMethods are the handlers used for requests that match the path of
+	// a route that does not have a handler for their method, by method.
+	defaultMethods map[string]http.Handler
+
+	// shadowBodyLimit is the size of the largest request body that is copied
+	// to shadow handlers.
+	shadowBodyLimit int64
+
+	// rateLimited is the handler for requests rejected by the rate limit of a
+	// route, or nil to use the default.
+	rateLimited http.Handler
+	// forbidden returns the handler for requests rejected by a guard, or is nil
+	// to use the default.
+	forbidden func(error) http.Handler
 }
 
 // New allocates and returns a new ServeMux.
 func New(opts ...Option) *ServeMux {
 	mux := &ServeMux{
-		node: node{
-			name:     "/",
-			typ:      typStatic,
-			handlers: make(map[string]http.Handler),
-		},
-		notFound: http.HandlerFunc(http.NotFound),
-		methodNotAllowed: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		notAcceptable: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
 		}),
-		options: defOptions,
+		unsupported: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		}),
+		versionFunc:  acceptVersion,
+		errorHandler: defErrorHandler,
+		maxParamLen:  defMaxParamLength,
+		maxWildLen:   defMaxWildcardLength,
+		maxSegments:  defMaxSegments,
+
+		shadowBodyLimit: defShadowBodyLimit,
 	}
+	mux.options = mux.defOptions
+	mux.notFound = http.HandlerFunc(mux.defNotFound)
+	mux.methodNotAllowed = http.HandlerFunc(mux.defMethodNotAllowed)
+	root := &node{
+		name: "/",
+		typ:  typStatic,
+	}
+	mux.tree.Store(&routeTree{root: root, compact: root})
 	for _, o := range opts {
 		o(mux)
 	}
+	mux.mu.Lock()
+	mux.published = true
+	mux.tree.Store(mux.newRouteTree(mux.root()))
+	registered := mux.registered
+	mux.registered = nil
+	mux.mu.Unlock()
+	for _, reg := range registered {
+		mux.notifyRegister(reg.methods, reg.pattern, reg.h)
+	}
 	return mux
 }
 
+// routeTree is a published version of the route tree.
+type routeTree struct {
+	// root is the root of the tree as it was registered.
+	root *node
+	// compact is a copy of root in which chains of static nodes have been merged
+	// so that they can be matched at once.
+	// It is only used for matching requests.
+	compact *node
+}
+
+// newRouteTree returns the tree to publish for root, in which the Allow header
+// of every route has been computed so that it is not computed for each
+// request.
+func (mux *ServeMux) newRouteTree(root *node) *routeTree {
+	compact := root.compact()
+	mux.precompute(&compact)
+	return &routeTree{root: root, compact: &compact}
+}
+
+// Handle registers the handler for the given pattern.
+// If a handler already exists for pattern, Handle panics and the ServeMux is
+// left unchanged.
+//
+// Handle is safe to call while the ServeMux is serving requests.
+func (mux *ServeMux) Handle(method, pattern string, h http.Handler, opts ...RouteOption) {
+	Handle(method, pattern, h, opts...)(mux)
+}
+
+// HandleFunc registers the handler function for the given pattern.
+// If a handler already exists for pattern, HandleFunc panics and the ServeMux
+// is left unchanged.
+//
+// HandleFunc is safe to call while the ServeMux is serving requests.
+func (mux *ServeMux) HandleFunc(method, pattern string, h http.HandlerFunc, opts ...RouteOption) {
+	Handle(method, pattern, h, opts...)(mux)
+}
+
+// Remove removes the handler registered for the given method and pattern and
+// reports whether one was found.
+// Parts of the tree that no longer lead to any handlers are discarded so that
+// they no longer match requests.
+// Routes registered using Subtree cannot be removed: Remove only considers
+// routes that match pattern exactly and reports false if there are none.
+// If pattern is malformed, no route can have been registered with it, so
+// Remove reports false.
+//
+// Remove is safe to call while the ServeMux is serving requests.
+// Requests that began routing before Remove was called may still be dispatched
+// to the removed handler.
+func (mux *ServeMux) Remove(method, pattern string) bool {
+	method = strings.ToUpper(method)
+	if ValidatePattern(pattern) != nil {
+		return false
+	}
+	var removed bool
+	mux.update(func(root *node) {
+		r, anchored := trimEnd(pattern[1:])
+		r, ext := trimExt(r)
+		// A route with a format extension is only removed if the pattern has the
+		// same extension parameter, and a route anchored with {$} only if the
+		// pattern is also anchored.
+		n := root.find(r)
+		if n == nil || n.ext != ext {
+			return
+		}
+		if ep, ok := n.handlers.get(method); !ok || strings.HasSuffix(ep.route, "{$}") != anchored {
+			return
+		}
+		removed = root.remove(method, r)
+	})
+	return removed
+}
+
+// current returns the currently published tree.
+func (mux *ServeMux) current() *routeTree {
+	return mux.tree.Load().(*routeTree)
+}
+
+// root returns the root of the currently published tree.
+func (mux *ServeMux) root() *node {
+	return mux.current().root
+}
+
+// compact returns the root of the compacted form of the currently published
+// tree for matching requests.
+// Until New returns it may not reflect the latest changes to the tree.
+func (mux *ServeMux) compact() *node {
+	return mux.current().compact
+}
+
+// update calls f with the root of the tree so that it may be modified.
+// Until New returns the tree is modified in place, afterwards f is called with
+// a copy of the tree which is then published if f does not panic.
+func (mux *ServeMux) update(f func(root *node)) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	if !mux.published {
+		f(mux.root())
+		return
+	}
+	root := mux.root().clone()
+	f(root)
+	mux.tree.Store(mux.newRouteTree(root))
+}
+
 // ServeHTTP dispatches the request to the handler whose pattern most closely
 // matches the request URL.
 func (mux *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	h, newReq := mux.handler(r)
-	h.ServeHTTP(w, newReq)
+	mux.serve(mux.current(), w, r)
+}
+
+// serve dispatches the request using the routes in t.
+func (mux *ServeMux) serve(t *routeTree, w http.ResponseWriter, r *http.Request) {
+	if mux.observe != nil {
+		start := time.Now()
+		res, newReq, _ := mux.handler(t, r)
+		if res.Kind != KindMatched && res.Kind != KindMethodNotAllowed {
+			res.Pattern = ""
+		}
+		newReq = mux.shadowed(newReq, res)
+		mux.serveObserved(w, newReq, res, start, false)
+		if mux.logRoute != nil {
+			mux.logRoute(w, newReq, res)
+		}
+		return
+	}
+	res, newReq, _ := mux.handler(t, r)
+	newReq = mux.shadowed(newReq, res)
+	res.Handler.ServeHTTP(w, newReq)
+	if mux.logRoute != nil {
+		mux.logRoute(w, newReq, res)
+	}
 }
 
 // Handler returns the handler to use for the given request, consulting
@@ -64,132 +377,932 @@ func (mux *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // It always returns a non-nil handler and request.
 //
 // The path used is unchanged for CONNECT requests.
+// For other requests, if the escaped form of the path is not clean Handler
+// returns a handler that redirects to the cleaned path, preserving the
+// encoding of the original path.
+// If any routes have been registered using HandleConnect, CONNECT requests are
+// matched against the request host instead of the path.
 //
 // If there is no registered handler that applies to the request, Handler
 // returns a page not found handler.
 // If a new request is returned it uses a context that contains any route
 // parameters that were matched against the request path.
 func (mux *ServeMux) Handler(r *http.Request) (http.Handler, *http.Request) {
-	return mux.handler(r)
+	res, newReq, _ := mux.handler(mux.current(), r)
+	return res.Handler, newReq
+}
+
+// MatchKind describes how a request was resolved by Lookup.
+type MatchKind int
+
+// A list of the ways in which a request may be resolved.
+const (
+	// KindNotFound indicates that no route matched the request.
+	KindNotFound MatchKind = iota
+	// KindMatched indicates that a route matched the request.
+	// This includes requests that are answered automatically, such as OPTIONS
+	// requests.
+	KindMatched
+	// KindMethodNotAllowed indicates that a route matched the request path but
+	// it does not handle the request method.
+	KindMethodNotAllowed
+	// KindRedirect indicates that the request will be redirected to a canonical
+	// path.
+	KindRedirect
+	// KindBadRequest indicates that no route matched the request because a path
+	// component could not be parsed as the type of a route parameter and the
+	// handler configured using BadRequestOnTypeMismatch will be used.
+	KindBadRequest
+	// KindLimitExceeded indicates that the request path was not matched
+	// against the routes because it exceeded one of the limits set using
+	// MaxParamLength, MaxWildcardLength, or MaxSegments.
+	KindLimitExceeded
+)
+
+// String returns a short name for the kind suitable for use in logs and
+// metrics.
+func (k MatchKind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not_found"
+	case KindMatched:
+		return "matched"
+	case KindMethodNotAllowed:
+		return "method_not_allowed"
+	case KindRedirect:
+		return "redirect"
+	case KindBadRequest:
+		return "bad_request"
+	case KindLimitExceeded:
+		return "limit_exceeded"
+	}
+	return fmt.Sprintf("MatchKind(%d)", int(k))
+}
+
+// MatchResult describes how a request was resolved by Lookup.
+type MatchResult struct {
+	Kind MatchKind
+
+	// Handler is the handler that will be used for the request.
+	// It is never nil.
+	Handler http.Handler
+
+	// Pattern is the pattern of the route that matched the request path.
+	// For CONNECT requests routed by HandleConnect it is the host pattern.
+	// If no route matched, Pattern is empty.
+	Pattern string
+
+	// Params contains the route parameters matched against the request path.
+	Params []ParamInfo
+
+	// Allowed contains the methods that may be used with the route that
+	// matched the request path.
+	// If no route matched, Allowed is nil.
+	Allowed []string
+}
+
+// Lookup is like Handler except that it reports how the request was resolved.
+// Calling the handler with the returned request is equivalent to calling
+// ServeHTTP with the original request.
+func (mux *ServeMux) Lookup(r *http.Request) (MatchResult, *http.Request) {
+	return mux.lookupTree(mux.current(), r)
+}
+
+// lookupTree resolves the request using the routes in t and reports how it was
+// resolved.
+func (mux *ServeMux) lookupTree(t *routeTree, r *http.Request) (MatchResult, *http.Request) {
+	res, newReq, n := mux.handler(t, r)
+	if n != nil {
+		res.Allowed, _ = mux.allowedFor(n, r)
+	}
+	if len(res.Params) > 0 {
+		res.Params = routeFrom(newReq).values()
+	}
+	return res, newReq
 }
 
-// handler returns the handler to use for the given request and a new request
-// with parameters set on the context.
-func (mux *ServeMux) handler(r *http.Request) (http.Handler, *http.Request) {
-	// TODO: Add /tree to /tree/ redirect option and apply here.
+// handler resolves the given request using the routes in t and returns a new
+// request with parameters set on the context.
+// If the request path matched a node in the tree it is also returned so that
+// the allowed methods may be computed by the caller.
+// The Allowed field of the result is not set and the values of any parameters
+// are not parsed.
+func (mux *ServeMux) handler(t *routeTree, r *http.Request) (MatchResult, *http.Request, *node) {
+	res, newReq, n := mux.route(t, r)
+	if mux.fallback != nil && mux.fallsBack(res.Kind) {
+		return MatchResult{Kind: res.Kind, Handler: mux.fallback}, r, nil
+	}
+	return res, newReq, n
+}
+
+// route resolves the given request in the same way as handler without
+// considering the fallback handler.
+func (mux *ServeMux) route(t *routeTree, r *http.Request) (MatchResult, *http.Request, *node) {
+	if r.Method == http.MethodConnect {
+		if routes := mux.connectRoutes(); len(routes) > 0 {
+			res, newReq := mux.connectHandler(routes, r)
+			return res, newReq, nil
+		}
+	}
+
 	path := r.URL.Path
+	if mux.escaped {
+		path = r.URL.EscapedPath()
+	}
 
-	// CONNECT requests are not canonicalized
+	// CONNECT requests are not canonicalized.
+	// Canonicalization is performed on the escaped path so that the redirect
+	// preserves the encoding sent by the client and encoded slashes are not
+	// treated as separators.
 	if r.Method != http.MethodConnect {
-		path = cleanPath(r.URL.Path)
-		if path != r.URL.Path {
+		escaped := r.URL.EscapedPath()
+		cleaned := cleanPath(escaped)
+		if cleaned != escaped {
+			unescaped, err := neturl.PathUnescape(cleaned)
+			if err != nil {
+				return MatchResult{Kind: KindNotFound, Handler: mux.notFound}, r, nil
+			}
 			url := *r.URL
-			url.Path = path
-			return http.RedirectHandler(url.String(), http.StatusPermanentRedirect), r
+			url.Path = unescaped
+			url.RawPath = cleaned
+			return MatchResult{
+				Kind:    KindRedirect,
+				Handler: http.RedirectHandler(url.String(), http.StatusPermanentRedirect),
+			}, r, nil
 		}
 	}
 
-	node := &mux.node
-	path = strings.TrimPrefix(path, "/")
+	var locale ParamInfo
+	if mux.locales != nil {
+		var ok bool
+		locale, path, ok = mux.splitLocale(path)
+		if !ok && mux.localeDefault != "" {
+			return MatchResult{
+				Kind:    KindRedirect,
+				Handler: http.RedirectHandler(mux.defaultLocalePath(r), mux.localeCode),
+			}, r, nil
+		}
+	}
 
-	// Requests for /
-	if path == "" {
-		h, ok := mux.node.handlers[r.Method]
-		if !ok {
-			switch {
-			case r.Method == http.MethodOptions && mux.options != nil:
-				return mux.options(mux.node), r
-			case mux.methodNotAllowed != nil && (mux.options != nil || len(mux.node.handlers) > 0):
-				return mux.methodNotAllowed, r
+	res := mux.find(t.compact, r.Method, strings.TrimPrefix(path, "/"), r, nil)
+	if res.kind == kindTooLong || res.kind == kindTooMany {
+		return MatchResult{Kind: KindLimitExceeded, Handler: mux.limitHandler(res.kind)}, r, nil
+	}
+	if res.kind != kindMatched && (res.node == nil || !res.node.live(r)) && mux.slashCode != 0 {
+		// Paths that only lead to other routes are redirected in preference to
+		// being treated as a match.
+		if loc, ok := mux.toggleSlash(t, r); ok {
+			return MatchResult{
+				Kind:    KindRedirect,
+				Handler: http.RedirectHandler(loc, mux.slashCode),
+			}, r, nil
+		}
+	}
+	var arm string
+	switch res.kind {
+	case kindMiss:
+		return mux.miss(t, r, res)
+	case kindMethodNotAllowed:
+		allowed, allow := mux.allowedFor(res.node, r)
+		r = withAllowed(r, allowed)
+		res.h = allowHeader(res.h, allow)
+	case kindMatched:
+		if res.ep != nil && res.ep.variant() {
+			res = mux.selectVariant(res, r.Method, mux.versionOf(r), mediaType(r), r)
+			if res.kind == kindMiss {
+				return mux.miss(t, r, res)
+			}
+		}
+		if res.ep != nil && res.ep.split != nil {
+			arm, res.h = mux.splitArm(res.ep, res.node, r, res.h)
+		}
+		if res.ep != nil && len(res.ep.guards) > 0 {
+			res.h = mux.protected(res.ep.guards, res.h)
+		}
+		if res.ep != nil && res.ep.limiter != nil {
+			res.h = mux.limited(res.ep.limiter, res.h)
+		}
+		if res.ep != nil && res.ep.deprecation != nil {
+			res.h = mux.deprecated(res.ep.deprecation, res.h)
+		}
+		if res.ep != nil && res.ep.tlsCode != 0 && !mux.isTLS(r) {
+			h, redirect := mux.insecure(r, res.ep.tlsCode)
+			if redirect {
+				return MatchResult{Kind: KindRedirect, Handler: h}, r, nil
 			}
-			return mux.notFound, r
+			res.h = h
 		}
+	}
+	rc := &routeContext{
+		ep:     res.ep,
+		params: res.params,
+		rest:   res.rest,
+		arm:    arm,
+	}
+	if locale.Name != "" {
+		rc.params = append([]ParamInfo{locale}, res.params...)
+		rc.locale = locale.Name
+		res.params = rc.params
+	}
+	if len(rc.params) > 0 && !mux.escaped {
+		rc.escaped = r.URL.EscapedPath()
+		rc.pathLen = len(r.URL.Path)
+	}
+	r = withRoute(r, rc)
+	if res.kind == kindNotFound {
+		r = mux.withNotFound(r, t, res)
+	}
+	if len(res.params) > 0 {
+		setPathValues(r, res.params)
+	}
+	if res.ep != nil && len(res.ep.values) > 0 {
+		ctx := r.Context()
+		for _, v := range res.ep.values {
+			ctx = context.WithValue(ctx, v.key, v.val)
+		}
+		r = r.WithContext(ctx)
+	}
+	pattern := res.node.route
+	if res.ep != nil {
+		pattern = res.ep.route
+	}
+	kind := KindMatched
+	switch res.kind {
+	case kindMethodNotAllowed:
+		kind = KindMethodNotAllowed
+	case kindNotFound:
+		kind = KindNotFound
+	}
+	return MatchResult{
+		Kind:    kind,
+		Handler: res.h,
+		Pattern: "/" + pattern,
+		Params:  res.params,
+	}, r, res.node
+}
 
-		r = r.WithContext(context.WithValue(r.Context(), ctxRoute{}, mux.node.route))
-		return h, r
+// Match returns the handler that would be used for a request with the given
+// method and path, the pattern it was registered with, and any route
+// parameters that were matched against the path without constructing a
+// request.
+// If no registered route handles the method and path, ok is false.
+//
+// Unlike Handler, Match does not canonicalize path: if path is not clean (for
+// example because it is not rooted or contains // or .. elements) ok is false.
+// If the ServeMux was configured with UseEscapedPath, path is expected to be
+// escaped.
+// If the route was registered with several versions, the default version is
+// used, and routes registered using Consumes never match.
+func (mux *ServeMux) Match(method, path string) (h http.Handler, pattern string, params []ParamInfo, ok bool) {
+	if cleanPath(path) != path {
+		return nil, "", nil, false
+	}
+	res := mux.find(mux.compact(), method, path[1:], nil, nil)
+	if res.kind == kindMatched && res.ep != nil && res.ep.variant() {
+		res = mux.selectVariant(res, method, mux.defaultVersion, "", nil)
+	}
+	if res.ep == nil {
+		return nil, "", nil, false
 	}
+	if !mux.escaped {
+		withEncoded(res.params, len(path), escapeComponent(path, false))
+	}
+	return res.h, "/" + res.ep.route, withValues(res.params), true
+}
+
+// matchKind describes the outcome of matching a path against the tree.
+type matchKind int
+
+const (
+	// kindMiss indicates that no node in the tree matched the path.
+	kindMiss matchKind = iota
+	// kindMatched indicates that a node matched the path and the handler
+	// should be used for the request, either because one was registered for the
+	// method or because the method is handled automatically.
+	kindMatched
+	// kindMethodNotAllowed indicates that a node matched the path but it did not
+	// have a handler for the method.
+	kindMethodNotAllowed
+	// kindNotFound indicates that a node matched the path but it did not have a
+	// handler for the method and method not allowed handling is disabled.
+	kindNotFound
+	// kindTooLong indicates that matching stopped because a parameter exceeded
+	// the configured maximum length.
+	kindTooLong
+	// kindTooMany indicates that the path was not matched because it had more
+	// components than the configured maximum.
+	kindTooMany
+)
+
+// result is the outcome of matching a path against the tree.
+type result struct {
+	kind matchKind
+	// node is the node that matched the path, if any.
+	node *node
+	// ep is the endpoint registered for the method, if any.
+	ep     *endpoint
+	h      http.Handler
+	params []ParamInfo
+	// rest is the remainder of the path below node if it is a subtree.
+	rest string
+	// mismatch is the parameter that could not be parsed if the path did not
+	// match any route because a component had the wrong type.
+	mismatch ParamInfo
+	// stop is the deepest node that matched the path if no route did, consumed
+	// is the number of path components it matched, and reason is why matching
+	// stopped there.
+	stop     *node
+	consumed uint
+	reason   StopReason
+}
+
+// stopped records where and why matching stopped if res did not match a
+// route.
+// A reason that was already recorded while resolving the path takes
+// precedence.
+func (res result) stopped(n *node, consumed uint, reason StopReason) result {
+	if res.kind != kindMiss && res.kind != kindNotFound {
+		return res
+	}
+	res.stop, res.consumed = n, consumed
+	if res.reason == StopNoRoute {
+		res.reason = reason
+	}
+	return res
+}
+
+// subtreeMatch is the deepest subtree that contains the path being matched.
+type subtreeMatch struct {
+	n *node
+	// rest is the remainder of the path below the subtree.
+	rest string
+	// nparams is the number of parameters that had been matched when the
+	// subtree was reached.
+	nparams int
+}
 
+// find walks the tree rooted at root to find the node matching path, which
+// must be clean and have had its leading slash removed, and resolves the
+// handler to use for the given method.
+// If no route matches path, the deepest subtree that contains it is used.
+func (mux *ServeMux) find(root *node, method, path string, r *http.Request, trace tracer) result {
+	node := root
+	var params []ParamInfo
+	var sub subtreeMatch
+
+	if mux.tooMany(path) {
+		trace.step(root, path, false, "the path has too many components")
+		return result{kind: kindTooMany}
+	}
+
+	// Requests for /
+	if path == "" {
+		sub.n = root.subtree
+		return mux.resolveEnd(root, false, method, params, sub, 1, r, trace).stopped(root, 0, StopNoRoute)
+	}
+
+	// The trailing slash is significant: a path that ends in one only matches
+	// routes that were registered with one.
+	slash := hasSlash(path)
 	offset := uint(1)
 
 nodeloop:
 	for node != nil {
+		if node.subtree != nil {
+			sub = subtreeMatch{n: node.subtree, rest: path, nparams: len(params)}
+		}
+
 		// If this is a variable route
 		if len(node.child) == 1 && node.child[0].typ != typStatic {
-			var part, remain string
-			part, remain, r = node.child[0].match(path, offset, r)
+			if mux.tooLong(&node.child[0], path) {
+				trace.step(&node.child[0], path, false, "the component exceeds the maximum length")
+				return result{kind: kindTooLong}
+			}
+			remain, pinfo, ok := node.child[0].match(path, offset, mux.escaped)
 			offset++
+			if trace != nil {
+				trace.param(&node.child[0], path, pinfo, ok)
+			}
 
 			// If the type doesn't match, we're done.
-			if part == "" {
-				return mux.notFound, r
+			if !ok {
+				res := mux.resolveEnd(nil, slash, method, params, sub, offset, r, trace)
+				if res.kind == kindMiss {
+					res.mismatch = pinfo
+				}
+				return res.stopped(node, offset-2, StopTypeMismatch)
+			}
+			if pinfo.Name != "" {
+				if params == nil {
+					params = make([]ParamInfo, 0, node.child[0].maxParams)
+				}
+				params = append(params, pinfo)
 			}
 
 			// The variable route matched and it's the last thing in the path, so we
 			// have our route:
 			if remain == "" {
-				h, ok := node.child[0].handlers[r.Method]
-				if !ok {
-					switch {
-					case r.Method == http.MethodOptions && mux.options != nil:
-						return mux.options(node.child[0]), r
-					case mux.methodNotAllowed != nil && (mux.options != nil || len(node.child[0].handlers) > 0):
-						return mux.methodNotAllowed, r
-					}
-					return mux.notFound, r
-				}
-
-				r = r.WithContext(context.WithValue(r.Context(), ctxRoute{}, node.child[0].route))
-				return h, r
+				return mux.resolveEnd(&node.child[0], slash, method, params, sub, offset, r, trace).stopped(&node.child[0], offset-1, StopNoRoute)
 			}
 			node = &node.child[0]
 			path = remain
 			continue
 		}
 
-		// If this is a static route
-		for _, child := range node.child {
-			var part, remain string
-			part, remain, r = child.match(path, offset, r)
-			offset++
+		// If this is a static route with enough children to be indexed
+		if node.static != nil {
+			part, _ := nextPart(path)
+			if mux.escaped {
+				var err error
+				part, err = neturl.PathUnescape(part)
+				if err != nil {
+					return mux.resolveEnd(nil, slash, method, params, sub, offset, r, trace).stopped(node, offset-1, StopNoChild)
+				}
+			}
+			i, ok := node.static[part]
+			if !ok {
+				trace.step(node, part, false, "no static child has this name")
+				return mux.resolveExt(node, path, slash, method, params, sub, offset, r, trace).stopped(node, offset-1, StopNoChild)
+			}
+			remain, end, ok := node.child[i].matchStatic(path, mux.escaped)
+			trace.static(&node.child[i], path, remain, ok)
+			if !ok {
+				return mux.noChild(mux.resolveExt(node, path, slash, method, params, sub, offset, r, trace), node, path, offset)
+			}
+			// A compacted node consumes one path component for itself and one for
+			// each node merged into it.
+			offset += 1 + uint(len(node.child[i].inner))
+			if remain == "" {
+				return mux.resolveEnd(end, slash, method, params, sub, offset, r, trace).stopped(end, offset-1, StopNoRoute)
+			}
+			node = end
+			path = remain
+			continue
+		}
+
+		// If this is a static route.
+		// Children are referenced by index so that the nodes we descend into and
+		// return are the ones stored in the tree: taking the address of a range
+		// copy would hand out a node that is not updated when the tree is.
+		for i := range node.child {
+			child := &node.child[i]
+			remain, end, ok := child.matchStatic(path, mux.escaped)
+			trace.static(child, path, remain, ok)
 			// The child did not match, so check the next.
-			if part == "" {
-				path = remain
+			if !ok {
 				continue
 			}
+			offset += 1 + uint(len(child.inner))
 
 			// The child matched and was the last thing in the path, so we have our
 			// route:
 			if remain == "" {
-				h, ok := child.handlers[r.Method]
-				if !ok {
-					switch {
-					case r.Method == http.MethodOptions && mux.options != nil:
-						return mux.options(child), r
-					case mux.methodNotAllowed != nil && (mux.options != nil || len(mux.node.handlers) > 0):
-						return mux.methodNotAllowed, r
-					}
-					return mux.notFound, r
-				}
-
-				r = r.WithContext(context.WithValue(r.Context(), ctxRoute{}, child.route))
-				return h, r
+				return mux.resolveEnd(end, slash, method, params, sub, offset, r, trace).stopped(end, offset-1, StopNoRoute)
 			}
 
 			// The child matched but was not the last one, move on to the next match.
-			node = &child
+			node = child
 			path = remain
 			continue nodeloop
 		}
 
 		// No child matched.
-		return mux.notFound, r
+		return mux.noChild(mux.resolveExt(node, path, slash, method, params, sub, offset, r, trace), node, path, offset)
+	}
+
+	return mux.resolveEnd(nil, slash, method, params, sub, offset, r, trace)
+}
+
+// noChild records where matching stopped if res did not match a route after
+// path, which starts with component number offset, did not match any of the
+// static children of n.
+// If path matched the start of a child that was compacted, matching stopped
+// at the last node merged into the child that matched.
+func (mux *ServeMux) noChild(res result, n *node, path string, offset uint) result {
+	if res.kind != kindMiss && res.kind != kindNotFound {
+		return res
+	}
+	for i := range n.child {
+		if inner, matched := n.child[i].matchInner(path, mux.escaped); inner != nil {
+			return res.stopped(inner, offset-1+matched, StopNoChild)
+		}
+	}
+	if len(n.child) == 0 {
+		return res.stopped(n, offset-1, StopNoWildcard)
+	}
+	return res.stopped(n, offset-1, StopNoChild)
+}
+
+// resolveEnd returns the result for a path that ended at n, or that did not
+// match any node if n is nil.
+// offset is the number of the component that would have followed n.
+// If no route matches, a wildcard child of n that may match an empty
+// remainder or the subtree sub (or the subtree of n if the path ended in a
+// slash) is used instead, if any.
+func (mux *ServeMux) resolveEnd(n *node, slash bool, method string, params []ParamInfo, sub subtreeMatch, offset uint, r *http.Request, trace tracer) result {
+	end := n
+	if n != nil {
+		end = n.end(slash)
+		if slash && n.subtree != nil {
+			sub = subtreeMatch{n: n.subtree, nparams: len(params)}
+		}
+		if mux.emptyWild && (end == nil || !end.live(r)) && len(n.child) == 1 && n.child[0].typ == typWild {
+			wild := &n.child[0]
+			trace.step(wild, "", true, "the path ended and empty wildcards are allowed")
+			if wild.name != "" {
+				params = append(params, ParamInfo{
+					Name:   wild.name,
+					Type:   typWild,
+					elem:   wild.elem,
+					offset: offset,
+				})
+			}
+			return mux.outrank(mux.resolve(wild, method, params, r, trace), method, params, sub, r, trace)
+		}
+	}
+	if sub.n != nil && (end == nil || !end.live(r)) {
+		trace.step(sub.n, sub.rest, true, "no route matched so the enclosing subtree is used")
+		res := mux.resolve(sub.n, method, params[:sub.nparams], r, trace)
+		res.rest = sub.rest
+		return res
+	}
+	if end == nil {
+		if n != nil {
+			trace.step(n, "", false, "no route is registered with or without the trailing slash")
+		}
+		return result{kind: kindMiss}
+	}
+	return mux.outrank(mux.resolve(end, method, params, r, trace), method, params, sub, r, trace)
+}
+
+// outrank returns the result for the subtree sub instead of res if res matched
+// a route and the subtree has a higher priority for method.
+func (mux *ServeMux) outrank(res result, method string, params []ParamInfo, sub subtreeMatch, r *http.Request, trace tracer) result {
+	if res.kind != kindMatched || res.ep == nil || sub.n == nil {
+		return res
+	}
+	_, ep, ok := mux.lookup(sub.n, method, r)
+	if !ok || ep.priority <= res.ep.priority {
+		return res
+	}
+	if trace != nil {
+		trace.step(sub.n, sub.rest, true, fmt.Sprintf("the enclosing subtree has priority %d, which is higher than %d", ep.priority, res.ep.priority))
+	}
+	res = mux.resolve(sub.n, method, params[:sub.nparams], r, trace)
+	res.rest = sub.rest
+	return res
+}
+
+// resolveExt returns the result for a path that did not match any of the
+// static children of n.
+// If the path is a route registered with a format extension followed by an
+// extension, the route is used and the extension is added to params.
+func (mux *ServeMux) resolveExt(n *node, path string, slash bool, method string, params []ParamInfo, sub subtreeMatch, offset uint, r *http.Request, trace tracer) result {
+	end, pinfo, ok := n.matchExt(path, offset, mux.escaped)
+	if !ok {
+		return mux.resolveEnd(nil, slash, method, params, sub, offset, r, trace)
+	}
+	if trace != nil {
+		trace.step(end, path, true, fmt.Sprintf("matched with the format extension %q", pinfo.Raw))
+	}
+	return mux.resolveEnd(end, false, method, append(params, pinfo), sub, pinfo.offset+1, r, trace)
+}
+
+// resolve returns the result for a path that matched n.
+// If there is no handler for method, the method not allowed handler is used if
+// OPTIONS handling is enabled or if n has handlers for other methods.
+func (mux *ServeMux) resolve(n *node, method string, params []ParamInfo, r *http.Request, trace tracer) result {
+	res := result{
+		kind:   kindMatched,
+		node:   n,
+		params: params,
+	}
+	var ok bool
+	res.h, res.ep, ok = mux.lookup(n, method, r)
+	if ok {
+		// Routes with parameters that do not satisfy their constraints are
+		// treated as though they did not match at all.
+		// Variants are checked once the variant for the request has been
+		// selected.
+		if !res.ep.variant() && !res.ep.allows(params) {
+			if trace != nil {
+				name, _ := res.ep.violation(params)
+				trace.step(n, "", false, fmt.Sprintf("the parameter %q does not satisfy the constraint on the %s route", name, method))
+			}
+			return result{kind: kindMiss, reason: StopConstraint}
+		}
+		if trace != nil {
+			reason := "a route is registered for the method"
+			if res.ep.priority != 0 {
+				reason = fmt.Sprintf("a route with priority %d is registered for the method", res.ep.priority)
+			}
+			trace.step(n, "", true, reason)
+		}
+		return res
+	}
+	if trace != nil {
+		trace.step(n, "", false, "no route is registered for "+method)
+	}
+	def, hasDefault := mux.defaultMethods[method]
+	switch {
+	case len(n.handlers) > 0 && len(n.child) == 0 && !n.live(r):
+		// Every route registered on n is disabled for the request, so n is
+		// treated as though it did not exist.
+		res.kind = kindNotFound
+		res.h = mux.notFound
+	case hasDefault && n.live(r):
+		res.kind = kindMethodNotAllowed
+		res.h = def
+	case method == http.MethodOptions && mux.options != nil:
+		res.h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mux.options(r, n).ServeHTTP(w, r)
+		})
+	case method == http.MethodTrace:
+		res.h = mux.traceHandler(n, r)
+		if !mux.trace {
+			res.kind = kindMethodNotAllowed
+		}
+	case n.methodNotAllowed != nil && n.live(r):
+		res.kind = kindMethodNotAllowed
+		res.h = n.methodNotAllowed
+	case mux.methodNotAllowed != nil && (mux.options != nil || n.live(r)):
+		res.kind = kindMethodNotAllowed
+		res.h = mux.methodNotAllowed
+	default:
+		res.kind = kindNotFound
+		res.h = mux.notFound
+	}
+	return res
+}
+
+// miss resolves a request that did not match any route, where res is the
+// result of matching its path.
+func (mux *ServeMux) miss(t *routeTree, r *http.Request, res result) (MatchResult, *http.Request, *node) {
+	if mux.fixedPathCode != 0 {
+		if loc, ok := mux.fixPath(t, r); ok {
+			return MatchResult{
+				Kind:    KindRedirect,
+				Handler: http.RedirectHandler(loc, mux.fixedPathCode),
+			}, r, nil
+		}
+	}
+	if mux.badRequest != nil && res.mismatch.Type != "" {
+		r = r.WithContext(context.WithValue(r.Context(), ctxMismatch{}, res.mismatch))
+		return MatchResult{Kind: KindBadRequest, Handler: mux.badRequest}, r, nil
+	}
+	return MatchResult{Kind: KindNotFound, Handler: mux.notFound}, mux.withNotFound(r, t, res), nil
+}
+
+// toggleSlash reports whether the request path would match a route if a
+// trailing slash was added or removed and, if so, returns the new location.
+func (mux *ServeMux) toggleSlash(t *routeTree, r *http.Request) (string, bool) {
+	path := r.URL.Path
+	if mux.escaped {
+		path = r.URL.EscapedPath()
+	}
+	if mux.locales != nil {
+		_, path, _ = mux.splitLocale(path)
+	}
+	if path == "/" {
+		return "", false
+	}
+	loc := r.URL.EscapedPath()
+	if hasSlash(path) {
+		path = path[:len(path)-1]
+		loc = strings.TrimSuffix(loc, "/")
+	} else {
+		path += "/"
+		loc += "/"
+	}
+	if mux.find(t.compact, r.Method, path[1:], r, nil).kind != kindMatched {
+		return "", false
+	}
+	if r.URL.RawQuery != "" {
+		loc += "?" + r.URL.RawQuery
+	}
+	return loc, true
+}
+
+// fixPath attempts to find a single route that matches the request path when
+// static components are compared case insensitively.
+// If one is found, the path of the route with the request parameters
+// substituted is returned.
+func (mux *ServeMux) fixPath(t *routeTree, r *http.Request) (string, bool) {
+	path := r.URL.Path
+	if mux.escaped {
+		path = r.URL.EscapedPath()
+	}
+	var prefix string
+	if mux.locales != nil {
+		var locale ParamInfo
+		locale, path, _ = mux.splitLocale(path)
+		if locale.Name != "" {
+			prefix = "/" + escapeComponent(locale.Raw, mux.escaped)
+		}
+	}
+	var found []string
+	path = strings.TrimPrefix(path, "/")
+	t.root.fold(path, "", mux.escaped, hasSlash(path), r.Method, &found)
+	if len(found) != 1 {
+		return "", false
+	}
+	loc := prefix + found[0]
+	if r.URL.RawQuery != "" {
+		loc += "?" + r.URL.RawQuery
+	}
+	return loc, true
+}
+
+// traceHandler returns the handler to use for TRACE requests that match n when
+// no TRACE handler has been registered.
+func (mux *ServeMux) traceHandler(n *node, r *http.Request) http.Handler {
+	if mux.trace {
+		return http.HandlerFunc(defTrace)
+	}
+	_, allow := mux.allowedFor(n, r)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	})
+}
+
+// lookup returns the handler to use for the given method on n along with the
+// endpoint that it was registered as.
+// Endpoints that are disabled for r are treated as though they were not
+// registered.
+func (mux *ServeMux) lookup(n *node, method string, r *http.Request) (http.Handler, *endpoint, bool) {
+	ep, ok := n.handlers.enabled(method, r)
+	if ok {
+		return ep.handler, ep, true
+	}
+	if method == http.MethodHead && mux.autoHead {
+		if ep, ok = n.handlers.enabled(http.MethodGet, r); ok {
+			return headHandler(ep.handler), ep, true
+		}
+	}
+	return nil, nil, false
+}
+
+// allowed returns the sorted list of methods that can be used with the route
+// represented by n, including any that are handled automatically.
+func (mux *ServeMux) allowed(n *node) []string {
+	if n.allow != nil {
+		return n.allow
+	}
+	methods := n.methods()
+	if _, ok := n.handlers.get(http.MethodOptions); !ok && mux.options != nil {
+		methods = append(methods, http.MethodOptions)
+	}
+	if _, ok := n.handlers.get(http.MethodTrace); !ok && mux.trace {
+		methods = append(methods, http.MethodTrace)
+	}
+	if _, ok := n.handlers.get(http.MethodHead); !ok && mux.autoHead {
+		if _, ok = n.handlers.get(http.MethodGet); ok {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// allowValue returns the value of the Allow header for the route represented
+// by n.
+func (mux *ServeMux) allowValue(n *node) string {
+	if n.allow != nil {
+		return n.allowValue
+	}
+	return strings.Join(mux.allowed(n), ",")
+}
+
+// allowedFor is like allowed and allowValue combined except that the methods
+// of routes that are disabled for r are omitted.
+func (mux *ServeMux) allowedFor(n *node, r *http.Request) ([]string, string) {
+	var disabled bool
+	for _, h := range n.handlers {
+		if h.ep.disabled(r) {
+			disabled = true
+			break
+		}
+	}
+	if !disabled {
+		return mux.allowed(n), mux.allowValue(n)
+	}
+	var methods []string
+	for _, method := range mux.allowed(n) {
+		registered := method
+		if _, ok := n.handlers.get(method); !ok && method == http.MethodHead {
+			// HEAD is handled automatically by the GET route.
+			registered = http.MethodGet
+		}
+		_, ok := n.handlers.get(registered)
+		if _, enabled := n.handlers.enabled(registered, r); ok && !enabled {
+			continue
+		}
+		methods = append(methods, method)
+	}
+	return methods, strings.Join(methods, ",")
+}
+
+// withAllowed returns a shallow copy of r with the allowed methods attached to
+// its context.
+func withAllowed(r *http.Request, allowed []string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), ctxAllowed{}, allowed))
+}
+
+// allowHeader returns a handler that sets the Allow header to the allowed
+// methods before calling h so that method not allowed handlers always produce
+// a valid response.
+func allowHeader(h http.Handler, allow string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// ValidatePattern checks pattern using the same rules that are applied when a
+// route is registered without registering it.
+// If pattern is invalid, the returned error is a *PatternError.
+func ValidatePattern(pattern string) error {
+	if cleanPath(pattern) != pattern {
+		return &PatternError{Pattern: pattern, Reason: uncleanReason}
+	}
+	for part, remain := nextPart(pattern[1:]); part != ""; part, remain = nextPart(remain) {
+		if base, ext, ok := splitExt(part); ok {
+			switch {
+			case remain != "" || hasSlash(pattern):
+				return &PatternError{Pattern: pattern, Reason: "format extensions must be at the end of the last component in a route"}
+			case ext == "" || strings.ContainsAny(ext, "{} "):
+				return &PatternError{Pattern: pattern, Reason: fmt.Sprintf("invalid format extension name %q", ext)}
+			}
+			if _, typ, _ := checkParam(base); typ != typStatic {
+				return &PatternError{Pattern: pattern, Reason: "format extensions must follow a static component"}
+			}
+			part = base
+		}
+		_, typ, err := checkParam(part)
+		if err != nil {
+			return &PatternError{Pattern: pattern, Reason: err.Reason}
+		}
+		if typ == typWild && remain != "" {
+			return &PatternError{Pattern: pattern, Reason: "wildcards must be the last component in a route"}
+		}
+		if typ == typEnd && remain != "" {
+			return &PatternError{Pattern: pattern, Reason: "{$} must be the last component in a route"}
+		}
+	}
+	return nil
+}
+
+// trimEnd removes a final {$} component from the route r, which must be valid
+// and have had its leading slash removed, and reports whether it was present.
+// Routes ending in {$} are registered in the same way as routes ending in a
+// trailing slash.
+func trimEnd(r string) (string, bool) {
+	switch {
+	case r == "{$}":
+		return "", true
+	case strings.HasSuffix(r, "/{$}"):
+		return r[:len(r)-len("{$}")], true
+	}
+	return r, false
+}
+
+// trimExt removes a format extension parameter such as {.format} from the end
+// of the route r, which must be valid and have had its leading slash removed,
+// and returns the name of the parameter or the empty string if there is none.
+func trimExt(r string) (string, string) {
+	last := r[strings.LastIndexByte(r, '/')+1:]
+	base, ext, ok := splitExt(last)
+	if !ok {
+		return r, ""
 	}
+	return r[:len(r)-len(last)+len(base)], ext
+}
 
-	return mux.notFound, r
+// splitExt splits the route component part into a static component and the
+// name of the format extension parameter that follows it, if any.
+// Escaped braces are not treated as the start of an extension.
+func splitExt(part string) (base, ext string, ok bool) {
+	i := strings.LastIndex(part, "{.")
+	if i <= 0 || part[len(part)-1] != '}' || part[i-1] == '{' {
+		return part, "", false
+	}
+	return part[:i], part[i+2 : len(part)-1], true
 }
 
-// parseParam returns a node with an empty handler from a path component.
+// parseParam returns the name and type of a path component.
+// If the component is invalid, parseParam panics.
 func parseParam(pattern string) (name string, typ string) {
+	name, typ, err := checkParam(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return name, typ
+}
+
+// checkParam returns the name and type of a path component or an error if it
+// is invalid.
+func checkParam(pattern string) (name string, typ string, err *PatternError) {
 	// README:
 	// The various checks in this function are a tad brittle and *order matters*
 	// in subtle ways.
@@ -198,13 +1311,20 @@ func parseParam(pattern string) (name string, typ string) {
 	// Eventually we should build a proper tokenizer for this.
 
 	// Static route components aren't patterns and must match exactly.
-	if pattern[0] != '{' || pattern[len(pattern)-1] != '}' {
-		return pattern, typStatic
+	// Literal braces are escaped by doubling them, so a component starting with
+	// {{ is static.
+	if pattern[0] != '{' || pattern[len(pattern)-1] != '}' || strings.HasPrefix(pattern, "{{") {
+		return unescapeBraces(pattern), typStatic, nil
 	}
 
 	// {} is an unnamed variable (it matches any single path component)
 	if len(pattern) == 2 {
-		return "", typString
+		return "", typString, nil
+	}
+
+	// {$} matches the end of the path after a trailing slash.
+	if pattern == "{$}" {
+		return "", typEnd, nil
 	}
 
 	// Variable matches ("{name type}" or "{type}")
@@ -217,11 +1337,50 @@ func parseParam(pattern string) (name string, typ string) {
 		idx = 1
 	}
 
+	// Wildcards may require each segment of the remainder to have a type
+	// ("{name path:type}").
+	if elem := strings.TrimPrefix(typ, typWild+":"); elem != typ {
+		switch elem {
+		case typInt, typUint, typFloat, typString:
+			return pattern[1:idx], typWild, nil
+		}
+		return "", "", &PatternError{Pattern: pattern, Reason: fmt.Sprintf("invalid segment type %q", elem)}
+	}
+
 	switch typ {
 	case typInt, typUint, typFloat, typString, typWild:
-		return pattern[1:idx], typ
+		return pattern[1:idx], typ, nil
+	case "...":
+		// The ellipsis used by net/http and other routers is an alias for path.
+		return pattern[1:idx], typWild, nil
 	}
-	panic(fmt.Sprintf("invalid type: %q", typ))
+	return "", "", &PatternError{Pattern: pattern, Reason: fmt.Sprintf("invalid type %q", typ)}
+}
+
+// wildElem returns the type of each segment of the wildcard route component
+// part, or the empty string if part is not a wildcard or does not have one.
+func wildElem(part string) string {
+	if _, typ := parseParam(part); typ != typWild {
+		return ""
+	}
+	start := strings.IndexByte(part, ' ') + 1
+	if start == 0 {
+		start = 1
+	}
+	typ := part[start : len(part)-1]
+	if !strings.HasPrefix(typ, typWild+":") {
+		return ""
+	}
+	return typ[len(typWild)+1:]
+}
+
+// unescapeBraces replaces each {{ or }} in the static route component s with a
+// single brace.
+func unescapeBraces(s string) string {
+	if !strings.Contains(s, "{{") && !strings.Contains(s, "}}") {
+		return s
+	}
+	return strings.NewReplacer("{{", "{", "}}", "}").Replace(s)
 }
 
 func nextPart(path string) (string, string) {
@@ -232,6 +1391,37 @@ func nextPart(path string) (string, string) {
 	return path[:idx], path[idx+1:]
 }
 
+// cleanPath returns the canonical path for p, eliminating . and .. elements.
+// Most paths are already clean, so they are checked first to avoid calling
+// path.Clean.
+func cleanPath(p string) string {
+	if isClean(p) {
+		return p
+	}
+	return slowCleanPath(p)
+}
+
+// isClean reports whether p is rooted and does not contain any empty, . or ..
+// elements, meaning that it is already in the form returned by cleanPath.
+func isClean(p string) bool {
+	if p == "" || p[0] != '/' {
+		return false
+	}
+	for i := 0; i < len(p); i++ {
+		if p[i] != '/' {
+			continue
+		}
+		rest := p[i+1:]
+		switch {
+		case strings.HasPrefix(rest, "/"),
+			rest == ".", strings.HasPrefix(rest, "./"),
+			rest == "..", strings.HasPrefix(rest, "../"):
+			return false
+		}
+	}
+	return true
+}
+
 // Code below this line was taken from the Go source and is used under the terms
 // of Go's BSD license (see the file LICENSE-GO). Its copyright statement is
 // below:
@@ -241,7 +1431,7 @@ func nextPart(path string) (string, string) {
 // license that can be found in the LICENSE file.
 
 // Return the canonical path for p, eliminating . and .. elements.
-func cleanPath(p string) string {
+func slowCleanPath(p string) string {
 	if p == "" {
 		return "/"
 	}
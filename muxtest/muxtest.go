@@ -0,0 +1,137 @@
+// Package muxtest provides helpers for testing the routes registered on a
+// mux.ServeMux.
+//
+// The helpers only use the exported introspection API of the mux package, so
+// they describe routes in the same way as Routes and Lookup.
+package muxtest // import "code.soquee.net/mux/muxtest"
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"code.soquee.net/mux"
+)
+
+// Routes returns a description of every route registered on m, one per line,
+// that is suitable for comparing against a golden file.
+// Each route is described by its method and pattern followed by any options
+// that affect how it is matched or reported, for example:
+//
+//	GET /user/{id uint} name=user_show
+//	POST /upload consumes=multipart/form-data
+//
+// Handlers are not described, so changing the handler of a route does not
+// change the output.
+func Routes(m *mux.ServeMux) []string {
+	routes := m.Routes()
+	lines := make([]string, 0, len(routes))
+	for _, route := range routes {
+		lines = append(lines, route.Method+" "+route.Pattern+describe(route))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// describe returns the options of route, each preceded by a space.
+func describe(route mux.RouteInfo) string {
+	var b strings.Builder
+	if route.Subtree {
+		b.WriteString(" subtree")
+	}
+	if route.Gone {
+		b.WriteString(" gone")
+	}
+	if route.Deprecated {
+		b.WriteString(" deprecated")
+		if !route.Sunset.IsZero() {
+			fmt.Fprintf(&b, " sunset=%s", route.Sunset.UTC().Format(time.RFC3339))
+		}
+		if route.Successor != "" {
+			fmt.Fprintf(&b, " successor=%s", route.Successor)
+		}
+	}
+	if route.Version != "" {
+		fmt.Fprintf(&b, " version=%s", route.Version)
+	}
+	if route.Consumes != "" {
+		fmt.Fprintf(&b, " consumes=%s", route.Consumes)
+	}
+	if route.Name != "" {
+		fmt.Fprintf(&b, " name=%s", route.Name)
+	}
+	if route.Priority != 0 {
+		fmt.Fprintf(&b, " priority=%d", route.Priority)
+	}
+	if len(route.Aliases) > 0 {
+		fmt.Fprintf(&b, " aliases=%s", strings.Join(route.Aliases, ","))
+	}
+	keys := make([]string, 0, len(route.Meta))
+	for k := range route.Meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " meta.%s=%v", k, route.Meta[k])
+	}
+	return b.String()
+}
+
+// Match reports an error on t unless a request with the given method and path
+// is routed by m to the route registered with wantPattern.
+// If wantPattern is empty, Match instead reports an error unless no route
+// matches the request path.
+func Match(t testing.TB, m *mux.ServeMux, method, path, wantPattern string) {
+	t.Helper()
+	res, _ := m.Lookup(httptest.NewRequest(method, path, nil))
+	switch {
+	case wantPattern == "" && res.Kind != mux.KindNotFound:
+		t.Errorf("%s %s: want no match, got %v %s", method, path, res.Kind, res.Pattern)
+	case wantPattern != "" && res.Kind != mux.KindMatched:
+		t.Errorf("%s %s: want %s, got %v", method, path, wantPattern, res.Kind)
+	case wantPattern != "" && res.Pattern != wantPattern:
+		t.Errorf("%s %s: want %s, got %s", method, path, wantPattern, res.Pattern)
+	}
+}
+
+// Diff returns the routes that were added to b, removed from a, or registered
+// with different options in each, one per line in the same format as Routes
+// and prefixed by "+ ", "- ", or "~ " respectively.
+// Changed routes are described as they are registered on b.
+// Routes are identified by their method, pattern, version, and media type.
+// If both have the same routes, Diff returns nil.
+func Diff(a, b *mux.ServeMux) []string {
+	before, after := index(a), index(b)
+	var diff []string
+	for key, line := range before {
+		switch other, ok := after[key]; {
+		case !ok:
+			diff = append(diff, "- "+line)
+		case other != line:
+			diff = append(diff, "~ "+other)
+		}
+	}
+	for key, line := range after {
+		if _, ok := before[key]; !ok {
+			diff = append(diff, "+ "+line)
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool {
+		return diff[i][2:] < diff[j][2:]
+	})
+	return diff
+}
+
+// index maps the identity of each route registered on m to the line that
+// describes it.
+func index(m *mux.ServeMux) map[string]string {
+	idx := make(map[string]string)
+	for _, route := range m.Routes() {
+		key := route.Method + " " + route.Pattern + " " + route.Version + " " + route.Consumes
+		idx[key] = route.Method + " " + route.Pattern + describe(route)
+	}
+	return idx
+}
@@ -0,0 +1,67 @@
+package muxtest_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"code.soquee.net/mux"
+	"code.soquee.net/mux/muxtest"
+)
+
+var nopHandler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+func TestRoutes(t *testing.T) {
+	m := mux.New(
+		mux.HandleFunc(http.MethodGet, "/user/{id uint}", nopHandler, mux.Name("user_show"), mux.Meta("auth", true)),
+		mux.HandleFunc(http.MethodPost, "/upload", nopHandler, mux.Consumes("multipart/form-data")),
+		mux.HandleFunc(http.MethodGet, "/", nopHandler),
+		mux.HandleFunc(http.MethodGet, "/v1/user/{id uint}", nopHandler, mux.Deprecated(time.Date(2027, time.January, 2, 0, 0, 0, 0, time.UTC), "/user/{id uint}")),
+	)
+	got := muxtest.Routes(m)
+	want := []string{
+		"GET /",
+		"GET /user/{id uint} name=user_show meta.auth=true",
+		"GET /v1/user/{id uint} deprecated sunset=2027-01-02T00:00:00Z successor=/user/{id uint}",
+		"POST /upload consumes=multipart/form-data",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected routes:\nwant=%q,\n got=%q", want, got)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	m := mux.New(
+		mux.HandleFunc(http.MethodGet, "/user/{id uint}", nopHandler),
+		mux.HandleFunc(http.MethodGet, "/settings", nopHandler),
+	)
+	muxtest.Match(t, m, http.MethodGet, "/user/123", "/user/{id uint}")
+	muxtest.Match(t, m, http.MethodGet, "/settings", "/settings")
+	muxtest.Match(t, m, http.MethodGet, "/nope", "")
+}
+
+func TestDiff(t *testing.T) {
+	a := mux.New(
+		mux.HandleFunc(http.MethodGet, "/", nopHandler),
+		mux.HandleFunc(http.MethodGet, "/user/{id uint}", nopHandler),
+		mux.HandleFunc(http.MethodDelete, "/user/{id uint}", nopHandler),
+	)
+	b := mux.New(
+		mux.HandleFunc(http.MethodGet, "/", nopHandler),
+		mux.HandleFunc(http.MethodGet, "/user/{id uint}", nopHandler, mux.Name("user_show")),
+		mux.HandleFunc(http.MethodPost, "/user", nopHandler),
+	)
+	if diff := muxtest.Diff(a, a); diff != nil {
+		t.Errorf("expected no difference, got %q", diff)
+	}
+	got := muxtest.Diff(a, b)
+	want := []string{
+		"- DELETE /user/{id uint}",
+		"~ GET /user/{id uint} name=user_show",
+		"+ POST /user",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected diff:\nwant=%q,\n got=%q", want, got)
+	}
+}
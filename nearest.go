@@ -0,0 +1,121 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// StopReason describes why matching a request path against the routes stopped
+// before a route was found.
+type StopReason int
+
+// A list of the reasons that matching may stop.
+const (
+	// StopNoRoute indicates that the whole path was matched but no route was
+	// registered for it, for example because it only leads to other routes or
+	// does not have the same trailing slash as the route.
+	StopNoRoute StopReason = iota
+	// StopNoChild indicates that the next component of the path did not match
+	// any of the routes that continue from the nearest match.
+	StopNoChild
+	// StopNoWildcard indicates that the path continued past the end of every
+	// route that it matched and there was no wildcard parameter to match the
+	// remainder.
+	StopNoWildcard
+	// StopTypeMismatch indicates that the next component of the path could not
+	// be parsed as the type of a route parameter.
+	StopTypeMismatch
+	// StopConstraint indicates that the whole path was matched but a route
+	// parameter did not satisfy a constraint set using Constrain.
+	StopConstraint
+)
+
+// String returns a short name for the reason suitable for use in logs and
+// metrics.
+func (s StopReason) String() string {
+	switch s {
+	case StopNoRoute:
+		return "no_route"
+	case StopNoChild:
+		return "no_child"
+	case StopNoWildcard:
+		return "no_wildcard"
+	case StopTypeMismatch:
+		return "type_mismatch"
+	case StopConstraint:
+		return "constraint"
+	}
+	return fmt.Sprintf("StopReason(%d)", int(s))
+}
+
+// NearestMatch describes how much of a request path matched the registered
+// routes before matching failed.
+// It is returned by NotFoundInfo.
+type NearestMatch struct {
+	// Prefix is the pattern of the routes up to the deepest point that matched
+	// the path, including the leading slash (for example "/docs" if the path
+	// /docs/instalation did not match the route /docs/installation).
+	Prefix string
+
+	// Consumed is the number of components of the path that were matched by
+	// Prefix.
+	Consumed int
+
+	// Reason is why matching stopped.
+	Reason StopReason
+}
+
+// ctxNotFound is a type used as the context key when storing a *notFoundInfo
+// on the HTTP context before calling the not found handler.
+type ctxNotFound struct{}
+
+// notFoundInfo is the information about a request that did not match any
+// route that is stored on the request context.
+type notFoundInfo struct {
+	// root is the route tree that the request was matched against.
+	root *node
+	// nearest is where matching stopped, if ok is true.
+	nearest NearestMatch
+	ok      bool
+}
+
+// withNotFound returns a shallow copy of r with the routes in t and the
+// nearest match described by res attached to its context.
+// If the default NotFound handler, which does not use them, is in use, r is
+// returned unaltered so that requests for unknown paths remain cheap to
+// reject.
+func (mux *ServeMux) withNotFound(r *http.Request, t *routeTree, res result) *http.Request {
+	if !mux.customNotFound {
+		return r
+	}
+	info := &notFoundInfo{root: t.root, ok: res.stop != nil}
+	if info.ok {
+		info.nearest = NearestMatch{
+			Prefix:   "/" + res.stop.route,
+			Consumed: int(res.consumed),
+			Reason:   res.reason,
+		}
+	}
+	return r.WithContext(context.WithValue(r.Context(), ctxNotFound{}, info))
+}
+
+// notFoundFrom returns the information stored on the context of r by
+// withNotFound, or nil if there is none.
+func notFoundFrom(r *http.Request) *notFoundInfo {
+	info, _ := r.Context().Value(ctxNotFound{}).(*notFoundInfo)
+	return info
+}
+
+// NotFoundInfo returns how much of the path of r matched the registered routes
+// before matching failed.
+// It is only set on requests passed to the handler configured using NotFound
+// after the path was matched against the routes, for all other requests ok is
+// false.
+func NotFoundInfo(r *http.Request) (nearest NearestMatch, ok bool) {
+	info := notFoundFrom(r)
+	if info == nil || !info.ok {
+		return NearestMatch{}, false
+	}
+	return info.nearest, true
+}
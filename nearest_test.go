@@ -0,0 +1,56 @@
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var notFoundInfoTests = [...]struct {
+	path    string
+	unset   bool
+	nearest mux.NearestMatch
+}{
+	0: {path: "/docs/instalation", nearest: mux.NearestMatch{Prefix: "/docs", Consumed: 1, Reason: mux.StopNoChild}},
+	1: {path: "/docs/guides/abc", nearest: mux.NearestMatch{Prefix: "/docs/guides", Consumed: 2, Reason: mux.StopTypeMismatch}},
+	2: {path: "/about/team", nearest: mux.NearestMatch{Prefix: "/about", Consumed: 1, Reason: mux.StopNoWildcard}},
+	3: {path: "/about/", nearest: mux.NearestMatch{Prefix: "/about", Consumed: 1, Reason: mux.StopNoRoute}},
+	4: {path: "/user/3", nearest: mux.NearestMatch{Prefix: "/user/{id uint}", Consumed: 2, Reason: mux.StopConstraint}},
+	5: {path: "/nope", nearest: mux.NearestMatch{Prefix: "/", Reason: mux.StopNoChild}},
+	6: {path: "/api/v1/users", nearest: mux.NearestMatch{Prefix: "/api/v1", Consumed: 2, Reason: mux.StopNoChild}},
+	7: {path: "/docs/installation", unset: true},
+	8: {path: "/docs/../about", unset: true},
+}
+
+func TestNotFoundInfo(t *testing.T) {
+	var nearest mux.NearestMatch
+	var ok bool
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/docs/installation", codeHandler(t, testCode)),
+		mux.Handle(http.MethodGet, "/docs/guides/{id uint}", failHandler(t)),
+		mux.Handle(http.MethodGet, "/about", failHandler(t)),
+		mux.Handle(http.MethodGet, "/user/{id uint}", failHandler(t), mux.Constrain("id", func(v interface{}) bool {
+			return v.(uint64)%2 == 0
+		})),
+		mux.Handle(http.MethodGet, "/api/v1/orders", failHandler(t)),
+		mux.NotFound(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nearest, ok = mux.NotFoundInfo(r)
+			w.WriteHeader(http.StatusNotFound)
+		})),
+	)
+	for i, tc := range notFoundInfoTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			nearest, ok = mux.NearestMatch{}, false
+			m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if ok == tc.unset {
+				t.Fatalf("Unexpected nearest match: want=%t, got=%t", !tc.unset, ok)
+			}
+			if nearest != tc.nearest {
+				t.Errorf("Unexpected nearest match: want=%+v, got=%+v", tc.nearest, nearest)
+			}
+		})
+	}
+}
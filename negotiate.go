@@ -0,0 +1,168 @@
+package mux
+
+import (
+	"encoding/json"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrorResponse describes the response written by the default NotFound and
+// MethodNotAllowed handlers.
+type ErrorResponse struct {
+	// The status code of the response (for example 404).
+	Status int `json:"status"`
+	// The status text of the response (for example "Not Found").
+	Error string `json:"error"`
+	// The method and path of the request.
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// The methods that may be used with the route if the status is 405.
+	Allowed []string `json:"allowed,omitempty"`
+}
+
+// renderer writes the body of default error responses for a media type.
+type renderer struct {
+	mediaType string
+	render    func(w io.Writer, resp ErrorResponse)
+}
+
+// defRenderers are the renderers used by the default NotFound and
+// MethodNotAllowed handlers in order of preference.
+var defRenderers = []renderer{
+	{mediaType: "text/plain", render: renderText},
+	{mediaType: "text/html", render: renderHTML},
+	{mediaType: "application/json", render: renderJSON},
+}
+
+// ErrorRenderer registers a function to write the body of the responses of
+// the default NotFound, MethodNotAllowed, RateLimited, and Forbidden handlers
+// for clients that accept the given media type.
+//
+// The default handlers choose between media types using the Accept header of
+// the request.
+// Renderers for text/plain, text/html, and application/json are provided and
+// may be replaced using ErrorRenderer.
+// If the request does not have an Accept header, or does not accept any of the
+// available media types, text/plain is used.
+// The Content-Type header and status code are set before f is called.
+//
+// Handlers configured using NotFound, MethodNotAllowed, RateLimited, or
+// Forbidden do not use renderers.
+func ErrorRenderer(mediaType string, f func(w io.Writer, resp ErrorResponse)) Option {
+	return func(mux *ServeMux) {
+		if mux.renderers == nil {
+			mux.renderers = append([]renderer(nil), defRenderers...)
+		}
+		for i, r := range mux.renderers {
+			if r.mediaType == mediaType {
+				mux.renderers[i].render = f
+				return
+			}
+		}
+		mux.renderers = append(mux.renderers, renderer{mediaType: mediaType, render: f})
+	}
+}
+
+// defNotFound is the default NotFound handler.
+func (mux *ServeMux) defNotFound(w http.ResponseWriter, r *http.Request) {
+	mux.renderError(w, r, http.StatusNotFound, nil)
+}
+
+// defMethodNotAllowed is the default MethodNotAllowed handler.
+func (mux *ServeMux) defMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	mux.renderError(w, r, http.StatusMethodNotAllowed, Allowed(r))
+}
+
+// renderError writes an error response in the media type that best matches
+// the Accept header of r.
+func (mux *ServeMux) renderError(w http.ResponseWriter, r *http.Request, code int, allowed []string) {
+	renderers := mux.renderers
+	if renderers == nil {
+		renderers = defRenderers
+	}
+	rend := negotiate(r.Header.Get("Accept"), renderers)
+	w.Header().Set("Content-Type", rend.mediaType+"; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	rend.render(w, ErrorResponse{
+		Status:  code,
+		Error:   http.StatusText(code),
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Allowed: allowed,
+	})
+}
+
+// negotiate returns the renderer for the media type that is most preferred by
+// the Accept header accept.
+// Ties are broken using the order of renderers, and the first renderer is used
+// if none are acceptable.
+func negotiate(accept string, renderers []renderer) renderer {
+	if accept == "" {
+		return renderers[0]
+	}
+	best, bestQ := renderers[0], 0.0
+	for _, rend := range renderers {
+		if q := quality(accept, rend.mediaType); q > bestQ {
+			best, bestQ = rend, q
+		}
+	}
+	return best
+}
+
+// quality returns the quality value given to mediaType by the most specific
+// matching media range in the Accept header accept, or 0 if it is not
+// acceptable.
+func quality(accept, mediaType string) float64 {
+	typ, _, _ := cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		var s int
+		switch {
+		case mediaRange == mediaType:
+			s = 2
+		case mediaRange == typ+"/*":
+			s = 1
+		case mediaRange == "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+		specificity, q = s, 1
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+	}
+	return q
+}
+
+func renderText(w io.Writer, resp ErrorResponse) {
+	// Match the bodies written by net/http.
+	if resp.Status == http.StatusNotFound {
+		io.WriteString(w, "404 page not found\n")
+		return
+	}
+	io.WriteString(w, resp.Error+"\n")
+}
+
+func renderHTML(w io.Writer, resp ErrorResponse) {
+	title := html.EscapeString(strconv.Itoa(resp.Status) + " " + resp.Error)
+	io.WriteString(w, "<!DOCTYPE html>\n<html><head><title>"+title+"</title></head>\n<body><h1>"+title+"</h1></body></html>\n")
+}
+
+func renderJSON(w io.Writer, resp ErrorResponse) {
+	json.NewEncoder(w).Encode(resp)
+}
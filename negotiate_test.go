@@ -0,0 +1,97 @@
+package mux_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var negotiateTests = [...]struct {
+	method      string
+	path        string
+	accept      string
+	code        int
+	contentType string
+	body        string
+}{
+	0: {method: http.MethodGet, path: "/nope", code: http.StatusNotFound, contentType: "text/plain; charset=utf-8", body: "404 page not found\n"},
+	1: {method: http.MethodPost, path: "/a", code: http.StatusMethodNotAllowed, contentType: "text/plain; charset=utf-8", body: "Method Not Allowed\n"},
+	2: {
+		method:      http.MethodGet,
+		path:        "/nope",
+		accept:      "application/json",
+		code:        http.StatusNotFound,
+		contentType: "application/json; charset=utf-8",
+		body:        `{"status":404,"error":"Not Found","method":"GET","path":"/nope"}` + "\n",
+	},
+	3: {
+		method:      http.MethodPost,
+		path:        "/a",
+		accept:      "application/json",
+		code:        http.StatusMethodNotAllowed,
+		contentType: "application/json; charset=utf-8",
+		body:        `{"status":405,"error":"Method Not Allowed","method":"POST","path":"/a","allowed":["GET","OPTIONS"]}` + "\n",
+	},
+	4: {
+		method:      http.MethodGet,
+		path:        "/nope",
+		accept:      "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+		code:        http.StatusNotFound,
+		contentType: "text/html; charset=utf-8",
+		body:        "<!DOCTYPE html>\n<html><head><title>404 Not Found</title></head>\n<body><h1>404 Not Found</h1></body></html>\n",
+	},
+	5: {method: http.MethodGet, path: "/nope", accept: "image/png", code: http.StatusNotFound, contentType: "text/plain; charset=utf-8", body: "404 page not found\n"},
+	6: {method: http.MethodGet, path: "/nope", accept: "*/*", code: http.StatusNotFound, contentType: "text/plain; charset=utf-8", body: "404 page not found\n"},
+	7: {method: http.MethodGet, path: "/nope", accept: "application/json;q=0.5, text/*;q=0.1", code: http.StatusNotFound, contentType: "application/json; charset=utf-8", body: `{"status":404,"error":"Not Found","method":"GET","path":"/nope"}` + "\n"},
+	8: {method: http.MethodGet, path: "/nope", accept: "application/json;q=0, */*", code: http.StatusNotFound, contentType: "text/plain; charset=utf-8", body: "404 page not found\n"},
+	9: {method: http.MethodGet, path: "/nope", accept: "application/problem+json", code: http.StatusNotFound, contentType: "application/problem+json; charset=utf-8", body: "404 GET /nope\n"},
+}
+
+func TestNegotiateErrors(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/a", failHandler(t)),
+		mux.ErrorRenderer("application/problem+json", func(w io.Writer, resp mux.ErrorResponse) {
+			fmt.Fprintf(w, "%d %s %s\n", resp.Status, resp.Method, resp.Path)
+		}),
+	)
+	for i, tc := range negotiateTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, req)
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != tc.contentType {
+				t.Errorf("Unexpected content type: want=%q, got=%q", tc.contentType, ct)
+			}
+			if body := rec.Body.String(); body != tc.body {
+				t.Errorf("Unexpected body:\nwant=%q,\n got=%q", tc.body, body)
+			}
+		})
+	}
+}
+
+func TestNegotiateCustomHandler(t *testing.T) {
+	m := mux.New(
+		mux.NotFound(codeHandler(t, testCode)),
+		mux.ErrorRenderer("application/json", func(w io.Writer, resp mux.ErrorResponse) {
+			t.Errorf("Renderer should not be used by custom handlers")
+		}),
+	)
+	req := httptest.NewRequest(http.MethodGet, "/nope", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	if rec.Code != testCode {
+		t.Errorf("Unexpected status code: want=%d, got=%d", testCode, rec.Code)
+	}
+}
@@ -1,79 +1,881 @@
 package mux
 
 import (
-	"context"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 type node struct {
-	name     string
-	typ      string
+	name string
+	typ  string
+	// route is the pattern that leads to this node, minus the leading slash.
 	route    string
-	handlers map[string]http.Handler
+	handlers handlerSet
+	// ext is the name of the format extension parameter of the routes
+	// registered on this node, if they were registered with one.
+	ext string
+	// elem is the type that each segment of the remainder of the path must have
+	// if the node is a wildcard that was registered with one.
+	elem string
+	// maxParams is an upper bound on the number of named parameters in any route
+	// that passes through this node.
+	// It is used to size the parameter slice when matching requests.
+	maxParams int
 
 	child []node
+	// slash is the node for the same route followed by a trailing slash, if one
+	// has been registered.
+	// It never has any children.
+	slash *node
+	// subtree is the node for a subtree registered at this node, which matches
+	// any path below it that does not match a more specific route, if one has
+	// been registered.
+	// It never has any children.
+	subtree *node
+	// inner holds the nodes that were merged into a static node when the tree
+	// was compacted, in order, without their children.
+	// The node itself takes the place of the last node in the chain.
+	inner []node
+	// static maps the first component of the names of static children to their
+	// index in child.
+	// It is only set in compacted trees and only if there are more than
+	// staticIndexSize static children.
+	static map[string]int
+	// allow is the sorted list of methods that can be used with the route
+	// represented by the node and allowValue is the same list formatted for the
+	// Allow header.
+	// They are only set in published and compiled trees.
+	allow      []string
+	allowValue string
+	// methodNotAllowed is the handler set using OnMethodNotAllowed by the last
+	// route registered on the node that used it, if any.
+	// If that route is overridden or removed, it is taken from one of the
+	// remaining routes instead.
+	methodNotAllowed http.Handler
 }
 
-func (n *node) match(path string, offset uint, r *http.Request) (part string, remain string, req *http.Request) {
+// resetMethodNotAllowed sets the methodNotAllowed handler of n from the routes
+// that remain after one of them was overridden or removed.
+func (n *node) resetMethodNotAllowed() {
+	n.methodNotAllowed = nil
+	for _, h := range n.handlers {
+		if h.ep.methodNotAllowed != nil {
+			n.methodNotAllowed = h.ep.methodNotAllowed
+		}
+	}
+}
+
+// live reports whether n has a route that is not disabled for r.
+func (n *node) live(r *http.Request) bool {
+	for _, h := range n.handlers {
+		if !h.ep.disabled(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// staticIndexSize is the number of static children above which they are
+// indexed by name instead of being searched linearly.
+const staticIndexSize = 8
+
+// reindex rebuilds the index of the static children of n.
+func (n *node) reindex() {
+	if len(n.child) <= staticIndexSize || n.child[0].typ != typStatic {
+		n.static = nil
+		return
+	}
+	n.static = make(map[string]int, len(n.child))
+	for i := range n.child {
+		first, _ := nextPart(n.child[i].name)
+		n.static[first] = i
+	}
+}
+
+// compact returns a copy of n in which each chain of static nodes that do not
+// have any handlers and only have a single static child has been merged into
+// the last node in the chain.
+// The name of the merged node is the names of every node in the chain joined
+// by slashes.
+// Compacted trees must only be used for matching.
+func (n *node) compact() node {
+	c := *n
+	if len(n.child) == 0 {
+		return c
+	}
+	c.child = make([]node, len(n.child))
+	for i := range n.child {
+		child := &n.child[i]
+		var inner []node
+		name := child.name
+		for child.typ == typStatic && len(child.handlers) == 0 && child.subtree == nil && len(child.child) == 1 && child.child[0].typ == typStatic {
+			link := *child
+			link.child = nil
+			link.static = nil
+			inner = append(inner, link)
+			child = &child.child[0]
+			name += "/" + child.name
+		}
+		c.child[i] = child.compact()
+		c.child[i].name = name
+		c.child[i].inner = inner
+	}
+	c.reindex()
+	return c
+}
+
+// matchStatic attempts to match the static node n against the start of path,
+// which may consume several components if n is the result of compacting the
+// tree.
+// If path ends before every component of n has been matched, the node from
+// the compacted chain that corresponds to the last component is returned
+// instead of n.
+func (n *node) matchStatic(path string, unescape bool) (remain string, end *node, ok bool) {
+	if !unescape && strings.HasPrefix(path, n.name) {
+		switch {
+		case len(path) == len(n.name):
+			return "", n, true
+		case path[len(n.name)] == '/':
+			return path[len(n.name)+1:], n, true
+		}
+	}
+	if !unescape && len(n.inner) == 0 {
+		return path, nil, false
+	}
+
+	name, rest := n.name, path
+	for i := 0; ; i++ {
+		var want, part string
+		want, name = nextPart(name)
+		part, rest = nextPart(rest)
+		if unescape {
+			var err error
+			part, err = url.PathUnescape(part)
+			if err != nil {
+				return path, nil, false
+			}
+		}
+		if part == "" || part != want {
+			return path, nil, false
+		}
+		switch {
+		case name == "":
+			return rest, n, true
+		case rest == "":
+			return "", &n.inner[i], true
+		}
+	}
+}
+
+// matchInner returns the deepest of the nodes merged into n when the tree was
+// compacted that matches the start of path, along with the number of
+// components of path that it matched.
+// If none of them match, inner is nil.
+func (n *node) matchInner(path string, unescape bool) (inner *node, matched uint) {
+	name, rest := n.name, path
+	for i := range n.inner {
+		var want, part string
+		want, name = nextPart(name)
+		part, rest = nextPart(rest)
+		if unescape {
+			var err error
+			part, err = url.PathUnescape(part)
+			if err != nil {
+				break
+			}
+		}
+		if part == "" || part != want {
+			break
+		}
+		inner, matched = &n.inner[i], matched+1
+	}
+	return inner, matched
+}
+
+// matchExt attempts to match path, the remainder of the request path, against
+// a static child of n that was registered with a format extension.
+// The extension is everything after the last dot in the final component of
+// path, and it is returned as a string parameter.
+func (n *node) matchExt(path string, offset uint, unescape bool) (end *node, pinfo ParamInfo, ok bool) {
+	dot := strings.LastIndexByte(path, '.')
+	if dot <= 0 || dot == len(path)-1 || path[dot-1] == '/' || strings.IndexByte(path[dot:], '/') != -1 {
+		return nil, pinfo, false
+	}
+	base, ext, encoded := path[:dot], path[dot+1:], path[dot+1:]
+	if unescape {
+		var err error
+		ext, err = url.PathUnescape(ext)
+		if err != nil {
+			return nil, pinfo, false
+		}
+	}
+
+	children := n.child
+	if n.static != nil {
+		first, _ := nextPart(base)
+		if unescape {
+			var err error
+			first, err = url.PathUnescape(first)
+			if err != nil {
+				return nil, pinfo, false
+			}
+		}
+		i, ok := n.static[first]
+		if !ok {
+			return nil, pinfo, false
+		}
+		children = n.child[i : i+1]
+	}
+	for i := range children {
+		remain, end, ok := children[i].matchStatic(base, unescape)
+		if !ok || remain != "" || end.ext == "" {
+			continue
+		}
+		return end, ParamInfo{
+			Raw:     ext,
+			Encoded: encoded,
+			Name:    end.ext,
+			Type:    typString,
+
+			offset: offset + uint(strings.Count(base, "/")),
+			tail:   len(encoded),
+		}, true
+	}
+	return nil, pinfo, false
+}
+
+// endpoint is a handler registered on a node along with the route it was
+// registered with and any per-route configuration.
+// Endpoints are never modified once they have been added to the tree.
+type endpoint struct {
+	handler http.Handler
+	// route is the pattern the handler was registered with, minus the leading
+	// slash.
+	route string
+	// segments is the parsed form of route.
+	segments []segment
+	meta     map[string]interface{}
+	// name is the name given to the route using Name, if any.
+	name string
+	// subtree is true if the handler was registered using Subtree.
+	subtree bool
+	// host is the host pattern of a route registered using HandleConnect.
+	host string
+	// constraints must all be satisfied by the route parameters for the
+	// endpoint to match.
+	constraints []constraint
+	// tlsCode is the status code used to redirect requests that were not made
+	// over TLS, or 0 if the endpoint does not require TLS.
+	tlsCode int
+	// version is the API version that the endpoint handles, or the empty string
+	// if it handles every version.
+	version string
+	// consumes is the media type of request bodies that the endpoint handles,
+	// or the empty string if it handles every media type.
+	consumes string
+	// aliases are the patterns that the handler was registered with using
+	// Alias, including this one.
+	aliases []string
+	// priority is the priority given to the route using Priority.
+	priority int
+	// values are added to the context of requests routed to the endpoint.
+	values []contextValue
+	// gone is true if the handler was registered using Gone or GoneHandler.
+	gone bool
+	// methodNotAllowed is the handler set using OnMethodNotAllowed, if any.
+	methodNotAllowed http.Handler
+	// shadow is the handler set using Shadow, if any.
+	shadow *shadow
+	// split is the second handler of a route registered using Split and
+	// splitKey is the function set using SplitKey, if any.
+	split    *split
+	splitKey func(*http.Request) string
+	// enabled is the predicate set using Enabled, if any.
+	enabled func(*http.Request) bool
+	// deprecation is the configuration set using Deprecated, if any.
+	deprecation *deprecation
+	// limiter is the rate limit set using Limit, if any.
+	limiter *limiter
+	// guards are the functions set using Protect, in the order they are
+	// called.
+	guards []func(*http.Request) error
+}
+
+// contextValue is a key and value added to the request context using
+// WithValue.
+type contextValue struct {
+	key, val interface{}
+}
+
+// variant reports whether ep is one of several endpoints that may be registered
+// for the same method and pattern.
+func (ep *endpoint) variant() bool {
+	return ep.version != "" || ep.consumes != ""
+}
+
+// disabled reports whether ep was registered using Enabled with a predicate
+// that returns false for r.
+// Endpoints are never disabled if r is nil.
+func (ep *endpoint) disabled(r *http.Request) bool {
+	return r != nil && ep.enabled != nil && !ep.enabled(r)
+}
+
+// sameVariant reports whether ep and other handle the same version and media
+// type.
+func (ep *endpoint) sameVariant(other *endpoint) bool {
+	return ep.version == other.version && ep.consumes == other.consumes
+}
+
+// overlaps reports whether ep and other, which are registered for the same
+// method and pattern, could both handle the same request.
+func (ep *endpoint) overlaps(other *endpoint) bool {
+	if (ep.version == "") != (other.version == "") {
+		return true
+	}
+	if ep.version != other.version {
+		return false
+	}
+	return ep.consumes == "" || other.consumes == "" || ep.consumes == other.consumes
+}
+
+// constraint is a predicate on the value of a named route parameter.
+type constraint struct {
+	name string
+	f    func(interface{}) bool
+}
+
+// allows reports whether params satisfy every constraint on ep.
+func (ep *endpoint) allows(params []ParamInfo) bool {
+	_, violated := ep.violation(params)
+	return !violated
+}
+
+// violation returns the name of the first parameter in params that does not
+// satisfy a constraint on ep, if any.
+func (ep *endpoint) violation(params []ParamInfo) (name string, violated bool) {
+	for _, c := range ep.constraints {
+		for _, pinfo := range params {
+			if pinfo.Name == c.name && !c.f(pinfo.parse()) {
+				return pinfo.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// segment is a single parsed component of a route pattern.
+type segment struct {
+	name string
+	typ  string
+	// elem is the type of each segment of a wildcard, if it has one.
+	elem string
+}
+
+// parsePattern parses each component of the route pattern r, minus the
+// leading slash.
+// A final {$} component does not match a component of the path, so it is
+// omitted, as is any format extension parameter on the last component.
+func parsePattern(r string) []segment {
+	r, _ = trimExt(r)
+	var segments []segment
+	for part, remain := nextPart(r); part != ""; part, remain = nextPart(remain) {
+		name, typ := parseParam(part)
+		if typ == typEnd {
+			break
+		}
+		segments = append(segments, segment{name: name, typ: typ, elem: wildElem(part)})
+	}
+	return segments
+}
+
+// handlerSet holds the endpoints registered on a node sorted by method, then by
+// version, and then by media type.
+// Each method has at most one endpoint unless the endpoints for the method
+// were registered for distinct versions or media types.
+// Most nodes have handlers for very few methods, so a slice is both smaller and
+// faster to search than a map.
+type handlerSet []methodEndpoint
+
+type methodEndpoint struct {
+	method string
+	ep     *endpoint
+}
+
+// get returns the endpoint registered for method.
+// If there are several variants of the endpoint, the first is returned.
+func (hs handlerSet) get(method string) (*endpoint, bool) {
+	for _, h := range hs {
+		if h.method == method {
+			return h.ep, true
+		}
+	}
+	return nil, false
+}
+
+// enabled returns the first endpoint registered for method that is not
+// disabled for r.
+func (hs handlerSet) enabled(method string, r *http.Request) (*endpoint, bool) {
+	for _, h := range hs {
+		if h.method == method && !h.ep.disabled(r) {
+			return h.ep, true
+		}
+	}
+	return nil, false
+}
+
+// getVariant returns the endpoint registered for method with the same version
+// and media type as ep.
+func (hs handlerSet) getVariant(method string, ep *endpoint) (*endpoint, bool) {
+	for _, h := range hs {
+		if h.method == method && h.ep.sameVariant(ep) {
+			return h.ep, true
+		}
+	}
+	return nil, false
+}
+
+// overlaps returns an endpoint registered for method that could handle the
+// same requests as ep, if any.
+func (hs handlerSet) overlaps(method string, ep *endpoint) (*endpoint, bool) {
+	for _, h := range hs {
+		if h.method == method && h.ep.overlaps(ep) {
+			return h.ep, true
+		}
+	}
+	return nil, false
+}
+
+// set registers ep for method, replacing any existing endpoint with the same
+// version and media type.
+func (hs *handlerSet) set(method string, ep *endpoint) {
+	i := sort.Search(len(*hs), func(i int) bool {
+		h := (*hs)[i]
+		switch {
+		case h.method != method:
+			return h.method > method
+		case h.ep.version != ep.version:
+			return h.ep.version > ep.version
+		}
+		return h.ep.consumes >= ep.consumes
+	})
+	if i < len(*hs) && (*hs)[i].method == method && (*hs)[i].ep.sameVariant(ep) {
+		(*hs)[i].ep = ep
+		return
+	}
+	*hs = append(*hs, methodEndpoint{})
+	copy((*hs)[i+1:], (*hs)[i:])
+	(*hs)[i] = methodEndpoint{method: method, ep: ep}
+}
+
+// remove removes every variant of the endpoint registered for method and
+// reports whether there was one.
+func (hs *handlerSet) remove(method string) bool {
+	kept := (*hs)[:0:0]
+	for _, h := range *hs {
+		if h.method != method {
+			kept = append(kept, h)
+		}
+	}
+	if len(kept) == len(*hs) {
+		return false
+	}
+	*hs = kept
+	return true
+}
+
+// anchoredRoute returns the route of an endpoint registered on n that ends in
+// {$}, if any.
+// It is safe to call on a nil node.
+func (n *node) anchoredRoute() (string, bool) {
+	if n == nil {
+		return "", false
+	}
+	for _, h := range n.handlers {
+		if strings.HasSuffix(h.ep.route, "{$}") {
+			return h.ep.route, true
+		}
+	}
+	return "", false
+}
+
+// named returns an endpoint registered on n or any node below it with the
+// given name, if any.
+func (n *node) named(name string) (*endpoint, bool) {
+	for _, h := range n.handlers {
+		if h.ep.name == name {
+			return h.ep, true
+		}
+	}
+	for _, c := range []*node{n.slash, n.subtree} {
+		if c == nil {
+			continue
+		}
+		if ep, ok := c.named(name); ok {
+			return ep, true
+		}
+	}
+	for i := range n.child {
+		if ep, ok := n.child[i].named(name); ok {
+			return ep, true
+		}
+	}
+	return nil, false
+}
+
+// aliasOf reports whether ep and other were registered together using Alias.
+func (ep *endpoint) aliasOf(other *endpoint) bool {
+	for _, alias := range ep.aliases {
+		if alias == "/"+other.route {
+			return true
+		}
+	}
+	return false
+}
+
+// methods returns the sorted methods that have handlers registered on n.
+func (n *node) methods() []string {
+	verbs := make([]string, 0, len(n.handlers))
+	for _, h := range n.handlers {
+		// Versions of the same endpoint are adjacent.
+		if len(verbs) > 0 && verbs[len(verbs)-1] == h.method {
+			continue
+		}
+		verbs = append(verbs, h.method)
+	}
+	return verbs
+}
+
+// clone returns a deep copy of n that shares no mutable state with the
+// original.
+func (n *node) clone() *node {
+	c := *n
+	c.handlers = append(handlerSet(nil), n.handlers...)
+	if n.slash != nil {
+		c.slash = n.slash.clone()
+	}
+	if n.subtree != nil {
+		c.subtree = n.subtree.clone()
+	}
+	if n.child != nil {
+		c.child = make([]node, len(n.child))
+		for i := range n.child {
+			c.child[i] = *n.child[i].clone()
+		}
+	}
+	return &c
+}
+
+// end returns the node that a request path ending at n resolves to.
+// If the path ended in a slash this is the slash node of n, which may be nil,
+// unless n is a wildcard node which also matches the trailing slash.
+func (n *node) end(slash bool) *node {
+	if !slash || n.typ == typWild {
+		return n
+	}
+	return n.slash
+}
+
+// hasSlash reports whether the route r ends in a trailing slash that is
+// significant when matching.
+func hasSlash(r string) bool {
+	return len(r) > 0 && r[len(r)-1] == '/'
+}
+
+// find returns the node that exactly matches the route r or nil if no such node
+// exists.
+func (n *node) find(r string) *node {
+	slash := hasSlash(r)
+	for part, remain := nextPart(r); part != ""; part, remain = nextPart(remain) {
+		name, typ := parseParam(part)
+		var next *node
+		for i := range n.child {
+			if n.child[i].name == name && n.child[i].typ == typ {
+				next = &n.child[i]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n.end(slash)
+}
+
+// remove deletes the handler for method from the node that exactly matches the
+// route r and prunes any nodes that are left without handlers, children, or
+// subtree.
+// It reports whether a handler was removed.
+func (n *node) remove(method, r string) bool {
+	if r == "/" && n.typ != typWild {
+		if n.slash == nil || !n.slash.remove(method, "") {
+			return false
+		}
+		if len(n.slash.handlers) == 0 {
+			n.slash = nil
+		}
+		return true
+	}
+	if r == "" || r == "/" {
+		if !n.handlers.remove(method) {
+			return false
+		}
+		if len(n.handlers) == 0 {
+			n.ext = ""
+		}
+		n.resetMethodNotAllowed()
+		return true
+	}
+
+	part, remain := nextPart(r)
+	if remain == "" && hasSlash(r) {
+		// Keep the trailing slash so that it is removed from the slash node.
+		remain = "/"
+	}
+	name, typ := parseParam(part)
+	for i := range n.child {
+		child := &n.child[i]
+		if child.name != name || child.typ != typ {
+			continue
+		}
+		if !child.remove(method, remain) {
+			return false
+		}
+		if len(child.handlers) == 0 && len(child.child) == 0 && child.slash == nil && child.subtree == nil {
+			n.child = append(n.child[:i:i], n.child[i+1:]...)
+		}
+		return true
+	}
+	return false
+}
+
+// fold finds every route under n that has a handler for method and
+// that matches path when static components are compared case insensitively.
+// The escaped path of each route (using the spelling of static components from
+// the route and the components of path for parameters) is appended to found,
+// prefixed by prefix.
+// If slash is true, the full request path ended in a trailing slash.
+func (n *node) fold(path, prefix string, unescape, slash bool, method string, found *[]string) {
+	if path == "" {
+		end := n.end(slash)
+		if end == nil {
+			return
+		}
+		if _, ok := end.handlers.get(method); ok {
+			if end != n {
+				prefix += "/"
+			}
+			*found = append(*found, prefix)
+		}
+		return
+	}
+
+	part, remain := nextPart(path)
+	for i := range n.child {
+		child := &n.child[i]
+		switch child.typ {
+		case typStatic:
+			decoded := part
+			if unescape {
+				var err error
+				decoded, err = url.PathUnescape(part)
+				if err != nil {
+					return
+				}
+			}
+			if !strings.EqualFold(decoded, child.name) {
+				continue
+			}
+			child.fold(remain, prefix+"/"+escapeComponent(child.name, false), unescape, slash, method, found)
+		case typWild:
+			if _, _, ok := child.match(path, 0, unescape); ok {
+				child.fold("", prefix+"/"+escapeComponent(path, unescape), unescape, slash, method, found)
+			}
+		default:
+			if _, _, ok := child.match(path, 0, unescape); ok {
+				child.fold(remain, prefix+"/"+escapeComponent(part, unescape), unescape, slash, method, found)
+			}
+		}
+	}
+}
+
+// escapeComponent returns the escaped form of a component (or components) of
+// the request path.
+// If escaped is true, the component is already escaped and is returned
+// unaltered.
+func escapeComponent(s string, escaped bool) string {
+	if escaped {
+		return s
+	}
+	u := url.URL{Path: s}
+	return u.EscapedPath()
+}
+
+// match attempts to match the first component of path against n.
+// If unescape is true, path is expected to be percent-encoded and each
+// component is decoded before it is compared or parsed.
+// If n matches, the remainder of the path is returned along with the parameter
+// that was matched, if n is a variable node.
+func (n *node) match(path string, offset uint, unescape bool) (remain string, pinfo ParamInfo, ok bool) {
 	// Nil nodes never match.
 	if n == nil {
-		return "", "", r
+		return path, pinfo, false
 	}
 
 	// wildcards are a special case that always match the entire remainder of the
 	// path.
+	var part string
 	if n.typ == typWild {
-		r = addValue(r, n.name, n.typ, path, offset, path)
-		return path, "", r
+		part = path
+	} else {
+		part, remain = nextPart(path)
+	}
+	encoded := part
+	if unescape {
+		var err error
+		part, err = url.PathUnescape(part)
+		if err != nil {
+			return path, pinfo, false
+		}
 	}
+	if part == "" {
+		return path, pinfo, false
+	}
+
+	pinfo = ParamInfo{
+		Raw:     part,
+		Encoded: encoded,
+		Name:    n.name,
+		Type:    n.typ,
 
-	part, remain = nextPart(path)
-	switch n.typ {
-	case typStatic:
+		offset: offset,
+		tail:   len(path),
+	}
+	if n.typ == typStatic {
 		if n.name == part {
-			return part, remain, r
+			return remain, ParamInfo{}, true
 		}
-		return "", path, r
-	case typString:
-		r = addValue(r, n.name, n.typ, part, offset, part)
-		return part, remain, r
-	case typUint:
-		v, err := strconv.ParseUint(part, 10, 64)
-		if err != nil {
-			return "", path, r
+		return path, ParamInfo{}, false
+	}
+	// The parameter is still returned so that the caller can report the
+	// component that did not match the type.
+	if !validParam(n.typ, part) {
+		return path, pinfo, false
+	}
+	if n.elem != "" {
+		pinfo.elem = n.elem
+		if !validSegments(n.elem, part) {
+			return path, pinfo, false
 		}
-		r = addValue(r, n.name, n.typ, part, offset, v)
-		return part, remain, r
-	case typInt:
-		v, err := strconv.ParseInt(part, 10, 64)
-		if err != nil {
-			return "", path, r
+	}
+	return remain, pinfo, true
+}
+
+// validSegments reports whether every slash separated segment of raw is
+// non-empty and can be parsed as a parameter of type elem.
+func validSegments(elem, raw string) bool {
+	for {
+		seg := raw
+		i := strings.IndexByte(raw, '/')
+		if i != -1 {
+			seg, raw = raw[:i], raw[i+1:]
 		}
-		r = addValue(r, n.name, n.typ, part, offset, v)
-		return part, remain, r
-	case typFloat:
-		v, err := strconv.ParseFloat(part, 64)
-		if err != nil {
-			return "", path, r
+		if seg == "" || !validParam(elem, seg) {
+			return false
+		}
+		if i == -1 {
+			return true
 		}
-		r = addValue(r, n.name, n.typ, part, offset, v)
-		return part, remain, r
 	}
-	panic("unknown type")
 }
 
-func addValue(r *http.Request, name, typ, raw string, offset uint, val interface{}) *http.Request {
-	if name != "" {
-		pinfo := ParamInfo{
-			Value: val,
-			Raw:   raw,
-			Name:  name,
-			Type:  typ,
+// validParam reports whether raw can be parsed as a parameter of type typ.
+func validParam(typ, raw string) bool {
+	var err error
+	switch typ {
+	case typWild, typString:
+	case typUint:
+		_, err = strconv.ParseUint(raw, 10, 64)
+	case typInt:
+		_, err = strconv.ParseInt(raw, 10, 64)
+	case typFloat:
+		_, err = strconv.ParseFloat(raw, 64)
+	default:
+		panic("unknown type")
+	}
+	return err == nil
+}
+
+// parse returns the parsed value of the parameter: a []string of its segments
+// if it is a wildcard with a segment type, or the value of Raw otherwise.
+func (pinfo ParamInfo) parse() interface{} {
+	if pinfo.elem == "" {
+		return paramValue(pinfo.Type, pinfo.Raw)
+	}
+	if pinfo.Raw == "" {
+		return []string{}
+	}
+	return strings.Split(pinfo.Raw, "/")
+}
+
+// paramValue returns the parsed value of raw, which must be a valid parameter
+// of type typ.
+func paramValue(typ, raw string) interface{} {
+	switch typ {
+	case typUint:
+		v, _ := strconv.ParseUint(raw, 10, 64)
+		return v
+	case typInt:
+		v, _ := strconv.ParseInt(raw, 10, 64)
+		return v
+	case typFloat:
+		v, _ := strconv.ParseFloat(raw, 64)
+		return v
+	}
+	return raw
+}
+
+// withEncoded sets the Encoded field of each parameter in params, which were
+// matched against the unescaped form of a path of length n, to the matching
+// part of escaped.
+func withEncoded(params []ParamInfo, n int, escaped string) []ParamInfo {
+	for i := range params {
+		start := n - params[i].tail
+		params[i].Encoded = encodedSpan(escaped, start, start+len(params[i].Raw))
+	}
+	return params
+}
 
-			offset: offset,
+// encodedSpan returns the part of the escaped path that decodes to the bytes
+// from start to end of the unescaped path.
+func encodedSpan(escaped string, start, end int) string {
+	var from, i int
+	for n := 0; i < len(escaped); n++ {
+		if n == start {
+			from = i
 		}
-		return r.WithContext(context.WithValue(r.Context(), ctxParam(name), pinfo))
+		if n == end {
+			return escaped[from:i]
+		}
+		if escaped[i] == '%' && i+2 < len(escaped) {
+			i += 3
+		} else {
+			i++
+		}
+	}
+	if start >= end {
+		return ""
+	}
+	return escaped[from:]
+}
+
+// withValues sets the Value field of each parameter in params.
+func withValues(params []ParamInfo) []ParamInfo {
+	for i := range params {
+		params[i].Value = params[i].parse()
 	}
-	return r
+	return params
 }
@@ -3,93 +3,169 @@ package mux
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 )
 
 var (
-	errNoRoute = errors.New("mux: no route was found in the context")
-	errNoParam = errors.New("mux: context was missing an expected parameter")
+	// ErrNoRoute is returned when a request was not routed to a handler
+	// registered on a ServeMux, for example because it never passed through
+	// one or because it was passed to the NotFound or MethodNotAllowed handler.
+	ErrNoRoute = errors.New("mux: no route was found in the context")
+
+	// ErrNoParam is returned when a parameter of the route that a request was
+	// routed to is missing from its context.
+	// Errors wrapping it include the name of the parameter.
+	ErrNoParam = errors.New("mux: context was missing an expected parameter")
 )
 
+// noParam returns an error wrapping ErrNoParam for the named parameter.
+func noParam(name string) error {
+	return fmt.Errorf("%w %q", ErrNoParam, name)
+}
+
 // WithParam returns a shallow copy of r with a new context that shadows the
 // given route parameter.
 // If the parameter does not exist, the original request is returned unaltered.
 //
 // Because WithParam is used to normalize request parameters after the route
 // has already been resolved, all replaced parameters are of type string.
+// On Go 1.22 and later the path value of the parameter is also replaced.
 func WithParam(r *http.Request, name, val string) *http.Request {
-	pinfo := Param(r, name)
-	if pinfo.Value == nil {
+	rc := routeFrom(r)
+	if rc == nil {
 		return r
 	}
+	for i, pinfo := range rc.values() {
+		if pinfo.Name != name {
+			continue
+		}
+		pinfo.Value = val
+		pinfo.Raw = val
+		pinfo.Encoded = escapeComponent(val, false)
+		pinfo.Type = typString
 
-	pinfo.Value = val
-	pinfo.Raw = val
-	pinfo.Type = typString
-	return r.WithContext(context.WithValue(r.Context(), ctxParam(name), pinfo))
+		// The stored slice is shared with other requests derived from the same
+		// context, so it must be copied before it is modified.
+		params := make([]ParamInfo, len(rc.params))
+		copy(params, rc.params)
+		params[i] = pinfo
+		newRC := &routeContext{
+			ep:     rc.ep,
+			params: params,
+			rest:   rc.rest,
+			locale: rc.locale,
+			arm:    rc.arm,
+		}
+		// The values were already parsed when they were copied.
+		newRC.once.Do(func() {})
+		return withPathValue(r, context.WithValue(r.Context(), ctxRoute{}, newRC), pinfo)
+	}
+	return r
 }
 
 // Path returns the request path by applying the route parameters found in the
 // context to the route used to match the given request.
-// This value may be different from r.URL.Path if some form of normalization has
-// been applied to a route parameter, in which case the user may choose to issue
-// a redirect to the canonical path.
+// The path is escaped: named parameters are rendered using their Encoded
+// value, and other components of the path using the text that appeared in
+// r.URL.EscapedPath().
+// This value may be different from r.URL.EscapedPath() if some form of
+// normalization has been applied to a route parameter, in which case the user
+// may choose to issue a redirect to the canonical path.
+//
+// If r was not routed to a handler registered on a ServeMux, the error is
+// ErrNoRoute.
+// As with Pattern, this includes requests passed unaltered to routes without
+// named parameters, metadata, a name, or values added using WithValue, whose
+// path is always r.URL.EscapedPath().
 func Path(r *http.Request) (string, error) {
-	route := r.Context().Value(ctxRoute{}).(string)
-	if route == "" {
-		return "", errNoRoute
+	rc := routeFrom(r)
+	// Requests that did not pass through a ServeMux, or that were passed to the
+	// NotFound or MethodNotAllowed handlers, do not have an endpoint.
+	if rc == nil || rc.ep == nil {
+		return "", ErrNoRoute
 	}
-	hasTrailingSlash := strings.HasSuffix(route, "/")
-	oldPath := strings.TrimPrefix(r.URL.Path, "/")
-
-	var canonicalPath strings.Builder
-	// Give us a comfortable capacity so that we have to resize the buffer less
-	// often.
-	canonicalPath.Grow(len(route))
+	// A final {$} is rendered as the trailing slash that it matches.
+	route, _ := trimEnd(rc.ep.route)
+	route, ext := trimExt(route)
 
-	for {
-		var component, pathComponent string
-		pathComponent, oldPath = nextPart(oldPath)
-
-		component, route = nextPart(route)
-		if component == "" {
-			// Add back any trailing slash consumed by nextPart.
-			if hasTrailingSlash {
-				err := canonicalPath.WriteByte('/')
-				if err != nil {
-					return "", err
-				}
-			}
-			break
+	// Find the value of each component first so that the exact size of the
+	// resulting path is known.
+	var buf [8]string
+	parts := buf[:0]
+	size := len(rc.ep.segments)
+	decoded, escaped := r.URL.Path, r.URL.EscapedPath()
+	remain := strings.TrimPrefix(decoded, "/")
+	// The locale prefix is not part of the route, but it is kept in the path.
+	var prefix string
+	if rc.locale != "" {
+		pinfo, ok := rc.param(rc.locale)
+		if !ok {
+			return "", noParam(rc.locale)
 		}
-		err := canonicalPath.WriteByte('/')
-		if err != nil {
-			return "", err
+		prefix = "/" + pinfo.Encoded
+		size += len(prefix)
+		_, remain = nextPart(remain)
+	}
+	for _, seg := range rc.ep.segments {
+		start := len(decoded) - len(remain)
+		var part string
+		if seg.typ == typWild {
+			part, remain = remain, ""
+		} else {
+			part, remain = nextPart(remain)
 		}
-		name, typ := parseParam(component)
 		switch {
-		case typ == typStatic:
-			_, err = canonicalPath.WriteString(name)
-			if err != nil {
-				return "", err
-			}
-		case name == "":
-			_, err = canonicalPath.WriteString(pathComponent)
-			if err != nil {
-				return "", err
+		case seg.typ == typWild && part == "":
+			// An empty wildcard is rendered without the slash before it.
+			continue
+		case seg.typ == typStatic:
+			part = escapeComponent(seg.name, false)
+		case seg.name != "":
+			pinfo, ok := rc.param(seg.name)
+			if !ok {
+				return "", noParam(seg.name)
 			}
+			part = pinfo.Encoded
 		default:
-			pinfo := Param(r, name)
-			if pinfo.Value == nil {
-				return "", errNoParam
-			}
-			_, err = canonicalPath.WriteString(pinfo.Raw)
-			if err != nil {
-				return "", err
-			}
+			part = encodedSpan(escaped, start, start+len(part))
+		}
+		parts = append(parts, part)
+		size += len(part)
+	}
+	// The format extension is only rendered if the request had one.
+	if ext != "" {
+		if pinfo, ok := rc.param(ext); ok {
+			parts[len(parts)-1] += "." + pinfo.Encoded
+			size += 1 + len(pinfo.Encoded)
 		}
 	}
+	// The root route, including /{$}, has no segments and is rendered as its
+	// slash.
+	hasTrailingSlash := route == "" || strings.HasSuffix(route, "/")
+	if hasTrailingSlash {
+		size++
+	}
+	// Subtrees match the remainder of the path verbatim.
+	if rc.ep.subtree {
+		remain = encodedSpan(escaped, len(decoded)-len(remain), len(decoded))
+	} else {
+		remain = ""
+	}
+	size += len(remain)
 
+	var canonicalPath strings.Builder
+	canonicalPath.Grow(size)
+	canonicalPath.WriteString(prefix)
+	for _, part := range parts {
+		canonicalPath.WriteByte('/')
+		canonicalPath.WriteString(part)
+	}
+	// Add back any trailing slash consumed by nextPart.
+	if hasTrailingSlash {
+		canonicalPath.WriteByte('/')
+	}
+	canonicalPath.WriteString(remain)
 	return canonicalPath.String(), nil
 }
@@ -0,0 +1,189 @@
+package mux
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Observation describes a request that was served by a ServeMux.
+type Observation struct {
+	// Method is the request method.
+	Method string
+	// Kind describes how the request was routed.
+	Kind MatchKind
+	// Pattern is the pattern of the route that matched the request path.
+	// If no route matched, for example if Kind is KindNotFound or KindRedirect,
+	// Pattern is empty.
+	Pattern string
+	// Status is the status code of the response.
+	// If the handler panicked, Status is 500.
+	Status int
+	// Bytes is the number of bytes written to the response body.
+	Bytes int64
+	// Duration is the time taken to route and serve the request.
+	Duration time.Duration
+	// Shadow is true if the request was a copy passed to a handler set using
+	// Shadow, in which case the response was discarded.
+	Shadow bool
+	// Arm is the arm of the route registered using Split that served the
+	// request, or the empty string if the route was not registered using
+	// Split.
+	Arm string
+}
+
+// Observe configures a function to be called with information about each
+// request after it has been served, for example to record per-route metrics.
+// f is called exactly once for each request, even if the handler panics, and
+// must be safe to call concurrently.
+func Observe(f func(o Observation)) Option {
+	return func(mux *ServeMux) {
+		mux.observe = f
+	}
+}
+
+// serveObserved serves r using the handler from res and reports the outcome
+// to the observe function.
+// shadow reports whether r is a copy being passed to a shadow handler.
+func (mux *ServeMux) serveObserved(w http.ResponseWriter, r *http.Request, res MatchResult, start time.Time, shadow bool) {
+	ow := &observeWriter{ResponseWriter: w}
+	defer func() {
+		p := recover()
+		o := Observation{
+			Method:   r.Method,
+			Kind:     res.Kind,
+			Pattern:  res.Pattern,
+			Status:   ow.code,
+			Bytes:    ow.n,
+			Duration: time.Since(start),
+			Shadow:   shadow,
+			Arm:      Arm(r),
+		}
+		if p != nil {
+			o.Status = http.StatusInternalServerError
+		}
+		if o.Status == 0 {
+			o.Status = http.StatusOK
+		}
+		mux.observe(o)
+		if p != nil {
+			panic(p)
+		}
+	}()
+	res.Handler.ServeHTTP(ow, r)
+}
+
+// observeWriter is an http.ResponseWriter that records the status code and
+// number of bytes written.
+type observeWriter struct {
+	http.ResponseWriter
+	code int
+	n    int64
+}
+
+func (w *observeWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+func (w *observeWriter) WriteHeader(statusCode int) {
+	// Informational responses may be followed by the final status.
+	if w.code == 0 && (statusCode < 100 || statusCode >= 200) {
+		w.code = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *observeWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.code == 0 {
+			w.code = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// ReadFrom copies src to the response body, using the ReadFrom method of the
+// underlying http.ResponseWriter if it has one.
+func (w *observeWriter) ReadFrom(src io.Reader) (int64, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	n, err := io.Copy(w.ResponseWriter, src)
+	w.n += n
+	return n, err
+}
+
+// Hijack lets the caller take over the connection if the underlying
+// http.ResponseWriter supports it.
+func (w *observeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hijack(w.ResponseWriter)
+}
+
+// Unwrap returns the underlying http.ResponseWriter.
+func (w *observeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// OnRegister configures a function to be called once for each method and
+// pattern that a handler is successfully registered for using Handle,
+// HandleFunc, HandleMethods, Alias, or Subtree, for example to log the routes
+// or to record them in a service catalog.
+// It is not called for handlers replaced using Override or for routes that
+// conflict with an existing route.
+//
+// OnRegister may be used several times and the functions are called in the
+// order that they were configured.
+// Routes registered before New returns are reported once it has finished
+// applying every option, so every function sees them regardless of the order
+// of the options; routes registered later are reported after they have been
+// added.
+// The functions are called without holding any locks, so they may register
+// routes themselves, and they must be safe to call concurrently if routes are
+// registered concurrently.
+func OnRegister(f func(method, pattern string, h http.Handler)) Option {
+	return func(mux *ServeMux) {
+		mux.mu.Lock()
+		defer mux.mu.Unlock()
+		mux.onRegister = append(mux.onRegister, f)
+	}
+}
+
+// registration is a route that was registered before New returned.
+type registration struct {
+	methods []string
+	pattern string
+	h       http.Handler
+}
+
+// didRegister reports that h was registered for pattern and each of the
+// methods to the functions configured using OnRegister.
+func (mux *ServeMux) didRegister(methods []string, pattern string, h http.Handler) {
+	mux.mu.Lock()
+	if !mux.published {
+		mux.registered = append(mux.registered, registration{methods: methods, pattern: pattern, h: h})
+		mux.mu.Unlock()
+		return
+	}
+	mux.mu.Unlock()
+	mux.notifyRegister(methods, pattern, h)
+}
+
+// notifyRegister calls each function configured using OnRegister for each of
+// the methods.
+func (mux *ServeMux) notifyRegister(methods []string, pattern string, h http.Handler) {
+	mux.mu.Lock()
+	observers := mux.onRegister
+	mux.mu.Unlock()
+	for _, method := range methods {
+		for _, f := range observers {
+			f(method, pattern, h)
+		}
+	}
+}
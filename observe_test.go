@@ -0,0 +1,188 @@
+package mux_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var observeTests = [...]struct {
+	method  string
+	path    string
+	kind    mux.MatchKind
+	pattern string
+	status  int
+	bytes   int64
+	panics  bool
+}{
+	0: {method: http.MethodGet, path: "/user/1", kind: mux.KindMatched, pattern: "/user/{id uint}", status: testCode, bytes: int64(len(testBody))},
+	1: {method: http.MethodPost, path: "/user/1", kind: mux.KindMethodNotAllowed, pattern: "/user/{id uint}", status: http.StatusMethodNotAllowed, bytes: int64(len("Method Not Allowed\n"))},
+	2: {method: http.MethodGet, path: "/nope", kind: mux.KindNotFound, status: http.StatusNotFound, bytes: int64(len("404 page not found\n"))},
+	3: {method: http.MethodGet, path: "/user//1", kind: mux.KindRedirect, status: http.StatusPermanentRedirect},
+	4: {method: http.MethodGet, path: "/panic", kind: mux.KindMatched, pattern: "/panic", status: http.StatusInternalServerError, panics: true},
+	5: {method: http.MethodGet, path: "/empty", kind: mux.KindMatched, pattern: "/empty", status: http.StatusOK},
+}
+
+func TestObserve(t *testing.T) {
+	var observations []mux.Observation
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/user/{id uint}", successHandler(true, true)),
+		mux.HandleFunc(http.MethodGet, "/panic", func(http.ResponseWriter, *http.Request) {
+			panic("oops")
+		}),
+		mux.HandleFunc(http.MethodGet, "/empty", func(http.ResponseWriter, *http.Request) {}),
+		mux.Observe(func(o mux.Observation) {
+			observations = append(observations, o)
+		}),
+	)
+	for i, tc := range observeTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			observations = observations[:0]
+			func() {
+				defer func() {
+					r := recover()
+					if tc.panics != (r != nil) {
+						t.Errorf("Unexpected panic: want=%t, got=%v", tc.panics, r)
+					}
+				}()
+				m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, tc.path, nil))
+			}()
+			if len(observations) != 1 {
+				t.Fatalf("Expected exactly one observation, got %d", len(observations))
+			}
+			o := observations[0]
+			if o.Method != tc.method || o.Kind != tc.kind || o.Pattern != tc.pattern || o.Status != tc.status {
+				t.Errorf("Unexpected observation: want=%s %v %q %d, got=%s %v %q %d", tc.method, tc.kind, tc.pattern, tc.status, o.Method, o.Kind, o.Pattern, o.Status)
+			}
+			if tc.bytes != 0 && o.Bytes != tc.bytes {
+				t.Errorf("Unexpected bytes written: want=%d, got=%d", tc.bytes, o.Bytes)
+			}
+			if o.Duration < 0 {
+				t.Errorf("Unexpected negative duration: %v", o.Duration)
+			}
+		})
+	}
+}
+
+func TestOnRegister(t *testing.T) {
+	var got []string
+	observer := func(name string) func(string, string, http.Handler) {
+		return func(method, pattern string, h http.Handler) {
+			if h == nil {
+				t.Errorf("Expected the handler to be passed to the observer")
+			}
+			got = append(got, name+" "+method+" "+pattern)
+		}
+	}
+	m := mux.New(
+		mux.HandleMethods([]string{http.MethodGet, http.MethodPost}, "/user", failHandler(t)),
+		mux.OnRegister(observer("a")),
+		mux.OnRegister(observer("b")),
+		mux.Alias(http.MethodGet, []string{"/old", "/new"}, failHandler(t)),
+		mux.Subtree(http.MethodGet, "/static/", failHandler(t)),
+		mux.Override(http.MethodGet, "/user", failHandler(t)),
+	)
+	want := []string{
+		"a GET /user", "b GET /user",
+		"a POST /user", "b POST /user",
+		"a GET /old", "b GET /old",
+		"a GET /new", "b GET /new",
+		"a GET /static/", "b GET /static/",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Unexpected registrations:\nwant=%q,\ngot=%q", want, got)
+	}
+
+	got = nil
+	m.HandleFunc(http.MethodDelete, "/user/{id uint}", failHandler(t))
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected registering a conflicting route to panic")
+			}
+		}()
+		m.HandleFunc(http.MethodGet, "/user/{name string}", failHandler(t))
+	}()
+	want = []string{"a DELETE /user/{id uint}", "b DELETE /user/{id uint}"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected registrations after New:\nwant=%q,\ngot=%q", want, got)
+	}
+}
+
+func TestObserveHijack(t *testing.T) {
+	hijack := func(w http.ResponseWriter) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Unexpected error hijacking the connection: %v", err)
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(rw, "HTTP/1.1 %d Hijacked\r\nContent-Length: 0\r\nConnection: close\r\n\r\n", testCode)
+		rw.Flush()
+	}
+	observed := make(chan mux.Observation, 4)
+	m := mux.New(
+		mux.Observe(func(o mux.Observation) {
+			observed <- o
+		}),
+		mux.HandleFunc(http.MethodGet, "/ws", func(w http.ResponseWriter, r *http.Request) {
+			hijack(w)
+		}),
+		mux.HandleErr(http.MethodGet, "/err", func(w http.ResponseWriter, r *http.Request) error {
+			hijack(w)
+			return errors.New("must not be written to the hijacked connection")
+		}),
+		mux.HandleChain(http.MethodGet, "/chain", []http.Handler{
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hijack(w)
+			}),
+			failHandler(t),
+		}),
+		mux.HandleFunc(http.MethodGet, "/copy", func(w http.ResponseWriter, r *http.Request) {
+			rf, ok := w.(io.ReaderFrom)
+			if !ok {
+				t.Errorf("Expected the response writer to be an io.ReaderFrom")
+				return
+			}
+			rf.ReadFrom(strings.NewReader(testBody))
+		}),
+	)
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	for _, path := range []string{"/ws", "/err", "/chain"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("Unexpected error requesting %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != testCode {
+			t.Errorf("Unexpected status code for %s: want=%d, got=%d", path, testCode, resp.StatusCode)
+		}
+	}
+	resp, err := http.Get(srv.URL + "/copy")
+	if err != nil {
+		t.Fatalf("Unexpected error requesting /copy: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != testBody {
+		t.Errorf("Unexpected body: want=%q, got=%q", testBody, body)
+	}
+
+	// The observation may be reported after the client has read the response.
+	for i := 0; i < 4; i++ {
+		o := <-observed
+		if o.Pattern == "/copy" && (o.Bytes != int64(len(testBody)) || o.Status != http.StatusOK) {
+			t.Errorf("Unexpected observation of ReadFrom: %+v", o)
+		}
+	}
+}
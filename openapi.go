@@ -0,0 +1,166 @@
+package mux
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PathItem describes the operations available on a single path in an OpenAPI 3
+// document.
+// It is meant to be marshaled to JSON and used as a starting point for a full
+// API description.
+type PathItem struct {
+	Parameters []Parameter `json:"parameters,omitempty"`
+	Get        *Operation  `json:"get,omitempty"`
+	Put        *Operation  `json:"put,omitempty"`
+	Post       *Operation  `json:"post,omitempty"`
+	Delete     *Operation  `json:"delete,omitempty"`
+	Options    *Operation  `json:"options,omitempty"`
+	Head       *Operation  `json:"head,omitempty"`
+	Patch      *Operation  `json:"patch,omitempty"`
+	Trace      *Operation  `json:"trace,omitempty"`
+}
+
+// Operation describes a single API operation on a path in an OpenAPI 3
+// document.
+type Operation struct {
+	OperationID string              `json:"operationId,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	Deprecated  bool                `json:"deprecated,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Response describes a single response from an API operation in an OpenAPI 3
+// document.
+type Response struct {
+	Description string `json:"description"`
+}
+
+// Parameter describes a single path parameter in an OpenAPI 3 document.
+type Parameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
+	Schema      Schema `json:"schema"`
+}
+
+// Schema describes the type of a parameter in an OpenAPI 3 document.
+type Schema struct {
+	Type    string   `json:"type"`
+	Format  string   `json:"format,omitempty"`
+	Minimum *float64 `json:"minimum,omitempty"`
+}
+
+// OpenAPIPaths returns the skeleton of the paths object of an OpenAPI 3
+// document describing the routes registered on the ServeMux, keyed by path
+// template (for example "/user/{id}").
+//
+// Route parameters are described using the closest matching schema type.
+// Unnamed parameters are given a name based on their position in the path.
+// Parameters of type path are described as strings, but because OpenAPI path
+// parameters cannot contain slashes the resulting template will not match all
+// of the paths that the route does.
+// OpenAPI path parameters cannot be optional, so format extension parameters
+// are omitted and the template describes the route without an extension.
+//
+// If a route has metadata attached using Meta with the key "summary" or
+// "operationId" and a string value it is used to fill in the operation.
+// Routes registered using Gone are marked as deprecated and only respond with
+// 410 (Gone), and routes marked using the Deprecated option are marked as
+// deprecated.
+// Routes registered for methods that OpenAPI does not support are omitted.
+func (mux *ServeMux) OpenAPIPaths() map[string]PathItem {
+	paths := make(map[string]PathItem)
+	for _, route := range mux.Routes() {
+		tmpl, params := openAPITemplate(route.Pattern)
+		item := paths[tmpl]
+		item.Parameters = params
+
+		op := &Operation{
+			Responses: map[string]Response{
+				"default": {Description: "Default response"},
+			},
+		}
+		op.Summary, _ = route.Meta["summary"].(string)
+		op.OperationID, _ = route.Meta["operationId"].(string)
+		op.Deprecated = route.Deprecated
+		if route.Gone {
+			op.Deprecated = true
+			op.Responses = map[string]Response{
+				"410": {Description: "Gone"},
+			}
+		}
+
+		switch route.Method {
+		case http.MethodGet:
+			item.Get = op
+		case http.MethodPut:
+			item.Put = op
+		case http.MethodPost:
+			item.Post = op
+		case http.MethodDelete:
+			item.Delete = op
+		case http.MethodOptions:
+			item.Options = op
+		case http.MethodHead:
+			item.Head = op
+		case http.MethodPatch:
+			item.Patch = op
+		case http.MethodTrace:
+			item.Trace = op
+		default:
+			continue
+		}
+		paths[tmpl] = item
+	}
+	return paths
+}
+
+// openAPITemplate converts a route pattern into an OpenAPI path template and
+// the list of parameters it contains.
+func openAPITemplate(pattern string) (string, []Parameter) {
+	pattern, _ = trimExt(pattern)
+	var params []Parameter
+	parts := strings.Split(pattern, "/")
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		name, typ := parseParam(part)
+		switch typ {
+		case typStatic:
+			continue
+		case typEnd:
+			// A final {$} is the same as a trailing slash.
+			parts[i] = ""
+			continue
+		}
+		if name == "" {
+			name = "param" + strconv.Itoa(len(params)+1)
+		}
+		param := Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+		}
+		switch typ {
+		case typInt:
+			param.Schema = Schema{Type: "integer", Format: "int64"}
+		case typUint:
+			var zero float64
+			param.Schema = Schema{Type: "integer", Format: "int64", Minimum: &zero}
+		case typFloat:
+			param.Schema = Schema{Type: "number", Format: "double"}
+		case typWild:
+			param.Schema = Schema{Type: "string"}
+			param.Description = "The remainder of the path, which may contain slashes."
+		default:
+			param.Schema = Schema{Type: "string"}
+		}
+		params = append(params, param)
+		parts[i] = "{" + name + "}"
+	}
+	return strings.Join(parts, "/"), params
+}
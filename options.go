@@ -3,6 +3,9 @@ package mux
 import (
 	"fmt"
 	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -16,9 +19,13 @@ type Option func(*ServeMux)
 // Not Found) by default instead of 200.
 // If the provided handler explicitly sets the status by calling
 // "http.ResponseWriter".WriteHeader, that status code is used instead.
+//
+// By default, a response in a format negotiated using the Accept header is
+// written as described in the documentation for ErrorRenderer.
 func NotFound(h http.Handler) Option {
 	return func(mux *ServeMux) {
 		mux.notFound = notFoundHandler(h)
+		mux.customNotFound = true
 	}
 }
 
@@ -34,118 +41,757 @@ func Options(f func([]string) http.Handler) Option {
 			return
 		}
 
-		mux.options = func(n node) http.Handler {
-			var verbs []string
-			for v := range n.handlers {
-				verbs = append(verbs, v)
-			}
-			return f(verbs)
+		mux.options = func(_ *http.Request, n *node) http.Handler {
+			return f(n.methods())
+		}
+		mux.customOptions = true
+	}
+}
+
+// OptionsStatus sets the status code used by the default OPTIONS handler.
+// When it is set, the response has an explicit Content-Length of 0 and no
+// body, for example OptionsStatus(http.StatusNoContent) results in a
+// 204 No Content response.
+// By default the response is 200 OK.
+//
+// OptionsStatus has no effect on handlers configured using Options or
+// OptionsHandler.
+func OptionsStatus(code int) Option {
+	return func(mux *ServeMux) {
+		mux.optionsCode = code
+	}
+}
+
+// OptionsHandler is like Options except that f is also passed the request and
+// the pattern of the route that was matched, for example to vary the response
+// based on request headers.
+// If you do not want options handling by default, set f to "nil".
+//
+// Registering handlers for OPTIONS requests on a specific path always overrides
+// the default handler.
+func OptionsHandler(f func(r *http.Request, pattern string, methods []string) http.Handler) Option {
+	return func(mux *ServeMux) {
+		if f == nil {
+			mux.options = nil
+			return
 		}
+
+		mux.options = func(r *http.Request, n *node) http.Handler {
+			return f(r, "/"+n.route, n.methods())
+		}
+		mux.customOptions = true
 	}
 }
 
 // MethodNotAllowed sets the default handler to call when a path is matched to a
 // route, but there is no handler registered for the specific method.
+// The Allow header is set on the response before h is called, and the methods
+// that could have been used can be retrieved from within h using the Allowed
+// function.
 //
-// By default, http.Error with http.StatusMethodNotAllowed is used.
+// By default, a response in a format negotiated using the Accept header is
+// written as described in the documentation for ErrorRenderer.
 func MethodNotAllowed(h http.Handler) Option {
 	return func(mux *ServeMux) {
 		mux.methodNotAllowed = h
+		mux.customMethodNotAllowed = h != nil
+	}
+}
+
+// OnMethodNotAllowed sets the handler to call in place of the MethodNotAllowed
+// handler when a request matches the path of the route but not any of the
+// methods registered for it.
+// The Allow header is set and the allowed methods can be retrieved using
+// Allowed in the same way as for the MethodNotAllowed handler.
+//
+// The handler applies to every method registered with the same pattern,
+// regardless of which of them was registered with the option.
+// If several of them were, the handler from the one registered last is used.
+// Handlers set using DefaultMethod take precedence.
+func OnMethodNotAllowed(h http.Handler) RouteOption {
+	return func(ep *endpoint) {
+		ep.methodNotAllowed = h
+	}
+}
+
+// DefaultMethod sets the handler to call when a request matches the path of a
+// route that does not have a handler registered for method, in place of the
+// MethodNotAllowed handler.
+// This can be used to answer a method the same way for every route, for
+// example to return 501 (Not Implemented) for all PROPFIND requests.
+// The Allow header is set as it is for the MethodNotAllowed handler and it
+// does not list methods that are only handled by a default handler.
+//
+// Default handlers also take precedence over the handlers that are used
+// automatically for OPTIONS and TRACE requests.
+// If h is nil, the default handler for method is removed.
+// If method is not a valid HTTP method, DefaultMethod panics.
+func DefaultMethod(method string, h http.Handler) Option {
+	if !validMethod(method) {
+		panic(&MethodError{Method: method})
+	}
+	method = strings.ToUpper(method)
+	return func(mux *ServeMux) {
+		if h == nil {
+			delete(mux.defaultMethods, method)
+			return
+		}
+		if mux.defaultMethods == nil {
+			mux.defaultMethods = make(map[string]http.Handler)
+		}
+		mux.defaultMethods[method] = h
+	}
+}
+
+// BadRequestOnTypeMismatch sets the handler to call when a request does not
+// match any route because a path component could not be parsed as the type of
+// a route parameter, for example when /orders/{id uint} receives /orders/abc.
+// The parameter that failed to match, including the offending component, can
+// be retrieved from within h using the TypeMismatch function.
+//
+// If the provided handler does not set the status code, it is set to 400 (Bad
+// Request) by default instead of 200.
+// By default, or if h is nil, such requests are handled by the NotFound
+// handler.
+func BadRequestOnTypeMismatch(h http.Handler) Option {
+	return func(mux *ServeMux) {
+		if h == nil {
+			mux.badRequest = nil
+			return
+		}
+		mux.badRequest = defCodeHandler(h, http.StatusBadRequest)
+	}
+}
+
+// RouteOption is used to configure an individual route when it is registered.
+type RouteOption func(*endpoint)
+
+// Meta attaches metadata to a route.
+// Metadata does not affect how requests are matched to routes, but it can be
+// retrieved from within handlers using the Metadata function and is included
+// in the output of Routes.
+// If Meta is used multiple times with the same key the last value is used.
+func Meta(key string, val interface{}) RouteOption {
+	return func(ep *endpoint) {
+		if ep.meta == nil {
+			ep.meta = make(map[string]interface{})
+		}
+		ep.meta[key] = val
+	}
+}
+
+// WithValue adds a value to the context of every request that is routed to the
+// route, where it can be retrieved by the handler and any middleware wrapping
+// it using the Value method of the context, for example to pass
+// route-specific configuration to a handler that is shared by many routes.
+// Values are added after the route parameters, and if WithValue is used
+// multiple times with the same key the last value is used.
+//
+// As with context.WithValue, key must be comparable and should be of an
+// unexported type defined by the caller.
+// If key is nil, is not comparable, or is of a type defined by this package,
+// WithValue panics.
+func WithValue(key, val interface{}) RouteOption {
+	switch {
+	case key == nil:
+		panic("mux: nil context key")
+	case !reflect.TypeOf(key).Comparable():
+		panic("mux: context key is not comparable")
+	case reflect.TypeOf(key).PkgPath() == reflect.TypeOf(ctxRoute{}).PkgPath():
+		panic(fmt.Sprintf("mux: context key of type %T is reserved", key))
+	}
+	return func(ep *endpoint) {
+		ep.values = append(ep.values, contextValue{key: key, val: val})
+	}
+}
+
+// Name gives a route a short, stable name that can be retrieved from within
+// handlers and middleware using RouteName and is included in the output of
+// Routes, for example for use as a metric label.
+// The same name may be used for several methods of the same pattern and for
+// the patterns registered together using Alias, but registering it for any
+// other pattern panics with a *NameError.
+func Name(name string) RouteOption {
+	return func(ep *endpoint) {
+		ep.name = name
+	}
+}
+
+// Priority sets the priority of a route for choosing between routes that
+// match the same request.
+// Registration rejects most routes that could match the same request, so today
+// this only happens when a path matches both a route and a subtree that
+// encloses it: the one with the highest priority is used and, if their
+// priorities are equal, the route is preferred because it is more specific.
+// The default priority is 0 and negative priorities are allowed.
+//
+// Priorities are included in the output of Routes and in the steps returned by
+// Explain.
+func Priority(n int) RouteOption {
+	return func(ep *endpoint) {
+		ep.priority = n
+	}
+}
+
+// Constrain restricts a route to requests where the value of the named route
+// parameter satisfies f.
+// f is called with the parsed value of the parameter (for example uint64(10)
+// for a parameter of type uint) after the path has been matched and before the
+// handler is called.
+// If f returns false the request is handled as though the route did not exist,
+// which normally results in the NotFound handler being called.
+//
+// Constraints do not affect which routes may be registered together: routes
+// that would conflict without them still conflict.
+// If the route does not have a parameter with the given name, registering it
+// panics.
+func Constrain(name string, f func(v interface{}) bool) RouteOption {
+	return func(ep *endpoint) {
+		if _, ext := trimExt(ep.route); ext == name {
+			ep.constraints = append(ep.constraints, constraint{name: name, f: f})
+			return
+		}
+		for _, seg := range ep.segments {
+			if seg.typ != typStatic && seg.name == name {
+				ep.constraints = append(ep.constraints, constraint{name: name, f: f})
+				return
+			}
+		}
+		panic(&PatternError{Pattern: "/" + ep.route, Reason: "no parameter named " + strconv.Quote(name)})
+	}
+}
+
+// Enabled makes a route exist only for requests where f returns true, for
+// example to gate it behind a feature flag that can change at runtime.
+// If f returns false the request is handled exactly as though the route were
+// not registered: an enclosing subtree may match it instead, the NotFound
+// handler is called if no other route does, and the method is left out of the
+// Allow header sent with 405 and OPTIONS responses.
+//
+// f is called on every request that reaches the route, and may be called more
+// than once per request, so it must be cheap and safe for concurrent use.
+// Calling it does not allocate.
+// Match and Explain, which are not passed a request, treat the route as
+// enabled.
+func Enabled(f func(r *http.Request) bool) RouteOption {
+	return func(ep *endpoint) {
+		ep.enabled = f
+	}
+}
+
+// AutoHead configures whether HEAD requests for routes that do not have a HEAD
+// handler are handled by the routes GET handler, if any.
+// When they are, the response body is discarded using a HeadWriter.
+// By default HEAD requests are not handled automatically.
+func AutoHead(enabled bool) Option {
+	return func(mux *ServeMux) {
+		mux.autoHead = enabled
+	}
+}
+
+// EmptyWildcards configures whether parameters of type path may match an empty
+// remainder of the path.
+// When they do, /files/{p path} matches /files and /files/ as well as any path
+// below them, and the Raw value of p is empty.
+// Routes registered for /files or /files/ take precedence over the wildcard.
+// The path returned by Path for an empty wildcard does not end in a slash, so
+// it is /files for both requests.
+// By default wildcards must match at least one character.
+func EmptyWildcards(enabled bool) Option {
+	return func(mux *ServeMux) {
+		mux.emptyWild = enabled
+	}
+}
+
+// Trace configures how TRACE requests for registered paths that do not have a
+// TRACE handler are answered.
+// If enabled, the request line and headers are reflected back to the client
+// with the content type message/http as described in RFC 9110, excluding
+// headers that are likely to contain credentials.
+// Otherwise, the default, they are answered with 405 Method Not Allowed and an
+// Allow header.
+//
+// Registering handlers for TRACE requests on a specific path always overrides
+// the default handler.
+func Trace(enabled bool) Option {
+	return func(mux *ServeMux) {
+		mux.trace = enabled
+	}
+}
+
+// RedirectFixedPath causes requests that do not match any route to be
+// redirected if they would match exactly one route when the static components
+// of the path are compared case insensitively.
+// The redirect uses the spelling of the static components from the route while
+// the values of any route parameters and the query string are preserved
+// verbatim.
+// If code is 0, http.StatusPermanentRedirect is used.
+func RedirectFixedPath(code int) Option {
+	if code == 0 {
+		code = http.StatusPermanentRedirect
+	}
+	return func(mux *ServeMux) {
+		mux.fixedPathCode = code
+	}
+}
+
+// RedirectTrailingSlash causes requests that do not match any route to be
+// redirected if they would match a route after adding or removing a trailing
+// slash.
+// For example, if only /about is registered a request for /about/ is redirected
+// to /about, and if only /docs/ is registered a request for /docs is redirected
+// to /docs/.
+// The query string is preserved.
+// If code is 0, http.StatusPermanentRedirect is used.
+func RedirectTrailingSlash(code int) Option {
+	if code == 0 {
+		code = http.StatusPermanentRedirect
+	}
+	return func(mux *ServeMux) {
+		mux.slashCode = code
+	}
+}
+
+// UseEscapedPath causes requests to be matched against the escaped form of the
+// request path (as returned by "url.URL".EscapedPath) instead of the decoded
+// path.
+// Path components are split on literal slashes only and each component is
+// decoded before it is compared to a route or parsed as a route parameter.
+// This allows parameters to contain percent-encoded slashes: the request
+// /projects/group%2Fname/info matches the route
+// /projects/{name string}/info with name set to "group/name".
+// Requests whose path contains an invalid percent-encoding never match a
+// route.
+func UseEscapedPath() Option {
+	return func(mux *ServeMux) {
+		mux.escaped = true
 	}
 }
 
 // HandleFunc registers the handler for the given pattern.
 // If a handler already exists for pattern, Handle panics.
-func HandleFunc(method, r string, h http.HandlerFunc) Option {
-	return Handle(method, r, h)
+func HandleFunc(method, r string, h http.HandlerFunc, opts ...RouteOption) Option {
+	return Handle(method, r, h, opts...)
 }
 
 // Handle registers the handler for the given pattern.
-// If a handler already exists for pattern, Handle panics.
-func Handle(method, r string, h http.Handler) Option {
-	method = strings.ToUpper(method)
-	if rr := cleanPath(r); rr != r {
-		panic(fmt.Sprintf("route %q is unclean, make sure it is rooted and remove any ., .., or //", r))
+// If a handler already exists for pattern, or method is not a valid HTTP method,
+// Handle panics.
+// The value passed to panic is a pointer to one of the error types in this
+// package, such as *ConflictError, which can be inspected using errors.As after
+// recovering.
+func Handle(method, r string, h http.Handler, opts ...RouteOption) Option {
+	return HandleMethods([]string{method}, r, h, opts...)
+}
+
+// Get registers the handler for GET requests to the given pattern.
+// It is shorthand for Handle(http.MethodGet, r, h, opts...).
+func Get(r string, h http.Handler, opts ...RouteOption) Option {
+	return Handle(http.MethodGet, r, h, opts...)
+}
+
+// GetFunc registers the handler function for GET requests to the given
+// pattern.
+// It is shorthand for HandleFunc(http.MethodGet, r, h, opts...).
+func GetFunc(r string, h http.HandlerFunc, opts ...RouteOption) Option {
+	return Handle(http.MethodGet, r, h, opts...)
+}
+
+// Post registers the handler for POST requests to the given pattern.
+// It is shorthand for Handle(http.MethodPost, r, h, opts...).
+func Post(r string, h http.Handler, opts ...RouteOption) Option {
+	return Handle(http.MethodPost, r, h, opts...)
+}
+
+// PostFunc registers the handler function for POST requests to the given
+// pattern.
+// It is shorthand for HandleFunc(http.MethodPost, r, h, opts...).
+func PostFunc(r string, h http.HandlerFunc, opts ...RouteOption) Option {
+	return Handle(http.MethodPost, r, h, opts...)
+}
+
+// Put registers the handler for PUT requests to the given pattern.
+// It is shorthand for Handle(http.MethodPut, r, h, opts...).
+func Put(r string, h http.Handler, opts ...RouteOption) Option {
+	return Handle(http.MethodPut, r, h, opts...)
+}
+
+// PutFunc registers the handler function for PUT requests to the given
+// pattern.
+// It is shorthand for HandleFunc(http.MethodPut, r, h, opts...).
+func PutFunc(r string, h http.HandlerFunc, opts ...RouteOption) Option {
+	return Handle(http.MethodPut, r, h, opts...)
+}
+
+// Patch registers the handler for PATCH requests to the given pattern.
+// It is shorthand for Handle(http.MethodPatch, r, h, opts...).
+func Patch(r string, h http.Handler, opts ...RouteOption) Option {
+	return Handle(http.MethodPatch, r, h, opts...)
+}
+
+// PatchFunc registers the handler function for PATCH requests to the given
+// pattern.
+// It is shorthand for HandleFunc(http.MethodPatch, r, h, opts...).
+func PatchFunc(r string, h http.HandlerFunc, opts ...RouteOption) Option {
+	return Handle(http.MethodPatch, r, h, opts...)
+}
+
+// Delete registers the handler for DELETE requests to the given pattern.
+// It is shorthand for Handle(http.MethodDelete, r, h, opts...).
+func Delete(r string, h http.Handler, opts ...RouteOption) Option {
+	return Handle(http.MethodDelete, r, h, opts...)
+}
+
+// DeleteFunc registers the handler function for DELETE requests to the given
+// pattern.
+// It is shorthand for HandleFunc(http.MethodDelete, r, h, opts...).
+func DeleteFunc(r string, h http.HandlerFunc, opts ...RouteOption) Option {
+	return Handle(http.MethodDelete, r, h, opts...)
+}
+
+// HandleMethods registers the handler for the given pattern under each of the
+// provided methods.
+// If a handler already exists for pattern and any of the methods, HandleMethods
+// panics and the handler is not registered for any of the other methods.
+func HandleMethods(methods []string, r string, h http.Handler, opts ...RouteOption) Option {
+	if len(methods) == 0 {
+		panic(&MethodError{Pattern: r})
+	}
+	upper := make([]string, 0, len(methods))
+	for _, method := range methods {
+		if !validMethod(method) {
+			panic(&MethodError{Method: method, Pattern: r})
+		}
+		upper = append(upper, strings.ToUpper(method))
+	}
+	methods = upper
+	if err := ValidatePattern(r); err != nil {
+		panic(err)
 	}
 	r = r[1:]
 
-	const (
-		alreadyRegistered = "route already registered for %s /%s"
-	)
+	ep := &endpoint{
+		handler:  h,
+		route:    r,
+		segments: parsePattern(r),
+	}
+	for _, o := range opts {
+		o(ep)
+	}
 
 	return func(mux *ServeMux) {
-		pointer := &mux.node
+		mux.update(func(root *node) {
+			register(root, methods, r, ep)
+		})
+		mux.didRegister(methods, "/"+r, h)
+	}
+}
 
-		// If we're registering a root handler
-		if r == "" {
-			// If it exists already
-			if _, ok := pointer.handlers[method]; ok {
-				panic(fmt.Sprintf(alreadyRegistered, method, r))
+// Alias registers the same handler for the method and each of the patterns,
+// for example to serve both an old and a new URL during a migration.
+// Either every pattern is registered or, if any of them is invalid or
+// conflicts with an existing route, none are and Alias panics.
+//
+// Each route lists the others in the Aliases field of the output of Routes,
+// and the pattern that matched a request can be retrieved using Pattern.
+func Alias(method string, patterns []string, h http.Handler, opts ...RouteOption) Option {
+	if !validMethod(method) {
+		panic(&MethodError{Method: method, Pattern: strings.Join(patterns, " ")})
+	}
+	method = strings.ToUpper(method)
+	patterns = append([]string(nil), patterns...)
+	eps := make([]*endpoint, 0, len(patterns))
+	for _, pattern := range patterns {
+		if err := ValidatePattern(pattern); err != nil {
+			panic(err)
+		}
+		r := pattern[1:]
+		ep := &endpoint{
+			handler:  h,
+			route:    r,
+			segments: parsePattern(r),
+			aliases:  patterns,
+		}
+		for _, o := range opts {
+			o(ep)
+		}
+		eps = append(eps, ep)
+	}
+
+	return func(mux *ServeMux) {
+		mux.update(func(root *node) {
+			// Until New returns the tree is modified in place, so check that every
+			// pattern can be registered on a copy first.
+			trial := root.clone()
+			for _, ep := range eps {
+				register(trial, []string{method}, ep.route, ep)
 			}
-			pointer.route = r
-			pointer.handlers[method] = h
-			return
+			for _, ep := range eps {
+				register(root, []string{method}, ep.route, ep)
+			}
+		})
+		for _, pattern := range patterns {
+			mux.didRegister([]string{method}, pattern, h)
 		}
+	}
+}
 
-	pathloop:
-		for part, remain := nextPart(r); remain != "" || part != ""; part, remain = nextPart(remain) {
-			name, typ := parseParam(part)
+// Override replaces the handler for an existing route.
+// If no handler has been registered for the method and pattern, Override
+// panics.
+// This can be used to deliberately substitute a handler, for example with a
+// stub during testing, without risking a typo silently adding a new route.
+// Routes registered using Subtree cannot be overridden, so Override panics if
+// only a subtree route exists for the method and pattern.
+//
+// Route options that were used when registering the original handler do not
+// carry over to the new handler.
+// If the new handler is given a name that is already used by a route with a
+// different pattern, Override panics.
+func Override(method, r string, h http.Handler, opts ...RouteOption) Option {
+	if !validMethod(method) {
+		panic(&MethodError{Method: method, Pattern: r})
+	}
+	method = strings.ToUpper(method)
+	if err := ValidatePattern(r); err != nil {
+		panic(err)
+	}
+	r = r[1:]
 
-			if typ == typWild && remain != "" {
-				panic(fmt.Sprintf("wildcards must be the last component in a route: /%s", r))
+	ep := &endpoint{
+		handler:  h,
+		route:    r,
+		segments: parsePattern(r),
+	}
+	for _, o := range opts {
+		o(ep)
+	}
+
+	return func(mux *ServeMux) {
+		mux.update(func(root *node) {
+			if ep.name != "" {
+				if existing, ok := root.named(ep.name); ok && existing.route != ep.route && !existing.aliasOf(ep) {
+					panic(&NameError{Name: ep.name, Pattern: "/" + r, Existing: "/" + existing.route})
+				}
+			}
+			end, anchored := trimEnd(r)
+			end, ext := trimExt(end)
+			n := root.find(end)
+			if n == nil || n.ext != ext {
+				panic(&RouteNotFoundError{Method: method, Pattern: "/" + r})
+			}
+			if existing, ok := n.handlers.getVariant(method, ep); !ok || strings.HasSuffix(existing.route, "{$}") != anchored {
+				panic(&RouteNotFoundError{Method: method, Pattern: "/" + r})
 			}
+			n.handlers.set(method, ep)
+			if ep.methodNotAllowed != nil {
+				n.methodNotAllowed = ep.methodNotAllowed
+			} else {
+				n.resetMethodNotAllowed()
+			}
+		})
+	}
+}
 
-			// If there are already children, check that this one is compatible with
-			// them.
-			if len(pointer.child) > 0 {
-				child := pointer.child[0]
-				switch {
-				// All non static routes must have the same type and name.
-				case typ != typStatic && child.typ != typ:
-					panic(fmt.Sprintf("conflicting type found, {%s %s} in route %q conflicts with existing registration of {%s %s}", name, typ, r, pointer.child[0].name, pointer.child[0].typ))
-				case typ != typStatic && child.name != name:
-					panic(fmt.Sprintf("conflicting variable name found, {%s %s} in route %q conflicts with existing registration of {%s %s}", name, typ, r, pointer.child[0].name, pointer.child[0].typ))
+// validMethod reports whether method is a valid HTTP method (a non-empty token
+// as defined by RFC 9110 § 5.6.2).
+func validMethod(method string) bool {
+	if method == "" {
+		return false
+	}
+	for i := 0; i < len(method); i++ {
+		c := method[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) != -1:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// register adds ep to the tree rooted at root for each of the methods.
+// The route r must be clean and must already have had its leading slash
+// removed.
+func register(root *node, methods []string, r string, ep *endpoint) {
+	full := r
+	if ep.name != "" {
+		if existing, ok := root.named(ep.name); ok && existing.route != ep.route && !ep.aliasOf(existing) {
+			panic(&NameError{Name: ep.name, Pattern: "/" + full, Existing: "/" + existing.route})
+		}
+	}
+	r, anchored := trimEnd(r)
+	r, ext := trimExt(r)
+
+	var nparams int
+	for _, seg := range parsePattern(r) {
+		if seg.typ != typStatic && seg.name != "" {
+			nparams++
+		}
+	}
+
+	pointer := root
+	if pointer.maxParams < nparams {
+		pointer.maxParams = nparams
+	}
+
+pathloop:
+	for part, remain := nextPart(r); remain != "" || part != ""; part, remain = nextPart(remain) {
+		name, typ := parseParam(part)
+		elem := wildElem(part)
+
+		if typ == typWild && remain != "" {
+			panic(&PatternError{Pattern: "/" + r, Reason: "wildcards must be the last component in a route"})
+		}
+
+		// If there are already children, check that this one is compatible with
+		// them.
+		if len(pointer.child) > 0 {
+			child := pointer.child[0]
+			switch {
+			// All non static routes must have the same type and name.
+			case typ != typStatic && (child.typ != typ || child.name != name || child.elem != elem),
 				// All static routes must have the same type.
-				case typ == typStatic && child.typ != typ:
-					panic(fmt.Sprintf("conflicting type found, {%s %s} in route %q conflicts with existing registration of {%s %s}", name, typ, r, pointer.child[0].name, pointer.child[0].typ))
+				typ == typStatic && child.typ != typ:
+				panic(&ConflictError{New: "/" + full, Existing: "/" + child.route, Routes: conflicts(pointer.child...)})
+			}
+		}
+
+		// A wildcard would match the same paths as a route anchored with {$} at
+		// this position.
+		if typ == typWild {
+			end := pointer.slash
+			if pointer == root {
+				end = root
+			}
+			if existing, ok := end.anchoredRoute(); ok {
+				panic(&ConflictError{New: "/" + full, Existing: "/" + existing, Routes: conflicts(node{handlers: end.handlers})})
+			}
+		}
+
+		// A format extension would match the same paths as a static sibling that
+		// starts with the same component followed by a dot.
+		if typ == typStatic {
+			for _, child := range pointer.child {
+				switch {
+				case ext != "" && remain == "" && strings.HasPrefix(child.name, name+"."):
+					panic(&ConflictError{New: "/" + full, Existing: "/" + child.route, Routes: conflicts(child)})
+				case child.ext != "" && strings.HasPrefix(name, child.name+"."):
+					panic(&ConflictError{New: "/" + full, Existing: "/" + child.route + "{." + child.ext + "}", Routes: conflicts(node{handlers: child.handlers})})
 				}
 			}
+		}
 
-			// Check if a node already exists in the tree with this name.
-			for i, child := range pointer.child {
-				if child.name == name {
-					if remain == "" {
-						// If this is the path we want to register and no handler has been
-						// registered for it, add one:
-						if _, ok := child.handlers[method]; !ok {
-							pointer.child[i].route = r
-							pointer.child[i].handlers[method] = h
-							continue pathloop
-						} else {
-							// If one already exists and this is the path we were trying to
-							// register, panic.
-							panic(fmt.Sprintf(alreadyRegistered, method, r))
-						}
-					}
-
-					pointer = &pointer.child[i]
-					continue pathloop
+		// Check if a node already exists in the tree with this name.
+		for i, child := range pointer.child {
+			if child.name == name {
+				pointer = &pointer.child[i]
+				if pointer.maxParams < nparams {
+					pointer.maxParams = nparams
 				}
+				continue pathloop
 			}
+		}
 
-			// Not found at his level. Append new node.
-			n := node{
-				name:     name,
-				typ:      typ,
-				handlers: make(map[string]http.Handler),
+		// Not found at his level. Append new node.
+		pointer.child = append(pointer.child, node{
+			name:      name,
+			typ:       typ,
+			elem:      elem,
+			route:     strings.TrimSuffix(r[:len(r)-len(remain)], "/"),
+			maxParams: nparams,
+		})
+		pointer = &pointer.child[len(pointer.child)-1]
+	}
+
+	if anchored && len(pointer.child) > 0 && pointer.child[0].typ == typWild {
+		panic(&ConflictError{New: "/" + full, Existing: "/" + pointer.child[0].route, Routes: conflicts(pointer.child[0])})
+	}
+
+	// Every route on a node must agree on whether it has a format extension so
+	// that requests with an extension are not routed to handlers that do not
+	// expect one.
+	if !hasSlash(r) && len(pointer.handlers) > 0 && pointer.ext != ext {
+		panic(&ConflictError{New: "/" + full, Existing: "/" + pointer.handlers[0].ep.route, Routes: conflicts(node{handlers: pointer.handlers})})
+	}
+
+	switch {
+	case ep.subtree:
+		if pointer.subtree == nil {
+			pointer.subtree = &node{
+				name:      "/...",
+				typ:       typStatic,
+				route:     r,
+				maxParams: nparams,
 			}
-			if remain == "" {
-				n.route = r
-				n.handlers[method] = h
+		}
+		pointer = pointer.subtree
+	// Routes with a trailing slash are registered on a separate node so that
+	// they are distinct from the same route without one.
+	// Wildcards already match any trailing slash.
+	case hasSlash(r) && pointer.typ != typWild:
+		if pointer.slash == nil {
+			pointer.slash = &node{
+				name:      "/",
+				typ:       typStatic,
+				route:     r,
+				maxParams: nparams,
 			}
+		}
+		pointer = pointer.slash
+	}
 
-			pointer.child = append(pointer.child, n)
-			pointer = &pointer.child[len(pointer.child)-1]
+	// Check every method before registering any of them so that a conflict
+	// never results in a partial registration.
+	for i, method := range methods {
+		// Several endpoints may be registered for the same method only if they
+		// can never handle the same request.
+		if existing, ok := pointer.handlers.overlaps(method, ep); ok {
+			panic(&DuplicateError{Method: method, Pattern: "/" + full, Existing: "/" + existing.route})
 		}
+		for _, prev := range methods[:i] {
+			if prev == method {
+				panic(&DuplicateError{Method: method, Pattern: "/" + full, Existing: "/" + full})
+			}
+		}
+	}
+	for _, method := range methods {
+		pointer.handlers.set(method, ep)
+	}
+	if ep.methodNotAllowed != nil {
+		pointer.methodNotAllowed = ep.methodNotAllowed
+	}
+	if !hasSlash(r) {
+		pointer.ext = ext
+	}
+}
+
+// conflicts returns the complete patterns of the routes registered on nodes
+// and every node below them, each preceded by its methods, for reporting in a
+// ConflictError.
+func conflicts(nodes ...node) []string {
+	var routes []RouteInfo
+	for i := range nodes {
+		nodes[i].routes(&routes)
+	}
+	var patterns []string
+	methods := make(map[string][]string)
+	seen := make(map[[2]string]bool)
+	for _, info := range routes {
+		if _, ok := methods[info.Pattern]; !ok {
+			patterns = append(patterns, info.Pattern)
+		}
+		// Versions and media types of the same route share a method.
+		if key := [2]string{info.Pattern, info.Method}; !seen[key] {
+			seen[key] = true
+			methods[info.Pattern] = append(methods[info.Pattern], info.Method)
+		}
+	}
+	sort.Strings(patterns)
+	for i, pattern := range patterns {
+		sort.Strings(methods[pattern])
+		patterns[i] = strings.Join(methods[pattern], ",") + " " + pattern
 	}
+	return patterns
 }
@@ -4,16 +4,24 @@ import (
 	"net/http"
 )
 
-// ctxParam is a type used for context keys that contain route parameters.
-type ctxParam string
-
 // ParamInfo represents a route parameter and related metadata.
 type ParamInfo struct {
 	// The parsed value of the parameter (for example int64(10))
 	// If and only if no such parameter existed on the route, Value will be nil.
+	// For parameters of type path with a segment type (for example
+	// "{p path:uint}") it is a []string of the segments of Raw.
 	Value interface{}
-	// The raw value of the parameter (for example "10")
+	// The raw value of the parameter (for example "10").
+	// Raw is always the percent-decoded text of the path component (or, for
+	// parameters of type path, the remainder of the path) that was matched, and
+	// it is the decoded text that is parsed to produce Value.
+	// For example, both /user/me and /user/%6d%65 result in a Raw value of "me".
 	Raw string
+	// The text of the parameter exactly as it appeared in the escaped request
+	// path (for example "a%2Fb" for a Raw value of "a/b").
+	// Path uses the encoded form to re-render the path so that values are
+	// never escaped twice.
+	Encoded string
 	// The name of the route component that the parameter was matched against (for
 	// example "name" in "{name int}")
 	Name string
@@ -25,11 +33,64 @@ type ParamInfo struct {
 	// route /{foo int} has offset 1 (zero being the root node, which is never a
 	// parameter).
 	offset uint
+	// elem is the type of each segment of a wildcard parameter, if it has one.
+	elem string
+	// tail is the length of the path that was being matched, starting at the
+	// parameter.
+	// It locates the parameter in the escaped form of a path that was matched
+	// in its unescaped form.
+	tail int
+}
+
+// TypeMismatch returns the route parameter that a path component could not be
+// parsed as when no route matched r.
+// Value is always nil, Raw is the offending component, and Name and Type are
+// taken from the route.
+// It is only set on requests passed to the handler configured using
+// BadRequestOnTypeMismatch; for all other requests ok is false.
+func TypeMismatch(r *http.Request) (pinfo ParamInfo, ok bool) {
+	pinfo, ok = r.Context().Value(ctxMismatch{}).(ParamInfo)
+	return pinfo, ok
+}
+
+// param returns the named route parameter and reports whether it was found.
+func (rc *routeContext) param(name string) (ParamInfo, bool) {
+	for _, pinfo := range rc.values() {
+		if pinfo.Name == name {
+			return pinfo, true
+		}
+	}
+	return ParamInfo{}, false
 }
 
 // Param returns the named route parameter from the requests context.
 func Param(r *http.Request, name string) ParamInfo {
-	v := r.Context().Value(ctxParam(name))
-	pinfo, _ := v.(ParamInfo)
+	rc := routeFrom(r)
+	if rc == nil {
+		return ParamInfo{}
+	}
+	pinfo, _ := rc.param(name)
 	return pinfo
 }
+
+// PathParams matches path against pattern without registering it on a
+// ServeMux or constructing a request.
+// Pattern is parsed using the same rules as Handle and an error is returned if
+// it is invalid.
+// If path matches, the parameters are returned exactly as they would be when
+// a request for path is routed by a ServeMux with only pattern registered,
+// otherwise ok is false.
+// Like Match, PathParams does not canonicalize path.
+func PathParams(pattern, path string) (params []ParamInfo, ok bool, err error) {
+	if err := ValidatePattern(pattern); err != nil {
+		return nil, false, err
+	}
+	mux := New(
+		Handle(http.MethodGet, pattern, http.NotFoundHandler()),
+		MaxParamLength(0),
+		MaxWildcardLength(0),
+		MaxSegments(0),
+	)
+	_, _, params, ok = mux.Match(http.MethodGet, path)
+	return params, ok, nil
+}
@@ -1,9 +1,13 @@
 package mux_test
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
 	"strconv"
+	"sync"
 	"testing"
 
 	"code.soquee.net/mux"
@@ -92,24 +96,137 @@ var paramsTests = [...]struct {
 		routes: []string{"/{}/"},
 		path:   "/b/",
 	},
+	10: {
+		routes: []string{"/user/{username string}/{id uint}"},
+		path:   "/user/%6d%65/%31%30",
+		params: []mux.ParamInfo{
+			{Value: "me", Raw: "me", Name: "username", Type: "string"},
+			{Value: uint64(10), Raw: "10", Name: "id", Type: "uint"},
+		},
+	},
+	11: {
+		routes: []string{"/one/{path}"},
+		path:   "/one/two/three",
+	},
+	12: {
+		routes: []string{"/a/{x int}/{}/b/{y string}/{float}/"},
+		path:   "/a/1/any/b/z/1.5/",
+		params: []mux.ParamInfo{
+			{Value: int64(1), Raw: "1", Name: "x", Type: "int"},
+			{Value: "z", Raw: "z", Name: "y", Type: "string"},
+		},
+	},
+	13: {
+		routes: []string{"/user/{id uint}/{$}"},
+		path:   "/user/5/",
+		params: []mux.ParamInfo{
+			{Value: uint64(5), Raw: "5", Name: "id", Type: "uint"},
+		},
+	},
+	14: {
+		routes:  []string{"/user/{id uint}/{$}"},
+		path:    "/user/5/x",
+		noMatch: true,
+	},
+	15: {
+		routes: []string{"/files/{p ...}"},
+		path:   "/files/a/b",
+		params: []mux.ParamInfo{
+			{Value: "a/b", Raw: "a/b", Name: "p", Type: "path"},
+		},
+	},
+	16: {
+		routes: []string{"/legacy/{{id}}/{id int}/a{{b}}c"},
+		path:   "/legacy/{id}/1/a{b}c",
+		params: []mux.ParamInfo{
+			{Value: int64(1), Raw: "1", Name: "id", Type: "int"},
+		},
+	},
+	17: {
+		routes:  []string{"/legacy/{{id}}"},
+		path:    "/legacy/{{id}}",
+		noMatch: true,
+	},
+	18: {
+		routes: []string{"/report{.format}"},
+		path:   "/report.json",
+		params: []mux.ParamInfo{
+			{Value: "json", Raw: "json", Name: "format", Type: "string"},
+		},
+	},
+	19: {
+		routes: []string{"/report{.format}"},
+		path:   "/report",
+	},
+	20: {
+		routes: []string{"/api/v1/report.v2{.format}"},
+		path:   "/api/v1/report.v2.csv",
+		params: []mux.ParamInfo{
+			{Value: "csv", Raw: "csv", Name: "format", Type: "string"},
+		},
+	},
+	21: {
+		routes: []string{"/user/{id uint}/report{.format}"},
+		path:   "/user/5/report.csv",
+		params: []mux.ParamInfo{
+			{Value: uint64(5), Raw: "5", Name: "id", Type: "uint"},
+			{Value: "csv", Raw: "csv", Name: "format", Type: "string"},
+		},
+	},
+	22: {
+		routes:  []string{"/report{.format}"},
+		path:    "/report.json/",
+		noMatch: true,
+	},
+	23: {
+		routes:  []string{"/report{.format}"},
+		path:    "/report.",
+		noMatch: true,
+	},
+	24: {
+		routes: []string{"/report{.format}/edit"},
+		panics: true,
+	},
+	25: {
+		routes:  []string{"/blobs/{p path:uint}"},
+		path:    "/blobs/1/x/3",
+		noMatch: true,
+	},
+	26: {
+		routes:  []string{"/blobs/{p path:uint}"},
+		path:    "/blobs/1/2/",
+		noMatch: true,
+	},
+	27: {
+		routes: []string{"/blobs/{p path:hex}"},
+		panics: true,
+	},
+	28: {
+		routes: []string{"/blobs/{p path:uint}", "/blobs/{p path}"},
+		panics: true,
+	},
 }
 
 // Used as an HTTP status code code to make sure the test path matches at
 // least one of the routes. This is just a sanity check on the tests
 // themselves.
 const (
-	testStatusCode     = 42
-	notFoundStatusCode = 43
+	testStatusCode     = 242
+	notFoundStatusCode = 243
 )
 
 func paramsHandler(t *testing.T, params []mux.ParamInfo) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		p, err := mux.Path(r)
-		if err != nil {
+		switch {
+		case err == mux.ErrNoRoute && len(params) == 0:
+			// Routes without named parameters are passed the request unaltered.
+		case err != nil:
 			t.Errorf("Error while generating canonical path: %v", err)
-		}
-		if p != r.URL.Path {
-			t.Errorf("Unexpected path generated from context: want=%q, got=%q", r.URL.Path, p)
+		default:
+			if want := r.URL.EscapedPath(); p != want {
+				t.Errorf("Unexpected path generated from context: want=%q, got=%q", want, p)
+			}
 		}
 
 		w.WriteHeader(testStatusCode)
@@ -165,9 +282,665 @@ func TestParams(t *testing.T) {
 	}
 }
 
+var paramOffsetTests = [...]struct {
+	routes []string
+	path   string
+	name   string
+	offset uint
+}{
+	0: {routes: []string{"/{id int}"}, path: "/1", name: "id", offset: 1},
+	1: {routes: []string{"/a/x", "/b/x", "/c/{id int}"}, path: "/c/1", name: "id", offset: 2},
+	2: {routes: []string{"/a/x", "/b/x", "/c/d/{id int}"}, path: "/c/d/1", name: "id", offset: 3},
+	3: {routes: []string{"/a/x", "/b/x", "/c/{p path}"}, path: "/c/d/e", name: "p", offset: 2},
+	4: {routes: []string{"/a/x", "/b/x", "/c/{id int}/d/e/{p path}"}, path: "/c/1/d/e/f/g", name: "p", offset: 5},
+	5: {routes: []string{"/0/x", "/1/x", "/2/x", "/3/x", "/4/x", "/5/x", "/6/x", "/7/x", "/8/{id int}"}, path: "/8/1", name: "id", offset: 2},
+}
+
+func TestParamOffset(t *testing.T) {
+	for i, tc := range paramOffsetTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var opts []mux.Option
+			for _, route := range tc.routes {
+				opts = append(opts, mux.HandleFunc("GET", route, func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(testStatusCode)
+					if offset := mux.ParamOffset(mux.Param(r, tc.name)); offset != tc.offset {
+						t.Errorf("Unexpected offset for %q: want=%d, got=%d", tc.name, tc.offset, offset)
+					}
+				}))
+			}
+			m := mux.New(opts...)
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest("GET", tc.path, nil))
+			if rec.Code != testStatusCode {
+				t.Fatalf("Test path (%q) did not match any route!", tc.path)
+			}
+		})
+	}
+}
+
+func TestPathEnd(t *testing.T) {
+	var p string
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err = mux.Path(r)
+		w.WriteHeader(testStatusCode)
+	})
+	// The routes are named so that the route is stored on the request.
+	m := mux.New(
+		mux.Handle("GET", "/{$}", h, mux.Name("root")),
+		mux.Handle("GET", "/docs/{$}", h, mux.Name("docs")),
+	)
+	for _, path := range []string{"/", "/docs/"} {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != testStatusCode {
+			t.Fatalf("Test path (%q) did not match any route!", path)
+		}
+		if err != nil {
+			t.Errorf("Unexpected error from Path for %s: %v", path, err)
+		}
+		if p != path {
+			t.Errorf("Unexpected path: want=%q, got=%q", path, p)
+		}
+	}
+}
+
+func TestPathNoRoute(t *testing.T) {
+	if _, err := mux.Path(httptest.NewRequest("GET", "/", nil)); !errors.Is(err, mux.ErrNoRoute) {
+		t.Errorf("Unexpected error from Path for request that was not routed: want=%v, got=%v", mux.ErrNoRoute, err)
+	}
+
+	pathErr := func(w http.ResponseWriter, r *http.Request) {
+		if _, err := mux.Path(r); !errors.Is(err, mux.ErrNoRoute) {
+			t.Errorf("Unexpected error from Path for %s %s: want=%v, got=%v", r.Method, r.URL.Path, mux.ErrNoRoute, err)
+		}
+		w.WriteHeader(testStatusCode)
+	}
+	m := mux.New(
+		mux.HandleFunc("GET", "/{id int}", codeHandler(t, 200)),
+		mux.NotFound(http.HandlerFunc(pathErr)),
+		mux.MethodNotAllowed(http.HandlerFunc(pathErr)),
+	)
+	for _, tc := range []struct {
+		method string
+		path   string
+	}{
+		{method: "GET", path: "/a"},
+		{method: "POST", path: "/1"},
+	} {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != testStatusCode {
+			t.Errorf("Unexpected code for %s %s: want=%d, got=%d", tc.method, tc.path, testStatusCode, rec.Code)
+		}
+	}
+}
+
 func TestParamNotFound(t *testing.T) {
 	pinfo := mux.Param(httptest.NewRequest("GET", "/", nil), "badparam")
 	if pinfo.Value != nil {
 		t.Errorf("Did not expect to find param but got %+v", pinfo)
 	}
 }
+
+var escapedTests = [...]struct {
+	route    string
+	path     string
+	code     int
+	params   []mux.ParamInfo
+	location string
+}{
+	0: {
+		route: "/projects/{name string}/info",
+		path:  "/projects/group%2Fname/info",
+		code:  testStatusCode,
+		params: []mux.ParamInfo{
+			{Value: "group/name", Raw: "group/name", Name: "name", Type: "string"},
+		},
+	},
+	1: {
+		route: "/projects/{name string}/info",
+		path:  "/projects/100%25/info",
+		code:  testStatusCode,
+		params: []mux.ParamInfo{
+			{Value: "100%", Raw: "100%", Name: "name", Type: "string"},
+		},
+	},
+	2: {
+		route: "/projects/{name string}/{id uint}",
+		path:  "/projects/a%2Fb%20c%25/%31%32",
+		code:  testStatusCode,
+		params: []mux.ParamInfo{
+			{Value: "a/b c%", Raw: "a/b c%", Name: "name", Type: "string"},
+			{Value: uint64(12), Raw: "12", Name: "id", Type: "uint"},
+		},
+	},
+	3: {
+		route: "/files/{p path}",
+		path:  "/files/a%2Fb/c",
+		code:  testStatusCode,
+		params: []mux.ParamInfo{
+			{Value: "a/b/c", Raw: "a/b/c", Name: "p", Type: "path"},
+		},
+	},
+	4: {
+		route: "/projects/{name string}/info",
+		path:  "/projects/group/name/info",
+		code:  notFoundStatusCode,
+	},
+	5: {
+		route: "/projects/with space",
+		path:  "/projects/with%20space",
+		code:  testStatusCode,
+	},
+	6: {
+		route: "/user/{username string}/{id uint}",
+		path:  "/user/%6d%65/%31%30",
+		code:  testStatusCode,
+		params: []mux.ParamInfo{
+			{Value: "me", Raw: "me", Name: "username", Type: "string"},
+			{Value: uint64(10), Raw: "10", Name: "id", Type: "uint"},
+		},
+	},
+	7: {
+		route: "/user/{id uint}",
+		path:  "/user/%31x",
+		code:  notFoundStatusCode,
+	},
+	8: {
+		route:    "/projects/{name string}/info",
+		path:     "/projects//group%2Fname/./info",
+		code:     http.StatusPermanentRedirect,
+		location: "/projects/group%2Fname/info",
+	},
+}
+
+func TestEscapedPath(t *testing.T) {
+	for i, tc := range escapedTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			m := mux.New(
+				mux.UseEscapedPath(),
+				mux.HandleFunc("GET", tc.route, paramsHandler(t, tc.params)),
+				mux.NotFound(codeHandler(t, notFoundStatusCode)),
+			)
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest("GET", tc.path, nil))
+			if rec.Code != tc.code {
+				t.Fatalf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+			if loc := rec.Header().Get("Location"); loc != tc.location {
+				t.Errorf("Unexpected redirect location: want=%q, got=%q", tc.location, loc)
+			}
+		})
+	}
+}
+
+var encodedTests = [...]struct {
+	escaped bool
+	route   string
+	path    string
+	raw     string
+	encoded string
+	canon   string
+}{
+	0: {route: "/files/{p path}", path: "/files/a%2Fb/c", raw: "a/b/c", encoded: "a%2Fb/c", canon: "/files/a%2Fb/c"},
+	1: {escaped: true, route: "/projects/{name string}/info", path: "/projects/a%2Fb/info", raw: "a/b", encoded: "a%2Fb", canon: "/projects/a%2Fb/info"},
+	2: {route: "/q/{name string}", path: "/q/a+b", raw: "a+b", encoded: "a+b", canon: "/q/a+b"},
+	3: {escaped: true, route: "/q/{name string}", path: "/q/a+b%2B", raw: "a+b+", encoded: "a+b%2B", canon: "/q/a+b%2B"},
+	4: {route: "/user/{name string}/x", path: "/user/%C3%A9t%C3%A9/x", raw: "été", encoded: "%C3%A9t%C3%A9", canon: "/user/%C3%A9t%C3%A9/x"},
+	5: {escaped: true, route: "/user/{name string}", path: "/user/%c3%a9", raw: "é", encoded: "%c3%a9", canon: "/user/%c3%a9"},
+	6: {route: "/a/{}/{name string}", path: "/a/%20/%6d%65", raw: "me", encoded: "%6d%65", canon: "/a/%20/%6d%65"},
+	7: {route: "/report{.format}", path: "/report.j%73on", raw: "json", encoded: "j%73on", canon: "/report.j%73on"},
+}
+
+func TestEncoded(t *testing.T) {
+	for i, tc := range encodedTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var pinfo mux.ParamInfo
+			var canon string
+			opts := []mux.Option{
+				mux.HandleFunc(http.MethodGet, tc.route, func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(testStatusCode)
+					for _, name := range []string{"p", "name", "format"} {
+						if p := mux.Param(r, name); p.Value != nil {
+							pinfo = p
+						}
+					}
+					canon, _ = mux.Path(r)
+				}),
+			}
+			if tc.escaped {
+				opts = append(opts, mux.UseEscapedPath())
+			}
+			m := mux.New(opts...)
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rec.Code != testStatusCode {
+				t.Fatalf("Test path (%q) did not match any route!", tc.path)
+			}
+			if pinfo.Raw != tc.raw {
+				t.Errorf("Unexpected raw value: want=%q, got=%q", tc.raw, pinfo.Raw)
+			}
+			if pinfo.Encoded != tc.encoded {
+				t.Errorf("Unexpected encoded value: want=%q, got=%q", tc.encoded, pinfo.Encoded)
+			}
+			if canon != tc.canon {
+				t.Errorf("Unexpected path: want=%q, got=%q", tc.canon, canon)
+			}
+
+			path := tc.path
+			if !tc.escaped {
+				path = httptest.NewRequest(http.MethodGet, tc.path, nil).URL.Path
+			}
+			_, _, params, ok := m.Match(http.MethodGet, path)
+			if !ok || len(params) == 0 {
+				t.Fatalf("Expected Match to find the route")
+			}
+			// Match is given the unescaped path unless UseEscapedPath is used, so the
+			// encoded value is the canonical escaping of the raw value.
+			want := tc.encoded
+			if !tc.escaped {
+				want = (&url.URL{Path: tc.raw}).EscapedPath()
+			}
+			if got := params[len(params)-1].Encoded; got != want {
+				t.Errorf("Unexpected encoded value from Match: want=%q, got=%q", want, got)
+			}
+		})
+	}
+}
+
+func TestWithParamEncoded(t *testing.T) {
+	var p string
+	m := mux.New(mux.HandleFunc(http.MethodGet, "/user/{name string}", func(w http.ResponseWriter, r *http.Request) {
+		r = mux.WithParam(r, "name", "a b")
+		if enc := mux.Param(r, "name").Encoded; enc != "a%20b" {
+			t.Errorf("Unexpected encoded value: want=%q, got=%q", "a%20b", enc)
+		}
+		p, _ = mux.Path(r)
+	}))
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/x", nil))
+	if p != "/user/a%20b" {
+		t.Errorf("Unexpected path: want=%q, got=%q", "/user/a%20b", p)
+	}
+}
+
+func TestParamAllocs(t *testing.T) {
+	m := mux.New(
+		mux.Handle("GET", "/a/{a int}", http.NotFoundHandler()),
+		mux.Handle("GET", "/b/{a int}/{b int}/{c uint}/{d uint}", http.NotFoundHandler()),
+	)
+	allocs := func(path string) float64 {
+		req := httptest.NewRequest("GET", path, nil)
+		return testing.AllocsPerRun(100, func() {
+			m.Handler(req)
+		})
+	}
+	// Small integers do not allocate when they are stored in ParamInfo.Value, so
+	// any difference comes from storing the parameters themselves.
+	one := allocs("/a/1")
+	four := allocs("/b/1/2/3/4")
+	if four != one {
+		t.Errorf("Expected allocations to be independent of the number of parameters: one=%v, four=%v", one, four)
+	}
+}
+
+func TestStaticRouteRequest(t *testing.T) {
+	var got *http.Request
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	})
+	m := mux.New(
+		mux.Handle("GET", "/static", h),
+		mux.Handle("GET", "/unnamed/{int}", h),
+		mux.Handle("GET", "/named", h, mux.Name("named")),
+		mux.Handle("GET", "/param/{a int}", h),
+	)
+	for i, tc := range [...]struct {
+		path string
+		same bool
+	}{
+		0: {path: "/static", same: true},
+		1: {path: "/unnamed/1", same: true},
+		2: {path: "/named"},
+		3: {path: "/param/1"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.path, nil)
+			got = nil
+			m.ServeHTTP(httptest.NewRecorder(), req)
+			if (got == req) != tc.same {
+				t.Errorf("Unexpected request identity for %s: want same=%t, got same=%t", tc.path, tc.same, got == req)
+			}
+		})
+	}
+}
+
+func benchmarkRoute(b *testing.B, route, path string) {
+	m := mux.New(mux.Handle("GET", route, http.NotFoundHandler()))
+	req := httptest.NewRequest("GET", path, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Handler(req)
+	}
+}
+
+func BenchmarkParams1(b *testing.B) {
+	benchmarkRoute(b, "/a/{a int}", "/a/1")
+}
+
+func BenchmarkParams4(b *testing.B) {
+	benchmarkRoute(b, "/a/{a int}/{b string}/{c uint}/{d float}", "/a/1/two/3/4.5")
+}
+
+func BenchmarkStatic(b *testing.B) {
+	benchmarkRoute(b, "/a/b/c/d/e/f/g/h", "/a/b/c/d/e/f/g/h")
+}
+
+func BenchmarkWideStatic(b *testing.B) {
+	var opts []mux.Option
+	for i := 0; i < 300; i++ {
+		opts = append(opts, mux.Handle("GET", "/api/v1/"+strconv.Itoa(i), http.NotFoundHandler()))
+	}
+	m := mux.New(opts...)
+	req := httptest.NewRequest("GET", "/api/v1/299", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Handler(req)
+	}
+}
+
+func BenchmarkPath(b *testing.B) {
+	m := mux.New(mux.Handle("GET", "/a/{a int}/b/{b string}/{c uint}/{d float}/{e path}", http.NotFoundHandler()))
+	_, req := m.Handler(httptest.NewRequest("GET", "/a/1234567890/b/a-rather-long-user-name/1234567890/1234.5678/some/deeply/nested/file.txt", nil))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := mux.Path(req)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParamConcurrent(t *testing.T) {
+	m := mux.New(mux.Handle("GET", "/{a int}/{b float}", http.NotFoundHandler()))
+	_, req := m.Handler(httptest.NewRequest("GET", "/1/2.5", nil))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v := mux.Param(req, "a").Value; v != int64(1) {
+				t.Errorf("Unexpected value for a: want=1, got=%v", v)
+			}
+			if v := mux.Param(req, "b").Value; v != 2.5 {
+				t.Errorf("Unexpected value for b: want=2.5, got=%v", v)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func benchmarkServeParams(b *testing.B, read bool) {
+	const route = "/a/{a int}/{b string}/{c uint}/{d float}"
+	m := mux.New(mux.HandleFunc("GET", route, func(w http.ResponseWriter, r *http.Request) {
+		if read {
+			for _, name := range []string{"a", "b", "c", "d"} {
+				mux.Param(r, name)
+			}
+		}
+	}))
+	req := httptest.NewRequest("GET", "/a/1000/two/3000/4.5", nil)
+	w := httptest.NewRecorder()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkParamsUnread(b *testing.B) {
+	benchmarkServeParams(b, false)
+}
+
+func BenchmarkParamsRead(b *testing.B) {
+	benchmarkServeParams(b, true)
+}
+
+var constrainTests = [...]struct {
+	method string
+	path   string
+	code   int
+}{
+	0: {method: http.MethodGet, path: "/projects/10", code: testStatusCode},
+	1: {method: http.MethodGet, path: "/projects/11", code: notFoundStatusCode},
+	2: {method: http.MethodPost, path: "/projects/11", code: 201},
+	3: {method: http.MethodGet, path: "/projects/10/files/a", code: testStatusCode},
+	4: {method: http.MethodGet, path: "/projects/12/files/a", code: notFoundStatusCode},
+	5: {method: http.MethodGet, path: "/projects/10/files/b", code: notFoundStatusCode},
+}
+
+func TestConstrain(t *testing.T) {
+	even := mux.Constrain("id", func(v interface{}) bool {
+		return v.(uint64)%2 == 0
+	})
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/projects/{id uint}", codeHandler(t, testStatusCode), even),
+		mux.Handle(http.MethodPost, "/projects/{id uint}", codeHandler(t, 201)),
+		mux.Handle(http.MethodGet, "/projects/{id uint}/files/{name string}", codeHandler(t, testStatusCode),
+			mux.Constrain("id", func(v interface{}) bool { return v.(uint64) < 12 }),
+			mux.Constrain("name", func(v interface{}) bool { return v.(string) == "a" }),
+		),
+		mux.NotFound(codeHandler(t, notFoundStatusCode)),
+	)
+	for i, tc := range constrainTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected code for %s %s: want=%d, got=%d", tc.method, tc.path, tc.code, rec.Code)
+			}
+		})
+	}
+}
+
+func TestWildcardSegments(t *testing.T) {
+	m := mux.New(
+		mux.HandleFunc(http.MethodGet, "/blobs/{p path:uint}", func(w http.ResponseWriter, r *http.Request) {
+			pinfo := mux.Param(r, "p")
+			if want := []string{"1", "22", "333"}; !reflect.DeepEqual(pinfo.Value, want) {
+				t.Errorf("Unexpected value: want=%q, got=%#v", want, pinfo.Value)
+			}
+			if pinfo.Raw != "1/22/333" || pinfo.Type != "path" {
+				t.Errorf("Unexpected raw value or type: %q %q", pinfo.Raw, pinfo.Type)
+			}
+			w.WriteHeader(testStatusCode)
+		}, mux.Name("blob")),
+	)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/blobs/1/22/333", nil))
+	if rec.Code != testStatusCode {
+		t.Errorf("Unexpected status code: want=%d, got=%d", testStatusCode, rec.Code)
+	}
+
+	if p, err := m.PathFor("blob", "p", "1/2"); err != nil || p != "/blobs/1/2" {
+		t.Errorf("Unexpected path: %q, %v", p, err)
+	}
+	if _, err := m.PathFor("blob", "p", "1/a"); err == nil {
+		t.Errorf("Expected rendering an invalid segment to fail")
+	}
+}
+
+func TestConstrainUnknownParam(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected constraining an unknown parameter to panic")
+		}
+	}()
+	mux.Handle(http.MethodGet, "/projects/{id uint}", failHandler(t), mux.Constrain("name", func(interface{}) bool {
+		return true
+	}))
+}
+
+var emptyWildcardTests = [...]struct {
+	path  string
+	code  int
+	raw   string
+	canon string
+}{
+	0: {path: "/files", code: testStatusCode, canon: "/files"},
+	1: {path: "/files/", code: testStatusCode, canon: "/files"},
+	2: {path: "/files/a/b", code: testStatusCode, raw: "a/b", canon: "/files/a/b"},
+	3: {path: "/files/a/", code: testStatusCode, raw: "a/", canon: "/files/a/"},
+	4: {path: "/docs", code: 201},
+	5: {path: "/docs/", code: testStatusCode, canon: "/docs"},
+}
+
+func TestEmptyWildcards(t *testing.T) {
+	wildHandler := func(w http.ResponseWriter, r *http.Request) {
+		pinfo := mux.Param(r, "p")
+		if pinfo.Value == nil {
+			t.Errorf("Expected wildcard parameter to be set")
+		}
+		p, _ := mux.Path(r)
+		w.Header().Set("Path", p)
+		w.Header().Set("Raw", pinfo.Raw)
+		w.WriteHeader(testStatusCode)
+	}
+	m := mux.New(
+		mux.HandleFunc(http.MethodGet, "/files/{p path}", wildHandler),
+		mux.HandleFunc(http.MethodGet, "/docs/{p path}", wildHandler),
+		mux.Handle(http.MethodGet, "/docs", codeHandler(t, 201)),
+		mux.EmptyWildcards(true),
+	)
+	for i, tc := range emptyWildcardTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rec.Code != tc.code {
+				t.Fatalf("Unexpected code: want=%d, got=%d", tc.code, rec.Code)
+			}
+			if tc.code != testStatusCode {
+				return
+			}
+			if raw := rec.Header().Get("Raw"); raw != tc.raw {
+				t.Errorf("Unexpected raw value: want=%q, got=%q", tc.raw, raw)
+			}
+			if p := rec.Header().Get("Path"); p != tc.canon {
+				t.Errorf("Unexpected path: want=%q, got=%q", tc.canon, p)
+			}
+		})
+	}
+
+	m = mux.New(
+		mux.HandleFunc(http.MethodGet, "/{p path}", wildHandler),
+		mux.EmptyWildcards(true),
+	)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != testStatusCode {
+		t.Errorf("Expected root wildcard to match empty path: want=%d, got=%d", testStatusCode, rec.Code)
+	}
+
+	m = mux.New(mux.Handle(http.MethodGet, "/files/{p path}", failHandler(t)))
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/files", nil))
+	if rec.Code == testStatusCode {
+		t.Errorf("Expected wildcards not to match empty paths by default")
+	}
+}
+
+func TestFormatExtension(t *testing.T) {
+	var opts []mux.Option
+	for i := 0; i < 10; i++ {
+		opts = append(opts, mux.Handle(http.MethodGet, "/"+strconv.Itoa(i), failHandler(t)))
+	}
+	var format mux.ParamInfo
+	var p string
+	opts = append(opts, mux.HandleFunc(http.MethodGet, "/report{.format}", func(w http.ResponseWriter, r *http.Request) {
+		format = mux.Param(r, "format")
+		p, _ = mux.Path(mux.WithParam(r, "format", "csv"))
+		w.WriteHeader(testStatusCode)
+	}))
+	m := mux.New(opts...)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/report.json", nil))
+	if rec.Code != testStatusCode {
+		t.Fatalf("Unexpected code: want=%d, got=%d", testStatusCode, rec.Code)
+	}
+	if format.Raw != "json" {
+		t.Errorf("Unexpected format: want=%q, got=%q", "json", format.Raw)
+	}
+	if p != "/report.csv" {
+		t.Errorf("Unexpected path: want=%q, got=%q", "/report.csv", p)
+	}
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/report", nil))
+	if rec.Code != testStatusCode {
+		t.Fatalf("Unexpected code: want=%d, got=%d", testStatusCode, rec.Code)
+	}
+	if format.Value != nil {
+		t.Errorf("Expected format to be unset, got %v", format.Value)
+	}
+	if p != "/report" {
+		t.Errorf("Unexpected path: want=%q, got=%q", "/report", p)
+	}
+
+	routes := m.Routes()
+	info := routes[len(routes)-1]
+	if info.Pattern != "/report{.format}" || len(info.ParamNames) != 1 || info.ParamNames[0] != "format" {
+		t.Errorf("Unexpected route info: %+v", info)
+	}
+}
+
+var pathParamsTests = [...]struct {
+	pattern string
+	path    string
+	params  []mux.ParamInfo
+	noMatch bool
+	err     bool
+}{
+	0: {pattern: "/user/{id uint}", path: "/user/12", params: []mux.ParamInfo{{Value: uint64(12), Raw: "12", Encoded: "12", Name: "id", Type: "uint"}}},
+	1: {pattern: "/user/{id uint}", path: "/user/abc", noMatch: true},
+	2: {pattern: "/user/{id uint}", path: "/user/12/", noMatch: true},
+	3: {pattern: "/files/{p path}", path: "/files/a/b c", params: []mux.ParamInfo{{Value: "a/b c", Raw: "a/b c", Encoded: "a/b%20c", Name: "p", Type: "path"}}},
+	4: {pattern: "/{uint}/{name string}", path: "/1/me", params: []mux.ParamInfo{{Value: "me", Raw: "me", Encoded: "me", Name: "name", Type: "string"}}},
+	5: {pattern: "/static", path: "/static"},
+	6: {pattern: "/static", path: "/other", noMatch: true},
+	7: {pattern: "/report{.format}", path: "/report.json", params: []mux.ParamInfo{{Value: "json", Raw: "json", Encoded: "json", Name: "format", Type: "string"}}},
+	8: {pattern: "/user/{id bogus}", path: "/user/12", err: true},
+	9: {pattern: "/user/{id uint}", path: "/user/../user/12", noMatch: true},
+}
+
+func TestPathParams(t *testing.T) {
+	for i, tc := range pathParamsTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			params, ok, err := mux.PathParams(tc.pattern, tc.path)
+			switch {
+			case tc.err && err == nil:
+				t.Fatalf("Expected an error for an invalid pattern")
+			case !tc.err && err != nil:
+				t.Fatalf("Unexpected error: %v", err)
+			case tc.err:
+				return
+			}
+			if ok == tc.noMatch {
+				t.Fatalf("Unexpected match result: want=%t, got=%t", !tc.noMatch, ok)
+			}
+			if len(params) != len(tc.params) {
+				t.Fatalf("Unexpected params: want=%+v, got=%+v", tc.params, params)
+			}
+			for j, pinfo := range params {
+				want := tc.params[j]
+				if pinfo.Value != want.Value || pinfo.Raw != want.Raw || pinfo.Encoded != want.Encoded || pinfo.Name != want.Name || pinfo.Type != want.Type {
+					t.Errorf("Unexpected param %d: want=%+v, got=%+v", j, want, pinfo)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+//go:build go1.22
+// +build go1.22
+
+package mux
+
+import (
+	"context"
+	"net/http"
+)
+
+// setPathValues sets the path value of r for each named parameter in params so
+// that handlers written for http.ServeMux can retrieve them using
+// "http.Request".PathValue.
+func setPathValues(r *http.Request, params []ParamInfo) {
+	for _, pinfo := range params {
+		if pinfo.Name != "" {
+			r.SetPathValue(pinfo.Name, pinfo.Raw)
+		}
+	}
+}
+
+// withPathValue returns a copy of r with its context changed to ctx and the
+// path value for pinfo replaced.
+// Path values are stored on the request itself and a shallow copy would share
+// them with r, so the request is cloned instead.
+func withPathValue(r *http.Request, ctx context.Context, pinfo ParamInfo) *http.Request {
+	r = r.Clone(ctx)
+	r.SetPathValue(pinfo.Name, pinfo.Raw)
+	return r
+}
@@ -0,0 +1,17 @@
+//go:build !go1.22
+// +build !go1.22
+
+package mux
+
+import (
+	"context"
+	"net/http"
+)
+
+// setPathValues does nothing on versions of Go that do not support path values.
+func setPathValues(r *http.Request, params []ParamInfo) {}
+
+// withPathValue returns a shallow copy of r with its context changed to ctx.
+func withPathValue(r *http.Request, ctx context.Context, pinfo ParamInfo) *http.Request {
+	return r.WithContext(ctx)
+}
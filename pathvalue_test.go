@@ -0,0 +1,37 @@
+//go:build go1.22
+// +build go1.22
+
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+func TestPathValue(t *testing.T) {
+	var id, p, normalized, original string
+	m := mux.New(
+		mux.HandleFunc(http.MethodGet, "/users/{id int}/{uint}/files/{p path}", func(w http.ResponseWriter, r *http.Request) {
+			id = r.PathValue("id")
+			p = r.PathValue("p")
+			normalized = mux.WithParam(r, "id", "me").PathValue("id")
+			original = r.PathValue("id")
+		}),
+	)
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/01/2/files/a/b%20c", nil))
+	if id != "01" {
+		t.Errorf("Unexpected path value for id: want=%q, got=%q", "01", id)
+	}
+	if p != "a/b c" {
+		t.Errorf("Unexpected path value for p: want=%q, got=%q", "a/b c", p)
+	}
+	if normalized != "me" {
+		t.Errorf("Unexpected path value after WithParam: want=%q, got=%q", "me", normalized)
+	}
+	if original != "01" {
+		t.Errorf("WithParam modified the path value of the original request: want=%q, got=%q", "01", original)
+	}
+}
@@ -0,0 +1,80 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrUnauthenticated can be returned, or wrapped, by a guard set using
+// Protect to reject a request because the client has not authenticated, as
+// opposed to being authenticated but not allowed to access the route.
+// By default such requests receive 401 (Unauthorized) instead of 403
+// (Forbidden).
+var ErrUnauthenticated = errors.New("mux: unauthenticated")
+
+// Protect adds a guard that must allow a request before it is passed to the
+// handler of the route, for example to check that the user may access the
+// organization identified by a route parameter.
+// The guard is called after the route has been matched with the request that
+// would be passed to the handler, so the route parameters can be retrieved
+// using Param.
+// If it returns a non-nil error, the handler is not called and the request is
+// passed to the handler returned by the function configured using Forbidden.
+//
+// If Protect is used several times on the same route, the guards are called in
+// the order they were given and the first error stops the request.
+// To share guards between a group of routes, put them first in a slice of
+// options that is passed to each route so that they run before the guards of
+// the individual routes.
+func Protect(guard func(r *http.Request) error) RouteOption {
+	return func(ep *endpoint) {
+		ep.guards = append(ep.guards[:len(ep.guards):len(ep.guards)], guard)
+	}
+}
+
+// Forbidden sets the function that returns the handler for requests that were
+// rejected by a guard set using Protect, given the error that the guard
+// returned.
+//
+// If the returned handler does not set the status code, it is set to 401
+// (Unauthorized) if the error is or wraps ErrUnauthenticated, and to 403
+// (Forbidden) otherwise.
+// By default, or if f is nil, a response with that status code in a format
+// negotiated using the Accept header is written as described in the
+// documentation for ErrorRenderer.
+// The error message is never written to the client by the default handler.
+func Forbidden(f func(err error) http.Handler) Option {
+	return func(mux *ServeMux) {
+		mux.forbidden = f
+	}
+}
+
+// protected returns a handler that calls h if every guard allows the request
+// and the Forbidden handler otherwise.
+func (mux *ServeMux) protected(guards []func(*http.Request) error, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, guard := range guards {
+			err := guard(r)
+			if err == nil {
+				continue
+			}
+			code := http.StatusForbidden
+			if errors.Is(err, ErrUnauthenticated) {
+				code = http.StatusUnauthorized
+			}
+			if mux.forbidden == nil {
+				mux.renderError(w, r, code, nil)
+				return
+			}
+			dw := &defCodeWriter{ResponseWriter: w, code: code}
+			mux.forbidden(err).ServeHTTP(dw, r)
+			// A handler that writes nothing must not let the request through as a
+			// 200.
+			if !dw.wrote {
+				dw.WriteHeader(code)
+			}
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,94 @@
+package mux_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var errNotMember = errors.New("not a member of the organization")
+
+var protectTests = [...]struct {
+	path  string
+	user  string
+	code  int
+	calls string
+}{
+	0: {path: "/orgs/1/repos", user: "1", code: testCode, calls: "auth,member"},
+	1: {path: "/orgs/2/repos", user: "1", code: http.StatusForbidden, calls: "auth,member"},
+	2: {path: "/orgs/1/repos", code: http.StatusUnauthorized, calls: "auth"},
+	3: {path: "/admin", user: "1", code: testStatusCode, calls: "auth,admin"},
+	4: {path: "/public", code: testCode},
+}
+
+func TestProtect(t *testing.T) {
+	var calls []string
+	authenticated := mux.Protect(func(r *http.Request) error {
+		calls = append(calls, "auth")
+		if r.Header.Get("User") == "" {
+			return fmt.Errorf("%w: no session", mux.ErrUnauthenticated)
+		}
+		return nil
+	})
+	group := []mux.RouteOption{authenticated}
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/orgs/{org uint}/repos", codeHandler(t, testCode), append(group, mux.Protect(func(r *http.Request) error {
+			calls = append(calls, "member")
+			if mux.Param(r, "org").Raw != r.Header.Get("User") {
+				return errNotMember
+			}
+			return nil
+		}))...),
+		mux.Handle(http.MethodGet, "/admin", failHandler(t), append(group, mux.Protect(func(r *http.Request) error {
+			calls = append(calls, "admin")
+			return errors.New("not an admin")
+		}))...),
+		mux.Handle(http.MethodGet, "/public", codeHandler(t, testCode)),
+		mux.Forbidden(func(err error) http.Handler {
+			if errors.Is(err, errNotMember) || errors.Is(err, mux.ErrUnauthenticated) {
+				// Handlers that do not write a response get the default status.
+				return http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+			}
+			return codeHandler(t, testStatusCode)
+		}),
+	)
+	for i, tc := range protectTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			calls = nil
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.user != "" {
+				req.Header.Set("User", tc.user)
+			}
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, req)
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+			if got := strings.Join(calls, ","); got != tc.calls {
+				t.Errorf("Unexpected guards called: want=%q, got=%q", tc.calls, got)
+			}
+		})
+	}
+}
+
+func TestProtectDefault(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/secret", failHandler(t), mux.Protect(func(*http.Request) error {
+			return errors.New("secret reason")
+		})),
+	)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/secret", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Unexpected status code: want=%d, got=%d", http.StatusForbidden, rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "secret reason") {
+		t.Errorf("Expected the error not to be written to the client, got %q", rec.Body.String())
+	}
+}
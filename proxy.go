@@ -0,0 +1,82 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// proxyMethods are the methods that Proxy registers handlers for.
+var proxyMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// Proxy registers a reverse proxy to upstream for every method other than
+// CONNECT and TRACE.
+// The pattern must end in a named parameter of type path, the escaped value of
+// which is appended to the path of upstream to form the path of the outbound
+// request (for example a request for /services/auth/login matched by the
+// pattern /services/auth/{p path} with the upstream http://auth.internal/v1 is
+// sent to http://auth.internal/v1/login).
+// The query of the request is appended to any query of upstream.
+//
+// The Host header of the outbound request is set to the host of upstream, the
+// original host and scheme are sent in the X-Forwarded-Host and
+// X-Forwarded-Proto headers, and the client address is appended to the
+// X-Forwarded-For header.
+// If the upstream server cannot be reached, the error is passed to the
+// handler configured using ErrorHandler as a *StatusError with the code 502
+// (Bad Gateway).
+func Proxy(pattern string, upstream *url.URL, opts ...RouteOption) Option {
+	_, name := wildPattern(pattern)
+	base := strings.TrimSuffix(upstream.EscapedPath(), "/")
+
+	return func(mux *ServeMux) {
+		proxy := &httputil.ReverseProxy{
+			Director: func(r *http.Request) {
+				proto := "http"
+				if mux.isTLS(r) {
+					proto = "https"
+				}
+				r.Header.Set("X-Forwarded-Host", r.Host)
+				r.Header.Set("X-Forwarded-Proto", proto)
+
+				rawPath := base + "/" + Param(r, name).Encoded
+				path, err := url.PathUnescape(rawPath)
+				if err != nil {
+					path = rawPath
+				}
+				r.URL.Scheme = upstream.Scheme
+				r.URL.Host = upstream.Host
+				r.URL.Path = path
+				r.URL.RawPath = ""
+				if path != rawPath {
+					r.URL.RawPath = rawPath
+				}
+				switch {
+				case upstream.RawQuery == "":
+				case r.URL.RawQuery == "":
+					r.URL.RawQuery = upstream.RawQuery
+				default:
+					r.URL.RawQuery = upstream.RawQuery + "&" + r.URL.RawQuery
+				}
+				r.Host = upstream.Host
+				// Prevent the transport from adding a default User-Agent.
+				if _, ok := r.Header["User-Agent"]; !ok {
+					r.Header.Set("User-Agent", "")
+				}
+			},
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				mux.errorHandler(w, r, &StatusError{Code: http.StatusBadGateway, Err: err})
+			},
+		}
+		HandleMethods(proxyMethods, pattern, proxy, opts...)(mux)
+	}
+}
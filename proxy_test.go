@@ -0,0 +1,84 @@
+package mux_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var proxyTests = [...]struct {
+	method string
+	target string
+	want   string
+}{
+	0: {method: http.MethodGet, target: "/services/auth/login", want: "GET /v1/login key=abc example.com http"},
+	1: {method: http.MethodPost, target: "/services/auth/a/b%2Fc%20d?x=1", want: "POST /v1/a/b%2Fc%20d key=abc&x=1 example.com http"},
+	2: {method: http.MethodDelete, target: "/services/auth/users/", want: "DELETE /v1/users/ key=abc example.com http"},
+}
+
+func TestProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s %s %s %s", r.Method, r.URL.EscapedPath(), r.URL.RawQuery, r.Header.Get("X-Forwarded-Host"), r.Header.Get("X-Forwarded-Proto"))
+	}))
+	defer upstream.Close()
+	u, err := url.Parse(upstream.URL + "/v1/?key=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := mux.New(mux.Proxy("/services/auth/{p path}", u))
+
+	for i, tc := range proxyTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Unexpected code: want=%d, got=%d", http.StatusOK, rec.Code)
+			}
+			if body := rec.Body.String(); body != tc.want {
+				t.Errorf("Unexpected upstream request: want=%q, got=%q", tc.want, body)
+			}
+		})
+	}
+}
+
+func TestProxyError(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	u, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream.Close()
+
+	var got error
+	m := mux.New(
+		mux.Proxy("/api/{p path}", u),
+		mux.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+			got = err
+			w.WriteHeader(testStatusCode)
+		}),
+	)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	if rec.Code != testStatusCode {
+		t.Errorf("Unexpected code: want=%d, got=%d", testStatusCode, rec.Code)
+	}
+	var statusErr *mux.StatusError
+	if !errors.As(got, &statusErr) || statusErr.Code != http.StatusBadGateway {
+		t.Errorf("Expected a StatusError with code %d, got %v", http.StatusBadGateway, got)
+	}
+}
+
+func TestProxyPattern(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a pattern without a wildcard to panic")
+		}
+	}()
+	mux.Proxy("/api/{id uint}", &url.URL{Scheme: "http", Host: "example.net"})
+}
@@ -0,0 +1,161 @@
+package mux
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// minSweepInterval is the shortest time between sweeps of the buckets of a
+// rate limiter for buckets that have refilled.
+const minSweepInterval = time.Second
+
+// Limit limits the rate of requests to a route using a token bucket for each
+// key returned by key: each bucket holds up to burst tokens, is refilled at
+// rate tokens per second, and each request takes one token.
+// If key is nil, requests are keyed by the client IP address from RemoteAddr.
+// The buckets are shared by every method that the route is registered for.
+//
+// Requests that find their bucket empty are not passed to the handler of the
+// route.
+// Instead the Retry-After header is set to the number of seconds until a
+// token will be available and the RateLimited handler is called.
+// Tokens are only taken when the handler of the route would be called, so
+// resolving a request using Lookup or Match does not count towards the limit.
+//
+// Buckets that have refilled completely are indistinguishable from new ones
+// and are periodically removed so that the memory used is proportional to the
+// number of keys that have been seen recently.
+// If rate or burst is not positive, Limit panics.
+func Limit(rate float64, burst int, key func(r *http.Request) string) RouteOption {
+	if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		panic("mux: rate limit must be positive and finite")
+	}
+	if burst <= 0 {
+		panic("mux: rate limit burst must be positive")
+	}
+	if key == nil {
+		key = clientIP
+	}
+	l := &limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		key:     key,
+		buckets: make(map[string]*bucket),
+	}
+	return func(ep *endpoint) {
+		ep.limiter = l
+	}
+}
+
+// RateLimited sets the handler to call when a request is rejected by the rate
+// limit of a route set using Limit.
+// The Retry-After header is set before h is called.
+//
+// If the provided handler does not set the status code, it is set to 429 (Too
+// Many Requests) by default instead of 200.
+// By default, or if h is nil, a response in a format negotiated using the
+// Accept header is written as described in the documentation for
+// ErrorRenderer.
+func RateLimited(h http.Handler) Option {
+	return func(mux *ServeMux) {
+		mux.rateLimited = h
+	}
+}
+
+// clientIP returns the IP address of the client that sent r.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// limiter is a set of token buckets, one for each key.
+type limiter struct {
+	rate  float64
+	burst float64
+	key   func(*http.Request) string
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// bucket is the state of a single token bucket as of last.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take takes a token from the bucket for key at time now.
+// If the bucket is empty, it reports false along with how long it will be
+// until a token is available.
+func (l *limiter) take(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed.Seconds()*l.rate)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep removes the buckets that have refilled completely by now.
+// To keep the cost of each request low, buckets are only swept once per
+// refill period, or once per minSweepInterval if that is longer.
+// The lock must be held.
+func (l *limiter) sweep(now time.Time) {
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	interval := refill
+	if interval < minSweepInterval {
+		interval = minSweepInterval
+	}
+	if now.Sub(l.lastSweep) < interval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// limited returns a handler that calls h if the rate limit l allows the
+// request and the RateLimited handler otherwise.
+func (mux *ServeMux) limited(l *limiter, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := l.take(l.key(r), time.Now())
+		if ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(wait.Seconds())), 10))
+		if mux.rateLimited == nil {
+			mux.renderError(w, r, http.StatusTooManyRequests, nil)
+			return
+		}
+		dw := &defCodeWriter{ResponseWriter: w, code: http.StatusTooManyRequests}
+		mux.rateLimited.ServeHTTP(dw, r)
+		// A handler that writes nothing must not turn the rejection into a 200.
+		if !dw.wrote {
+			dw.WriteHeader(http.StatusTooManyRequests)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"code.soquee.net/mux"
+)
+
+var limitTests = [...]struct {
+	path       string
+	remoteAddr string
+	code       int
+	retryAfter string
+}{
+	0: {path: "/login", remoteAddr: "192.0.2.1:1234", code: testCode},
+	1: {path: "/login", remoteAddr: "192.0.2.1:5678", code: testCode},
+	2: {path: "/login", remoteAddr: "192.0.2.1:1234", code: http.StatusTooManyRequests, retryAfter: "100"},
+	3: {path: "/login", remoteAddr: "192.0.2.2:1234", code: testCode},
+	4: {path: "/reset", remoteAddr: "192.0.2.1:1234", code: testCode},
+	5: {path: "/reset", remoteAddr: "192.0.2.2:1234", code: testStatusCode, retryAfter: "100"},
+	6: {path: "/other", remoteAddr: "192.0.2.1:1234", code: testCode},
+	7: {path: "/other", remoteAddr: "192.0.2.1:1234", code: testCode},
+}
+
+func TestLimit(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodPost, "/login", codeHandler(t, testCode), mux.Limit(0.01, 2, nil)),
+		mux.Handle(http.MethodPost, "/reset", codeHandler(t, testCode), mux.Limit(0.01, 1, func(r *http.Request) string {
+			return "global"
+		})),
+		mux.Handle(http.MethodPost, "/other", codeHandler(t, testCode)),
+		mux.RateLimited(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/reset" {
+				w.WriteHeader(testStatusCode)
+			}
+		})),
+	)
+	for i, tc := range limitTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			req.RemoteAddr = tc.remoteAddr
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, req)
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+			if retry := rec.Header().Get("Retry-After"); retry != tc.retryAfter {
+				t.Errorf("Unexpected Retry-After header: want=%q, got=%q", tc.retryAfter, retry)
+			}
+		})
+	}
+
+	// Looking up a route does not take a token.
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.RemoteAddr = "192.0.2.3:1234"
+	for i := 0; i < 3; i++ {
+		m.Lookup(req)
+	}
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	if rec.Code != testCode {
+		t.Errorf("Expected lookups not to count towards the limit, got status %d", rec.Code)
+	}
+}
+
+func TestLimitRefillAndEviction(t *testing.T) {
+	l := mux.NewLimiter(1, 2)
+	now := time.Unix(0, 0)
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Take("a", now); !ok {
+			t.Fatalf("Expected token %d to be available", i)
+		}
+	}
+	ok, wait := l.Take("a", now)
+	if ok || wait != time.Second {
+		t.Fatalf("Expected empty bucket with a 1s wait, got ok=%t wait=%v", ok, wait)
+	}
+	if ok, _ := l.Take("a", now.Add(time.Second)); !ok {
+		t.Fatalf("Expected bucket to refill")
+	}
+	l.Take("b", now.Add(time.Second))
+	if n := l.Len(); n != 2 {
+		t.Fatalf("Unexpected number of buckets: want=2, got=%d", n)
+	}
+
+	// Once both buckets have refilled they are removed by the next request.
+	l.Take("c", now.Add(10*time.Second))
+	if n := l.Len(); n != 1 {
+		t.Errorf("Expected refilled buckets to be evicted, got %d buckets", n)
+	}
+}
+
+func TestLimitConcurrent(t *testing.T) {
+	const (
+		burst   = 50
+		keys    = 4
+		workers = 32
+		reqs    = 100
+	)
+	var served int64
+	m := mux.New(
+		mux.HandleFunc(http.MethodGet, "/limited", func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&served, 1)
+		}, mux.Limit(0.001, burst, func(r *http.Request) string {
+			return r.Header.Get("Key")
+		})),
+	)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < reqs; j++ {
+				req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+				req.Header.Set("Key", strconv.Itoa((i+j)%keys))
+				rec := httptest.NewRecorder()
+				m.ServeHTTP(rec, req)
+				if rec.Code != http.StatusOK && rec.Code != http.StatusTooManyRequests {
+					t.Errorf("Unexpected status code: %d", rec.Code)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if served != burst*keys {
+		t.Errorf("Unexpected number of requests served: want=%d, got=%d", burst*keys, served)
+	}
+}
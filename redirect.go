@@ -0,0 +1,68 @@
+package mux
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redirect registers a handler for oldPattern that redirects requests to the
+// path created by substituting the route parameters matched by oldPattern into
+// newPattern.
+// Parameters are matched up by name, and the query string of the request is
+// preserved.
+//
+// If newPattern contains an unnamed parameter or a parameter that is not
+// captured by oldPattern, or if either pattern is invalid, Redirect panics.
+func Redirect(method, oldPattern, newPattern string, code int) Option {
+	if rr := cleanPath(newPattern); rr != newPattern {
+		panic(&PatternError{Pattern: newPattern, Reason: uncleanReason})
+	}
+
+	captured := make(map[string]bool)
+	for part, remain := nextPart(strings.TrimPrefix(oldPattern, "/")); part != ""; part, remain = nextPart(remain) {
+		name, typ := parseParam(part)
+		if typ != typStatic && name != "" {
+			captured[name] = true
+		}
+	}
+
+	var segments []node
+	for part, remain := nextPart(newPattern[1:]); part != ""; part, remain = nextPart(remain) {
+		name, typ := parseParam(part)
+		switch {
+		case typ == typWild && remain != "":
+			panic(&PatternError{Pattern: newPattern, Reason: "wildcards must be the last component in a route"})
+		case typ != typStatic && name == "":
+			panic(&PatternError{Pattern: newPattern, Reason: "redirect target may not contain unnamed parameters"})
+		case typ != typStatic && !captured[name]:
+			panic(&PatternError{Pattern: newPattern, Reason: fmt.Sprintf("redirect target uses parameter %q which is not captured by %q", name, oldPattern)})
+		}
+		segments = append(segments, node{name: name, typ: typ})
+	}
+	trailingSlash := len(newPattern) > 1 && strings.HasSuffix(newPattern, "/")
+
+	return Handle(method, oldPattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var loc strings.Builder
+		for _, seg := range segments {
+			loc.WriteByte('/')
+			switch seg.typ {
+			case typStatic:
+				loc.WriteString(escapeComponent(seg.name, false))
+			case typWild:
+				loc.WriteString(escapeComponent(Param(r, seg.name).Raw, false))
+			default:
+				loc.WriteString(url.PathEscape(Param(r, seg.name).Raw))
+			}
+		}
+		if loc.Len() == 0 || trailingSlash {
+			loc.WriteByte('/')
+		}
+		if r.URL.RawQuery != "" {
+			loc.WriteByte('?')
+			loc.WriteString(r.URL.RawQuery)
+		}
+		http.Redirect(w, r, loc.String(), code)
+	}))
+}
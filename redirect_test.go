@@ -0,0 +1,72 @@
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var redirectTests = [...]struct {
+	oldPattern string
+	newPattern string
+	path       string
+	location   string
+	panics     bool
+}{
+	0: {
+		oldPattern: "/users/{id uint}/profile",
+		newPattern: "/people/{id uint}",
+		path:       "/users/42/profile?tab=about",
+		location:   "/people/42?tab=about",
+	},
+	1: {
+		oldPattern: "/docs/{p path}",
+		newPattern: "/documentation/{p path}",
+		path:       "/docs/a/b%20c.html",
+		location:   "/documentation/a/b%20c.html",
+	},
+	2: {
+		oldPattern: "/{a string}/{b string}/",
+		newPattern: "/x/{b string}/{a string}/",
+		path:       "/1/2/",
+		location:   "/x/2/1/",
+	},
+	3: {
+		oldPattern: "/old",
+		newPattern: "/",
+		path:       "/old",
+		location:   "/",
+	},
+	4: {oldPattern: "/users/{id uint}", newPattern: "/people/{uid uint}", panics: true},
+	5: {oldPattern: "/users/{uint}", newPattern: "/people/{uint}", panics: true},
+	6: {oldPattern: "/users/{id uint}", newPattern: "people/{id uint}", panics: true},
+	7: {oldPattern: "/users/{p path}", newPattern: "/people/{p path}/x", panics: true},
+}
+
+func TestRedirect(t *testing.T) {
+	for i, tc := range redirectTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			defer func() {
+				r := recover()
+				switch {
+				case tc.panics && r == nil:
+					t.Errorf("Expected redirect to panic")
+				case !tc.panics && r != nil:
+					t.Errorf("Did not expect panic, got=%q", r)
+				}
+			}()
+			m := mux.New(mux.Redirect(http.MethodGet, tc.oldPattern, tc.newPattern, http.StatusMovedPermanently))
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rec.Code != http.StatusMovedPermanently {
+				t.Errorf("Unexpected status code: want=%d, got=%d", http.StatusMovedPermanently, rec.Code)
+			}
+			if loc := rec.Header().Get("Location"); loc != tc.location {
+				t.Errorf("Unexpected location: want=%q, got=%q", tc.location, loc)
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package mux_test
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"code.soquee.net/mux"
@@ -57,20 +61,20 @@ var registerTests = [...]struct {
 	}},
 	5: {
 		routes: func(t *testing.T) []mux.Option {
-			return []mux.Option{mux.Handle("GET", "/{int}", codeHandler(t, 5))}
+			return []mux.Option{mux.Handle("GET", "/{int}", codeHandler(t, 205))}
 		},
 		expect: []expected{
-			{path: "/1", code: 5},
-			{path: "/-1", code: 5},
+			{path: "/1", code: 205},
+			{path: "/-1", code: 205},
 			{path: "/nope", code: 404},
 		},
 	},
 	6: {
 		routes: func(t *testing.T) []mux.Option {
-			return []mux.Option{mux.Handle("GET", "/{u uint}", codeHandler(t, 5))}
+			return []mux.Option{mux.Handle("GET", "/{u uint}", codeHandler(t, 205))}
 		},
 		expect: []expected{
-			{path: "/1", code: 5},
+			{path: "/1", code: 205},
 			{path: "/-1", code: 404},
 			{path: "/nope", code: 404},
 		},
@@ -108,40 +112,40 @@ var registerTests = [...]struct {
 	14: {
 		routes: func(t *testing.T) []mux.Option {
 			return []mux.Option{
-				mux.Handle("GET", "/good/one", codeHandler(t, 1)),
-				mux.Handle("GET", "/good/two", codeHandler(t, 2)),
+				mux.Handle("GET", "/good/one", codeHandler(t, 201)),
+				mux.Handle("GET", "/good/two", codeHandler(t, 202)),
 				mux.MethodNotAllowed(nil),
 			}
 		},
 		expect: []expected{
 			{path: "/good", code: 404},
-			{path: "/good/one", code: 1},
-			{path: "/good/two", code: 2},
+			{path: "/good/one", code: 201},
+			{path: "/good/two", code: 202},
 		},
 	},
 	15: {
 		routes: func(t *testing.T) []mux.Option {
 			return []mux.Option{
-				mux.Handle("GET", "/a/b", codeHandler(t, 1)),
-				mux.Handle("GET", "/a", codeHandler(t, 2)),
+				mux.Handle("GET", "/a/b", codeHandler(t, 201)),
+				mux.Handle("GET", "/a", codeHandler(t, 202)),
 			}
 		},
 		expect: []expected{
-			{path: "/a", code: 2},
-			{path: "/a/b", code: 1},
+			{path: "/a", code: 202},
+			{path: "/a/b", code: 201},
 			{path: "/a/c", code: 404},
 		},
 	},
 	16: {
 		routes: func(t *testing.T) []mux.Option {
 			return []mux.Option{
-				mux.Handle("GET", "/a", codeHandler(t, 2)),
-				mux.Handle("GET", "/a/b", codeHandler(t, 1)),
+				mux.Handle("GET", "/a", codeHandler(t, 202)),
+				mux.Handle("GET", "/a/b", codeHandler(t, 201)),
 			}
 		},
 		expect: []expected{
-			{path: "/a", code: 2},
-			{path: "/a/b", code: 1},
+			{path: "/a", code: 202},
+			{path: "/a/b", code: 201},
 			{path: "/a/c", code: 404},
 		},
 	},
@@ -159,11 +163,11 @@ var registerTests = [...]struct {
 	19: {
 		routes: func(t *testing.T) []mux.Option {
 			return []mux.Option{
-				mux.Handle("GET", "/", codeHandler(t, 2)),
+				mux.Handle("GET", "/", codeHandler(t, 202)),
 			}
 		},
 		expect: []expected{
-			{path: "/", code: 2},
+			{path: "/", code: 202},
 		},
 	},
 	20: {panics: true, routes: func(t *testing.T) []mux.Option {
@@ -198,6 +202,104 @@ var registerTests = [...]struct {
 			mux.Handle("GET", "test", failHandler(t)),
 		}
 	}},
+	26: {panics: true, routes: func(t *testing.T) []mux.Option {
+		return []mux.Option{
+			mux.Handle("HEAD", "/user", failHandler(t)),
+			mux.HandleMethods([]string{"GET", "HEAD"}, "/user", failHandler(t)),
+		}
+	}},
+	27: {panics: true, routes: func(t *testing.T) []mux.Option {
+		return []mux.Option{
+			mux.HandleMethods([]string{"GET", "get"}, "/user", failHandler(t)),
+		}
+	}},
+	28: {panics: true, routes: func(t *testing.T) []mux.Option {
+		return []mux.Option{
+			mux.HandleMethods(nil, "/user", failHandler(t)),
+		}
+	}},
+	29: {
+		routes: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.HandleMethods([]string{"GET", "HEAD"}, "/user", codeHandler(t, 201)),
+				mux.Handle("POST", "/user", failHandler(t)),
+			}
+		},
+		expect: []expected{
+			{path: "/user", code: 201},
+		},
+	},
+	30: {panics: true, routes: func(t *testing.T) []mux.Option {
+		return []mux.Option{mux.Handle("GE T", "/user", failHandler(t))}
+	}},
+	31: {panics: true, routes: func(t *testing.T) []mux.Option {
+		return []mux.Option{mux.Handle("", "/user", failHandler(t))}
+	}},
+	32: {panics: true, routes: func(t *testing.T) []mux.Option {
+		return []mux.Option{mux.Handle("GET,HEAD", "/user", failHandler(t))}
+	}},
+	33: {routes: func(t *testing.T) []mux.Option {
+		return []mux.Option{mux.Handle("M-SEARCH", "/user", failHandler(t))}
+	}},
+	34: {
+		routes: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.Handle("GET", "/about", codeHandler(t, 201)),
+				mux.Handle("GET", "/about/", codeHandler(t, 202)),
+				mux.Handle("GET", "/users/{id int}/", codeHandler(t, 203)),
+				mux.Handle("GET", "/files/{p path}", codeHandler(t, 204)),
+			}
+		},
+		expect: []expected{
+			{path: "/about", code: 201},
+			{path: "/about/", code: 202},
+			{path: "/users/1/", code: 203},
+			{path: "/users/1", code: 405},
+			{path: "/files/a/", code: 204},
+		},
+	},
+	35: {panics: true, routes: func(t *testing.T) []mux.Option {
+		return []mux.Option{
+			mux.Handle("GET", "/about/", failHandler(t)),
+			mux.Handle("GET", "/about/", failHandler(t)),
+		}
+	}},
+	36: {
+		routes: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.Handle("GET", "/{$}", codeHandler(t, 201)),
+				mux.Handle("GET", "/docs/{$}", codeHandler(t, 202)),
+				mux.Handle("GET", "/docs/{id int}", codeHandler(t, 203)),
+				mux.Options(nil),
+			}
+		},
+		expect: []expected{
+			{path: "/", code: 201},
+			{path: "/docs/", code: 202},
+			{path: "/docs/1", code: 203},
+			{path: "/docs/x", code: 404},
+			{path: "/docs", code: 404},
+		},
+	},
+	37: {panics: true, routes: func(t *testing.T) []mux.Option {
+		return []mux.Option{
+			mux.Handle("GET", "/docs/{$}", failHandler(t)),
+			mux.Handle("GET", "/docs/", failHandler(t)),
+		}
+	}},
+	38: {
+		routes: func(t *testing.T) []mux.Option {
+			return []mux.Option{
+				mux.Handle("GET", "/files/{p path}", codeHandler(t, 201)),
+				mux.Handle("POST", "/files/{p ...}", failHandler(t)),
+				mux.Handle("GET", "/static/{...}", codeHandler(t, 202)),
+			}
+		},
+		expect: []expected{
+			{path: "/files/a/b", code: 201},
+			{path: "/static/css/main.css", code: 202},
+		},
+	},
 }
 
 func TestRegisterRoutes(t *testing.T) {
@@ -225,3 +327,657 @@ func TestRegisterRoutes(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterAfterNew(t *testing.T) {
+	m := mux.New(mux.Handle("GET", "/a", codeHandler(t, 201)))
+
+	serve := func(method, path string) int {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec.Code
+	}
+
+	if code := serve("GET", "/b/c"); code != 404 {
+		t.Fatalf("Unexpected code before registration: want=404, got=%d", code)
+	}
+	m.Handle("GET", "/b/c", codeHandler(t, 202))
+	m.HandleFunc("POST", "/a", codeHandler(t, 203))
+	for _, tc := range []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{method: "GET", path: "/a", code: 201},
+		{method: "POST", path: "/a", code: 203},
+		{method: "GET", path: "/b/c", code: 202},
+	} {
+		if code := serve(tc.method, tc.path); code != tc.code {
+			t.Errorf("Unexpected code for %s %s: want=%d, got=%d", tc.method, tc.path, tc.code, code)
+		}
+	}
+
+	// A registration that panics must not leave any part of the route behind.
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("Expected conflicting registration to panic")
+			}
+		}()
+		mux.HandleMethods([]string{"GET", "GET"}, "/d/e", failHandler(t))(m)
+	}()
+	if code := serve("OPTIONS", "/d"); code != 404 {
+		t.Errorf("Expected partial registration to be discarded: want=404, got=%d", code)
+	}
+}
+
+func TestRegisterSibling(t *testing.T) {
+	m := mux.New(mux.Handle("GET", "/a/b", codeHandler(t, 201)))
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	if rec := serve("GET", "/a/b"); rec.Code != 201 {
+		t.Fatalf("Unexpected code before registration: want=201, got=%d", rec.Code)
+	}
+	m.Handle("GET", "/a/c", codeHandler(t, 202))
+	m.Handle("POST", "/a/b", codeHandler(t, 203))
+	for _, tc := range []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{method: "GET", path: "/a/b", code: 201},
+		{method: "POST", path: "/a/b", code: 203},
+		{method: "GET", path: "/a/c", code: 202},
+	} {
+		if rec := serve(tc.method, tc.path); rec.Code != tc.code {
+			t.Errorf("Unexpected code for %s %s: want=%d, got=%d", tc.method, tc.path, tc.code, rec.Code)
+		}
+	}
+	if allow := serve("OPTIONS", "/a/b").Header().Get("Allow"); allow != "GET,OPTIONS,POST" {
+		t.Errorf("Unexpected Allow header after registration: want=%q, got=%q", "GET,OPTIONS,POST", allow)
+	}
+}
+
+func TestRegisterConcurrent(t *testing.T) {
+	m := mux.New()
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/1", nil))
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		m.Handle("GET", "/"+strconv.Itoa(i)+"/{id int}", codeHandler(t, 200))
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestRemove(t *testing.T) {
+	m := mux.New(
+		mux.Handle("GET", "/", codeHandler(t, 201)),
+		mux.Handle("GET", "/a", codeHandler(t, 202)),
+		mux.Handle("GET", "/a/", codeHandler(t, 204)),
+		mux.HandleMethods([]string{"GET", "POST"}, "/a/{id int}/b", codeHandler(t, 203)),
+		mux.Handle("GET", "/r{.f}", codeHandler(t, 205)),
+		mux.Handle("GET", "/c/", codeHandler(t, 206)),
+		mux.Handle("GET", "/d/{$}", codeHandler(t, 207)),
+		mux.Subtree("GET", "/s/", codeHandler(t, 208)),
+		mux.Subtree("GET", "/images/", codeHandler(t, 209)),
+		mux.Handle("GET", "/images", codeHandler(t, 210)),
+	)
+
+	serve := func(method, path string) int {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec.Code
+	}
+
+	for i, tc := range []struct {
+		method  string
+		pattern string
+		removed bool
+		reqs    []expected
+	}{
+		0: {method: "GET", pattern: "/a/{id int}/b", removed: true, reqs: []expected{
+			{path: "GET /a/1/b", code: 405},
+			{path: "POST /a/1/b", code: 203},
+		}},
+		1: {method: "GET", pattern: "/a/{id int}/b"},
+		2: {method: "POST", pattern: "/a/{id uint}/b"},
+		3: {method: "post", pattern: "/a/{id int}/b", removed: true, reqs: []expected{
+			{path: "OPTIONS /a/1/b", code: 404},
+			{path: "OPTIONS /a/1", code: 404},
+			{path: "GET /a", code: 202},
+		}},
+		4: {method: "GET", pattern: "/a/", removed: true, reqs: []expected{
+			{path: "GET /a/", code: 404},
+			{path: "GET /a", code: 202},
+		}},
+		5: {method: "GET", pattern: "/a/"},
+		6: {method: "GET", pattern: "/a", removed: true, reqs: []expected{
+			{path: "OPTIONS /a", code: 404},
+			{path: "GET /", code: 201},
+		}},
+		7: {method: "GET", pattern: "/", removed: true, reqs: []expected{
+			{path: "GET /", code: 405},
+		}},
+		8: {method: "GET", pattern: "a"},
+		9: {method: "GET", pattern: "/r", reqs: []expected{
+			{path: "GET /r.json", code: 205},
+		}},
+		10: {method: "GET", pattern: "/r{.f}", removed: true, reqs: []expected{
+			{path: "GET /r.json", code: 404},
+		}},
+		11: {method: "GET", pattern: "/c/{$}", reqs: []expected{
+			{path: "GET /c/", code: 206},
+		}},
+		12: {method: "GET", pattern: "/d/", reqs: []expected{
+			{path: "GET /d/", code: 207},
+		}},
+		13: {method: "GET", pattern: "/d/{$}", removed: true, reqs: []expected{
+			{path: "GET /d/", code: 404},
+		}},
+		14: {method: "GET", pattern: "/s/", reqs: []expected{
+			{path: "GET /s/x", code: 208},
+		}},
+		15: {method: "GET", pattern: "/a/{id bool}/b"},
+		16: {method: "GET", pattern: "/{p path}/b"},
+		17: {method: "GET", pattern: "/images", removed: true, reqs: []expected{
+			{path: "GET /images/x.png", code: 209},
+		}},
+	} {
+		if removed := m.Remove(tc.method, tc.pattern); removed != tc.removed {
+			t.Errorf("%d: unexpected result removing %s %s: want=%t, got=%t", i, tc.method, tc.pattern, tc.removed, removed)
+		}
+		for _, req := range tc.reqs {
+			parts := strings.SplitN(req.path, " ", 2)
+			if code := serve(parts[0], parts[1]); code != req.code {
+				t.Errorf("%d: unexpected code for %s: want=%d, got=%d", i, req.path, req.code, code)
+			}
+		}
+	}
+}
+
+func TestOverride(t *testing.T) {
+	m := mux.New(
+		mux.Handle("GET", "/pay/{id uint}", failHandler(t)),
+		mux.Handle("POST", "/pay/{id uint}", codeHandler(t, 202)),
+		mux.Override("get", "/pay/{id uint}", codeHandler(t, 201)),
+		mux.Handle("GET", "/docs/", failHandler(t)),
+		mux.Subtree("GET", "/static/", failHandler(t)),
+	)
+	for _, tc := range []struct {
+		method string
+		code   int
+	}{
+		{method: "GET", code: 201},
+		{method: "POST", code: 202},
+	} {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(tc.method, "/pay/1", nil))
+		if rec.Code != tc.code {
+			t.Errorf("Unexpected code for %s: want=%d, got=%d", tc.method, tc.code, rec.Code)
+		}
+	}
+
+	for _, tc := range []struct {
+		method  string
+		pattern string
+	}{
+		{method: "PUT", pattern: "/pay/{id uint}"},
+		{method: "GET", pattern: "/pay/{id int}"},
+		{method: "GET", pattern: "/pay"},
+		{method: "GET", pattern: "/pya/{id uint}"},
+		{method: "GET", pattern: "/docs/{$}"},
+		{method: "GET", pattern: "/static/"},
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("Expected override of %s %s to panic", tc.method, tc.pattern)
+				}
+			}()
+			mux.Override(tc.method, tc.pattern, failHandler(t))(m)
+		}()
+	}
+}
+
+func TestVerbs(t *testing.T) {
+	m := mux.New(
+		mux.Get("/r", codeHandler(t, 201)),
+		mux.Post("/r", codeHandler(t, 202)),
+		mux.Put("/r", codeHandler(t, 203)),
+		mux.Patch("/r", codeHandler(t, 204)),
+		mux.Delete("/r", codeHandler(t, 205)),
+		mux.GetFunc("/f", codeHandler(t, 211)),
+		mux.PostFunc("/f", codeHandler(t, 212)),
+		mux.PutFunc("/f", codeHandler(t, 213)),
+		mux.PatchFunc("/f", codeHandler(t, 214)),
+		mux.DeleteFunc("/f", codeHandler(t, 215)),
+	)
+	for i, method := range []string{"GET", "POST", "PUT", "PATCH", "DELETE"} {
+		for j, path := range []string{"/r", "/f"} {
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+			if code := 201 + 10*j + i; rec.Code != code {
+				t.Errorf("Unexpected code for %s %s: want=%d, got=%d", method, path, code, rec.Code)
+			}
+		}
+	}
+}
+
+var registerErrorTests = [...]struct {
+	routes func()
+	want   error
+}{
+	0: {
+		routes: func() {
+			mux.New(
+				mux.Handle(http.MethodGet, "/user/{id int}/edit", http.NotFoundHandler()),
+				mux.Handle(http.MethodGet, "/user/{name string}", http.NotFoundHandler()),
+			)
+		},
+		want: &mux.ConflictError{New: "/user/{name string}", Existing: "/user/{id int}", Routes: []string{"GET /user/{id int}/edit"}},
+	},
+	1: {
+		routes: func() {
+			mux.New(
+				mux.Handle(http.MethodGet, "/user/{id int}", http.NotFoundHandler()),
+				mux.Handle(http.MethodGet, "/user/me", http.NotFoundHandler()),
+			)
+		},
+		want: &mux.ConflictError{New: "/user/me", Existing: "/user/{id int}", Routes: []string{"GET /user/{id int}"}},
+	},
+	2: {
+		routes: func() {
+			mux.New(
+				mux.Handle(http.MethodGet, "/user", http.NotFoundHandler()),
+				mux.Handle(http.MethodGet, "/user", http.NotFoundHandler()),
+			)
+		},
+		want: &mux.DuplicateError{Method: http.MethodGet, Pattern: "/user", Existing: "/user"},
+	},
+	3: {
+		routes: func() { mux.Handle(http.MethodGet, "/user//edit", http.NotFoundHandler()) },
+		want:   &mux.PatternError{Pattern: "/user//edit", Reason: "route is unclean, make sure it is rooted and remove any ., .., or //"},
+	},
+	4: {
+		routes: func() { mux.New(mux.Handle(http.MethodGet, "/{p path}/edit", http.NotFoundHandler())) },
+		want:   &mux.PatternError{Pattern: "/{p path}/edit", Reason: "wildcards must be the last component in a route"},
+	},
+	5: {
+		routes: func() { mux.New(mux.Handle(http.MethodGet, "/{id bool}", http.NotFoundHandler())) },
+		want:   &mux.PatternError{Pattern: "/{id bool}", Reason: `invalid type "bool"`},
+	},
+	6: {
+		routes: func() { mux.Handle("GET POST", "/", http.NotFoundHandler()) },
+		want:   &mux.MethodError{Method: "GET POST", Pattern: "/"},
+	},
+	7: {
+		routes: func() { mux.HandleMethods(nil, "/", http.NotFoundHandler()) },
+		want:   &mux.MethodError{Pattern: "/"},
+	},
+	8: {
+		routes: func() { mux.New(mux.Override(http.MethodGet, "/user", http.NotFoundHandler())) },
+		want:   &mux.RouteNotFoundError{Method: http.MethodGet, Pattern: "/user"},
+	},
+	9: {
+		routes: func() {
+			mux.New(
+				mux.Handle(http.MethodGet, "/docs/{$}", http.NotFoundHandler()),
+				mux.Handle(http.MethodGet, "/docs/{p path}", http.NotFoundHandler()),
+			)
+		},
+		want: &mux.ConflictError{New: "/docs/{p path}", Existing: "/docs/{$}", Routes: []string{"GET /docs/{$}"}},
+	},
+	10: {
+		routes: func() {
+			mux.New(
+				mux.Handle(http.MethodGet, "/{p path}", http.NotFoundHandler()),
+				mux.Handle(http.MethodGet, "/{$}", http.NotFoundHandler()),
+			)
+		},
+		want: &mux.ConflictError{New: "/{$}", Existing: "/{p path}", Routes: []string{"GET /{p path}"}},
+	},
+	11: {
+		routes: func() {
+			mux.New(
+				mux.Handle(http.MethodGet, "/files/{p path}", http.NotFoundHandler()),
+				mux.Handle(http.MethodGet, "/files/{p ...}", http.NotFoundHandler()),
+			)
+		},
+		want: &mux.DuplicateError{Method: http.MethodGet, Pattern: "/files/{p ...}", Existing: "/files/{p path}"},
+	},
+	12: {
+		routes: func() {
+			mux.New(
+				mux.Handle(http.MethodGet, "/report{.format}", http.NotFoundHandler()),
+				mux.Handle(http.MethodGet, "/report.json", http.NotFoundHandler()),
+			)
+		},
+		want: &mux.ConflictError{New: "/report.json", Existing: "/report{.format}", Routes: []string{"GET /report{.format}"}},
+	},
+	13: {
+		routes: func() {
+			mux.New(
+				mux.Handle(http.MethodGet, "/a/report.json", http.NotFoundHandler()),
+				mux.Handle(http.MethodGet, "/a/report{.format}", http.NotFoundHandler()),
+			)
+		},
+		want: &mux.ConflictError{New: "/a/report{.format}", Existing: "/a/report.json", Routes: []string{"GET /a/report.json"}},
+	},
+	14: {
+		routes: func() {
+			mux.New(
+				mux.Handle(http.MethodGet, "/report{.format}", http.NotFoundHandler()),
+				mux.Handle(http.MethodPost, "/report", http.NotFoundHandler()),
+			)
+		},
+		want: &mux.ConflictError{New: "/report", Existing: "/report{.format}", Routes: []string{"GET /report{.format}"}},
+	},
+	15: {
+		routes: func() {
+			mux.New(
+				mux.Handle(http.MethodGet, "/a", http.NotFoundHandler(), mux.Name("x")),
+				mux.Handle(http.MethodGet, "/b", http.NotFoundHandler(), mux.Name("y")),
+				mux.Override(http.MethodGet, "/b", http.NotFoundHandler(), mux.Name("x")),
+			)
+		},
+		want: &mux.NameError{Name: "x", Pattern: "/b", Existing: "/a"},
+	},
+	16: {
+		routes: func() { mux.Override("get x", "/a", http.NotFoundHandler()) },
+		want:   &mux.MethodError{Method: "get x", Pattern: "/a"},
+	},
+	17: {
+		routes: func() { mux.Override(http.MethodGet, "/{id bool}", http.NotFoundHandler()) },
+		want:   &mux.PatternError{Pattern: "/{id bool}", Reason: `invalid type "bool"`},
+	},
+}
+
+func TestRegisterErrors(t *testing.T) {
+	for i, tc := range registerErrorTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			defer func() {
+				r := recover()
+				err, ok := r.(error)
+				if !ok {
+					t.Fatalf("Expected panic with an error, got %#v", r)
+				}
+				if !reflect.DeepEqual(err, tc.want) {
+					t.Errorf("Unexpected error: want=%#v, got=%#v", tc.want, err)
+				}
+				if err.Error() == "" {
+					t.Errorf("Expected non-empty error message")
+				}
+			}()
+			tc.routes()
+		})
+	}
+}
+
+func TestRegisterErrorsAs(t *testing.T) {
+	defer func() {
+		err, _ := recover().(error)
+		var conflict *mux.ConflictError
+		if !errors.As(err, &conflict) {
+			t.Fatalf("Expected a ConflictError, got %v", err)
+		}
+	}()
+	mux.New(
+		mux.Handle(http.MethodGet, "/{a int}", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/{b int}", http.NotFoundHandler()),
+	)
+}
+
+var conflictMessageTests = [...]struct {
+	routes []mux.Option
+	want   []string
+}{
+	0: {
+		routes: []mux.Option{
+			mux.HandleMethods([]string{http.MethodGet, http.MethodPut}, "/user/{id int}/edit", http.NotFoundHandler()),
+			mux.Handle(http.MethodDelete, "/user/{id int}", http.NotFoundHandler()),
+			mux.Handle(http.MethodGet, "/user/{name string}/posts", http.NotFoundHandler()),
+		},
+		want: []string{"/user/{name string}/posts", "GET,PUT /user/{id int}/edit", "DELETE /user/{id int}"},
+	},
+	1: {
+		routes: []mux.Option{
+			mux.Handle(http.MethodGet, "/api/users", http.NotFoundHandler()),
+			mux.Handle(http.MethodGet, "/api/orders/{id uint}", http.NotFoundHandler()),
+			mux.Handle(http.MethodGet, "/api/{p path}", http.NotFoundHandler()),
+		},
+		want: []string{"/api/{p path}", "GET /api/orders/{id uint}", "GET /api/users"},
+	},
+	2: {
+		routes: []mux.Option{
+			mux.Handle(http.MethodGet, "/files/{p path}", http.NotFoundHandler()),
+			mux.Handle(http.MethodGet, "/files/{p ...}", http.NotFoundHandler()),
+		},
+		want: []string{"/files/{p ...}", "/files/{p path}"},
+	},
+}
+
+func TestConflictMessage(t *testing.T) {
+	for i, tc := range conflictMessageTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			defer func() {
+				err, ok := recover().(error)
+				if !ok {
+					t.Fatalf("Expected panic with an error")
+				}
+				for _, want := range tc.want {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("Expected message to contain %q, got %q", want, err)
+					}
+				}
+			}()
+			mux.New(tc.routes...)
+		})
+	}
+}
+
+var validatePatternTests = [...]struct {
+	pattern string
+	reason  string
+}{
+	0:  {pattern: "/"},
+	1:  {pattern: "/user/{id uint}/edit/"},
+	2:  {pattern: "/files/{}/{p path}"},
+	3:  {pattern: "user", reason: "route is unclean, make sure it is rooted and remove any ., .., or //"},
+	4:  {pattern: "/user/../admin", reason: "route is unclean, make sure it is rooted and remove any ., .., or //"},
+	5:  {pattern: "/user/{id bool}", reason: `invalid type "bool"`},
+	6:  {pattern: "/user/{a b c}", reason: `invalid type "b c"`},
+	7:  {pattern: "/{p path}/edit", reason: "wildcards must be the last component in a route"},
+	8:  {pattern: "/docs/{$}"},
+	9:  {pattern: "/{$}/edit", reason: "{$} must be the last component in a route"},
+	10: {pattern: "/legacy/{{id}}"},
+	11: {pattern: "/report{.format}"},
+	12: {pattern: "/report{.format}/", reason: "format extensions must be at the end of the last component in a route"},
+	13: {pattern: "/report{.format}/edit", reason: "format extensions must be at the end of the last component in a route"},
+	14: {pattern: "/{id}{.format}", reason: "format extensions must follow a static component"},
+	15: {pattern: "/report{.}", reason: `invalid format extension name ""`},
+	16: {pattern: "/report{{.format}}"},
+}
+
+func TestValidatePattern(t *testing.T) {
+	for i, tc := range validatePatternTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			err := mux.ValidatePattern(tc.pattern)
+			if tc.reason == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			var perr *mux.PatternError
+			if !errors.As(err, &perr) {
+				t.Fatalf("Expected PatternError, got %v", err)
+			}
+			if perr.Pattern != tc.pattern || perr.Reason != tc.reason {
+				t.Errorf("Unexpected error: want=%q (%s), got=%q (%s)", tc.pattern, tc.reason, perr.Pattern, perr.Reason)
+			}
+		})
+	}
+}
+
+func TestWideStatic(t *testing.T) {
+	const n = 20
+	var opts []mux.Option
+	for i := 0; i < n; i++ {
+		opts = append(opts, mux.Handle("GET", "/w/s"+strconv.Itoa(i)+"/{id int}", codeHandler(t, 200+i)))
+	}
+	m := mux.New(opts...)
+	escaped := mux.New(append(opts, mux.UseEscapedPath())...)
+
+	serve := func(m *mux.ServeMux, path string) int {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code
+	}
+	for i := 0; i < n; i++ {
+		path := "/w/s" + strconv.Itoa(i) + "/1"
+		if code := serve(m, path); code != 200+i {
+			t.Errorf("Unexpected code for %s: want=%d, got=%d", path, 200+i, code)
+		}
+	}
+	if code := serve(escaped, "/w/%73%35/1"); code != 205 {
+		t.Errorf("Unexpected code for escaped path: want=205, got=%d", code)
+	}
+	if code := serve(m, "/w/nope/1"); code != 404 {
+		t.Errorf("Unexpected code for missing sibling: want=404, got=%d", code)
+	}
+
+	if !m.Remove("GET", "/w/s3/{id int}") {
+		t.Fatalf("Expected route to be removed")
+	}
+	if code := serve(m, "/w/s3/1"); code != 404 {
+		t.Errorf("Unexpected code for removed route: want=404, got=%d", code)
+	}
+	if code := serve(m, "/w/s19/1"); code != 219 {
+		t.Errorf("Unexpected code after removal: want=219, got=%d", code)
+	}
+	m.Handle("GET", "/w/new", codeHandler(t, 299))
+	if code := serve(m, "/w/new"); code != 299 {
+		t.Errorf("Unexpected code for route added after New: want=299, got=%d", code)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected variable sibling of indexed static children to panic")
+		}
+	}()
+	m.Handle("GET", "/w/{name string}", codeHandler(t, 200))
+}
+
+func TestCompactChains(t *testing.T) {
+	m := mux.New(
+		mux.Handle("GET", "/api/v1/orgs/settings/billing/invoices", codeHandler(t, 201)),
+		mux.Handle("GET", "/api/v1/orgs/settings/billing/invoices/{id uint}", codeHandler(t, 202)),
+	)
+	escaped := mux.New(
+		mux.Handle("GET", "/api/v1/orgs/settings/billing/invoices", codeHandler(t, 201)),
+		mux.UseEscapedPath(),
+	)
+	serve := func(m *mux.ServeMux, method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+	for _, tc := range []struct {
+		m      *mux.ServeMux
+		method string
+		path   string
+		code   int
+		allow  string
+	}{
+		{m: m, method: "GET", path: "/api/v1/orgs/settings/billing/invoices", code: 201},
+		{m: m, method: "GET", path: "/api/v1/orgs/settings/billing/invoices/", code: 404},
+		{m: m, method: "GET", path: "/api/v1/orgs/settings/billing/invoices/7", code: 202},
+		{m: m, method: "GET", path: "/api/v1/orgs/settings/billing/invoice", code: 404},
+		{m: m, method: "GET", path: "/api/v1/orgs/settings/billing/invoicesx", code: 404},
+		{m: m, method: "GET", path: "/api/v1/orgs/settings/billingx", code: 404},
+		{m: m, method: "OPTIONS", path: "/api/v1/orgs", code: 200, allow: "OPTIONS"},
+		{m: m, method: "OPTIONS", path: "/api/v1/orgs/", code: 404},
+		{m: m, method: "GET", path: "/api/v1/orgs", code: 405},
+		{m: escaped, method: "GET", path: "/api/v1/%6Frgs/settings/billing/invoices", code: 201},
+		{m: escaped, method: "GET", path: "/api/v1%2Forgs/settings/billing/invoices", code: 404},
+		{m: escaped, method: "OPTIONS", path: "/api/v1/orgs", code: 200, allow: "OPTIONS"},
+	} {
+		w := serve(tc.m, tc.method, tc.path)
+		if w.Code != tc.code {
+			t.Errorf("Unexpected code for %s %s: want=%d, got=%d", tc.method, tc.path, tc.code, w.Code)
+		}
+		if allow := w.Header().Get("Allow"); tc.method == "OPTIONS" && allow != tc.allow {
+			t.Errorf("Unexpected Allow header for %s %s: want=%q, got=%q", tc.method, tc.path, tc.allow, allow)
+		}
+	}
+
+	// Branch in the middle of the compacted chain after it has been published.
+	m.Handle("POST", "/api/v1/orgs", codeHandler(t, 203))
+	m.Handle("GET", "/api/v1/orgs/members", codeHandler(t, 204))
+	for path, code := range map[string]int{
+		"/api/v1/orgs/settings/billing/invoices":   201,
+		"/api/v1/orgs/settings/billing/invoices/7": 202,
+		"/api/v1/orgs/members":                     204,
+		"/api/v1/orgs/settings":                    405,
+	} {
+		if w := serve(m, "GET", path); w.Code != code {
+			t.Errorf("Unexpected code for GET %s after branching: want=%d, got=%d", path, code, w.Code)
+		}
+	}
+	if w := serve(m, "POST", "/api/v1/orgs"); w.Code != 203 {
+		t.Errorf("Unexpected code for POST /api/v1/orgs: want=203, got=%d", w.Code)
+	}
+	if w := serve(m, "OPTIONS", "/api/v1/orgs"); w.Header().Get("Allow") != "OPTIONS,POST" {
+		t.Errorf("Unexpected Allow header for /api/v1/orgs: want=%q, got=%q", "OPTIONS,POST", w.Header().Get("Allow"))
+	}
+
+	var got []string
+	for _, route := range m.Routes() {
+		got = append(got, route.Method+" "+route.Pattern)
+	}
+	want := []string{
+		"POST /api/v1/orgs",
+		"GET /api/v1/orgs/members",
+		"GET /api/v1/orgs/settings/billing/invoices",
+		"GET /api/v1/orgs/settings/billing/invoices/{id uint}",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected routes:\nwant=%q,\n got=%q", want, got)
+	}
+}
+
+func BenchmarkNew(b *testing.B) {
+	var opts []mux.Option
+	for i := 0; i < 400; i++ {
+		prefix := "/r" + strconv.Itoa(i)
+		opts = append(opts,
+			mux.Handle("GET", prefix, http.NotFoundHandler()),
+			mux.Handle("GET", prefix+"/{id uint}", http.NotFoundHandler()),
+			mux.Handle("PUT", prefix+"/{id uint}", http.NotFoundHandler()),
+		)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mux.New(opts...)
+	}
+}
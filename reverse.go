@@ -0,0 +1,120 @@
+package mux
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// PathFor returns the escaped path of the route with the given name, which was
+// set using the Name option, with its parameters replaced by the values in
+// params.
+// Params holds pairs of parameter names and values, for example
+// PathFor("user_edit", "id", 10) for the route /user/{id uint}/edit.
+// Values are formatted using fmt.Sprint.
+//
+// If no route has the given name the error wraps ErrNoRoute, and if a
+// parameter of the route is not given a value the error wraps ErrNoParam.
+// An error is also returned if a value cannot be parsed as the type of its
+// parameter, does not satisfy the constraints on the route, or is given for a
+// parameter that the route does not have.
+func (mux *ServeMux) PathFor(name string, params ...interface{}) (string, error) {
+	ep, ok := mux.root().named(name)
+	if !ok {
+		return "", fmt.Errorf("%w named %q", ErrNoRoute, name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("mux: odd number of parameters for route %q", name)
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if !ok {
+			return "", fmt.Errorf("mux: parameter name %v for route %q is not a string", params[i], name)
+		}
+		values[key] = fmt.Sprint(params[i+1])
+	}
+	return mux.renderPath(ep, values)
+}
+
+// renderPath returns the escaped path of the route of ep with the named
+// parameters replaced by values.
+func (mux *ServeMux) renderPath(ep *endpoint, values map[string]string) (string, error) {
+	route, _ := trimEnd(ep.route)
+	route, ext := trimExt(route)
+
+	var b strings.Builder
+	var used int
+	var pinfos []ParamInfo
+	param := func(name, typ, elem string) (string, error) {
+		if name == "" {
+			return "", fmt.Errorf("mux: route %q has an unnamed parameter", "/"+ep.route)
+		}
+		v, ok := values[name]
+		if !ok {
+			return "", noParam(name)
+		}
+		used++
+		if v == "" && !(typ == typWild && mux.emptyWild) || !validParam(typ, v) || elem != "" && v != "" && !validSegments(elem, v) {
+			return "", fmt.Errorf("mux: invalid value %q for parameter %q of type %s", v, name, typ)
+		}
+		pinfos = append(pinfos, ParamInfo{Raw: v, Name: name, Type: typ, elem: elem})
+		return v, nil
+	}
+	for _, seg := range ep.segments {
+		switch seg.typ {
+		case typStatic:
+			b.WriteByte('/')
+			b.WriteString(url.PathEscape(seg.name))
+		case typWild:
+			v, err := param(seg.name, seg.typ, seg.elem)
+			if err != nil {
+				return "", err
+			}
+			// An empty wildcard is rendered without the slash before it.
+			if v != "" {
+				b.WriteByte('/')
+				b.WriteString(escapeComponent(v, false))
+			}
+		default:
+			v, err := param(seg.name, seg.typ, "")
+			if err != nil {
+				return "", err
+			}
+			b.WriteByte('/')
+			b.WriteString(url.PathEscape(v))
+		}
+	}
+	if ext != "" {
+		v, err := param(ext, typString, "")
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte('.')
+		b.WriteString(url.PathEscape(v))
+	}
+	if used != len(values) {
+		for name := range values {
+			if !hasParam(pinfos, name) {
+				return "", fmt.Errorf("mux: route %q has no parameter named %q", "/"+ep.route, name)
+			}
+		}
+	}
+	if name, violated := ep.violation(pinfos); violated {
+		return "", fmt.Errorf("mux: value %q for parameter %q does not satisfy the constraints of route %q", values[name], name, "/"+ep.route)
+	}
+	if hasSlash(route) || b.Len() == 0 {
+		b.WriteByte('/')
+	}
+	return b.String(), nil
+}
+
+// hasParam reports whether params contains a parameter with the given name.
+func hasParam(params []ParamInfo, name string) bool {
+	for _, pinfo := range params {
+		if pinfo.Name == name {
+			return true
+		}
+	}
+	return false
+}
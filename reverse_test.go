@@ -0,0 +1,79 @@
+package mux_test
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var pathForTests = [...]struct {
+	name   string
+	params []interface{}
+	want   string
+	err    error
+}{
+	0:  {name: "root", want: "/"},
+	1:  {name: "user_edit", params: []interface{}{"id", 10}, want: "/user/10/edit"},
+	2:  {name: "user_edit", params: []interface{}{"id", uint64(12)}, want: "/user/12/edit"},
+	3:  {name: "user_edit", params: []interface{}{"id", "abc"}},
+	4:  {name: "user_edit", err: mux.ErrNoParam},
+	5:  {name: "user_edit", params: []interface{}{"id", 10, "other", 1}},
+	6:  {name: "user_edit", params: []interface{}{"id"}},
+	7:  {name: "nope", err: mux.ErrNoRoute},
+	8:  {name: "profile", params: []interface{}{"name", "a b/c?"}, want: "/profile/a%20b%2Fc%3F/"},
+	9:  {name: "files", params: []interface{}{"p", "a b/c.txt"}, want: "/files/a%20b/c.txt"},
+	10: {name: "files", params: []interface{}{"p", ""}},
+	11: {name: "report", params: []interface{}{"format", "json"}, want: "/report.json"},
+	12: {name: "odd", params: []interface{}{"n", 3}},
+	13: {name: "odd", params: []interface{}{"n", 2}, want: "/odd/2"},
+	14: {name: "docs", want: "/docs/"},
+	15: {name: "legacy", want: "/legacy/%7Bid%7D"},
+}
+
+func TestPathFor(t *testing.T) {
+	h := http.NotFoundHandler()
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/", h, mux.Name("root")),
+		mux.Handle(http.MethodGet, "/user/{id uint}/edit", h, mux.Name("user_edit")),
+		mux.Handle(http.MethodGet, "/profile/{name string}/", h, mux.Name("profile")),
+		mux.Handle(http.MethodGet, "/files/{p path}", h, mux.Name("files")),
+		mux.Handle(http.MethodGet, "/report{.format}", h, mux.Name("report")),
+		mux.Handle(http.MethodGet, "/odd/{n int}", h, mux.Name("odd"), mux.Constrain("n", func(v interface{}) bool {
+			return v.(int64)%2 == 0
+		})),
+		mux.Handle(http.MethodGet, "/docs/{$}", h, mux.Name("docs")),
+		mux.Handle(http.MethodGet, "/legacy/{{id}}", h, mux.Name("legacy")),
+	)
+	for i, tc := range pathForTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			p, err := m.PathFor(tc.name, tc.params...)
+			switch {
+			case tc.want == "" && err == nil:
+				t.Fatalf("Expected an error, got path %q", p)
+			case tc.want != "" && err != nil:
+				t.Fatalf("Unexpected error: %v", err)
+			case tc.err != nil && !errors.Is(err, tc.err):
+				t.Errorf("Unexpected error: want=%v, got=%v", tc.err, err)
+			}
+			if p != tc.want {
+				t.Errorf("Unexpected path: want=%q, got=%q", tc.want, p)
+			}
+		})
+	}
+}
+
+func TestTemplateFuncsError(t *testing.T) {
+	m := mux.New(mux.Handle(http.MethodGet, "/user/{id uint}/edit", http.NotFoundHandler(), mux.Name("user_edit")))
+	tmpl := template.Must(template.New("").Funcs(mux.TemplateFuncs(m)).Parse(`<a href="{{ url "user_edit" "id" . }}">Edit</a>`))
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, "me")
+	if err == nil || !strings.Contains(err.Error(), `invalid value "me"`) {
+		t.Errorf("Expected an execution error for an invalid parameter, got %v", err)
+	}
+}
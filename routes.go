@@ -0,0 +1,364 @@
+package mux
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RouteInfo describes a single registered route.
+type RouteInfo struct {
+	// The method the handler was registered for (for example "GET")
+	Method string
+	// The pattern the handler was registered with, including the leading slash
+	// (for example "/user/{id uint}")
+	Pattern string
+	// The handler registered for the method and pattern.
+	Handler http.Handler
+	// Any metadata attached to the route using the Meta option.
+	// It must not be modified.
+	Meta map[string]interface{}
+	// The names of any named route parameters in the order they appear in the
+	// pattern.
+	ParamNames []string
+	// The types of the named route parameters in the same order as ParamNames
+	// (for example "uint").
+	ParamTypes []string
+	// Whether the pattern ends in a path parameter that matches the remainder
+	// of the request path.
+	HasWildcard bool
+	// Whether the handler was registered using Subtree and matches every path
+	// below the pattern.
+	Subtree bool
+	// The API version the handler was registered for using the Version option,
+	// if any.
+	Version string
+	// The media type the handler was registered for using the Consumes option,
+	// if any.
+	Consumes string
+	// The name given to the route using the Name option, if any.
+	Name string
+	// The other patterns the handler was registered with using Alias, if any.
+	Aliases []string
+	// The priority given to the route using the Priority option, or 0.
+	Priority int
+	// Whether the route was registered using Gone or GoneHandler for an
+	// endpoint that has been retired.
+	Gone bool
+	// Whether the route was marked as deprecated using the Deprecated option,
+	// along with its sunset time and successor pattern, if any.
+	Deprecated bool
+	Sunset     time.Time
+	Successor  string
+}
+
+// Pattern returns the pattern of the route that matched r, including the
+// leading slash.
+// If r was not routed to a handler by a ServeMux, Pattern returns the empty
+// string.
+// Routes other than subtrees without named parameters, metadata, a name, or
+// values added using WithValue are passed the original request, so Pattern also
+// returns the empty string for them.
+func Pattern(r *http.Request) string {
+	rc := routeFrom(r)
+	if rc == nil || rc.ep == nil {
+		return ""
+	}
+	if rc.ep.host != "" {
+		return rc.ep.host
+	}
+	return "/" + rc.ep.route
+}
+
+// RouteName returns the name given using the Name option to the route that
+// matched r.
+// If r was not routed by a ServeMux or the route does not have a name, ok is
+// false.
+func RouteName(r *http.Request) (name string, ok bool) {
+	rc := routeFrom(r)
+	if rc == nil || rc.ep == nil || rc.ep.name == "" {
+		return "", false
+	}
+	return rc.ep.name, true
+}
+
+// Metadata returns the metadata attached to the route that matched r using the
+// Meta option.
+// If r was not routed by a ServeMux or no metadata was attached to the route,
+// Metadata returns nil.
+// The returned map must not be modified.
+func Metadata(r *http.Request) map[string]interface{} {
+	rc := routeFrom(r)
+	if rc == nil || rc.ep == nil {
+		return nil
+	}
+	return rc.ep.meta
+}
+
+// Allowed returns the sorted list of methods that may be used with the route
+// that was matched by r, including any methods that are handled automatically
+// such as OPTIONS or HEAD.
+// It is only set on requests passed to the handler configured using
+// MethodNotAllowed, for all other requests Allowed returns nil.
+// The returned slice must not be modified.
+func Allowed(r *http.Request) []string {
+	methods, _ := r.Context().Value(ctxAllowed{}).([]string)
+	return methods
+}
+
+// Routes returns every route registered on the ServeMux sorted by pattern, then
+// by method, then by version, and then by media type.
+func (mux *ServeMux) Routes() []RouteInfo {
+	var routes []RouteInfo
+	mux.root().routes(&routes)
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		if routes[i].Method != routes[j].Method {
+			return routes[i].Method < routes[j].Method
+		}
+		if routes[i].Version != routes[j].Version {
+			return routes[i].Version < routes[j].Version
+		}
+		return routes[i].Consumes < routes[j].Consumes
+	})
+	return routes
+}
+
+// Walk calls f for each route registered on the ServeMux in the same order as
+// they are returned by Routes.
+// If f returns an error, Walk stops and returns the error.
+func (mux *ServeMux) Walk(f func(RouteInfo) error) error {
+	for _, route := range mux.Routes() {
+		err := f(route)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stats describes the size and shape of the route tree.
+type Stats struct {
+	// The number of registered routes, counting each method separately.
+	Routes int
+	// The number of nodes in the route tree, including the root.
+	Nodes int
+	// The largest number of path components in any registered route.
+	MaxDepth int
+	// The number of routes that end in a parameter of type path.
+	WildcardRoutes int
+	// The number of nodes that match a route parameter, including wildcards.
+	VariableNodes int
+	// The number of routes registered for each method.
+	Methods map[string]int
+}
+
+// Stats returns statistics about the routes that are currently registered on
+// the ServeMux.
+// It walks the route tree each time it is called, so it always reflects any
+// routes that have been added or removed since the ServeMux was created.
+func (mux *ServeMux) Stats() Stats {
+	stats := Stats{Methods: make(map[string]int)}
+	mux.root().stats(&stats, 0)
+	return stats
+}
+
+// stats adds n and its children to stats.
+func (n *node) stats(stats *Stats, depth int) {
+	stats.Nodes++
+	if n.typ != typStatic {
+		stats.VariableNodes++
+	}
+	if len(n.handlers) > 0 && depth > stats.MaxDepth {
+		stats.MaxDepth = depth
+	}
+	for _, h := range n.handlers {
+		stats.Routes++
+		stats.Methods[h.method]++
+		if n.typ == typWild {
+			stats.WildcardRoutes++
+		}
+	}
+	for _, child := range n.sortedChildren() {
+		child.stats(stats, n.childDepth(child, depth))
+	}
+}
+
+// childDepth returns the depth of child given the depth of its parent n.
+// Slash and subtree nodes match the end of the same path component as n, so
+// they are at the same depth.
+func (n *node) childDepth(child *node, depth int) int {
+	if child == n.slash || child == n.subtree {
+		return depth
+	}
+	return depth + 1
+}
+
+// WalkTree calls f for each node in the route tree that has at least one
+// handler registered.
+// Nodes are visited depth first with the children of each node visited in
+// lexical order, and each node is visited before its children.
+// The pattern passed to f includes the names and types of any route parameters
+// (for example "/user/{id uint}"), methods contains the sorted list of methods
+// that have registered handlers, and depth is the number of path components in
+// the pattern.
+// If f returns an error, WalkTree stops and returns the error.
+func (mux *ServeMux) WalkTree(f func(pattern string, methods []string, depth int) error) error {
+	return mux.root().walk(f, 0)
+}
+
+// walk calls f for n and each of its children that have a handler registered.
+func (n *node) walk(f func(pattern string, methods []string, depth int) error, depth int) error {
+	if len(n.handlers) > 0 {
+		methods := n.methods()
+		sort.Strings(methods)
+		pattern := "/" + n.route
+		if n.ext != "" {
+			pattern += "{." + n.ext + "}"
+		}
+		err := f(pattern, methods, depth)
+		if err != nil {
+			return err
+		}
+	}
+	for _, child := range n.sortedChildren() {
+		err := child.walk(f, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DumpTree writes a human readable listing of the route tree to w for
+// debugging.
+// Each node is written on its own line, indented by its depth in the tree and
+// followed by the methods that have handlers registered on it, if any.
+// Static nodes are written as their literal path component, variable nodes as
+// the parameter that they match in braces (for example "{id uint}"), and
+// wildcard nodes as the parameter followed by "..." (for example
+// "{p path}...").
+// The children of each node are listed in lexical order so that the output is
+// deterministic.
+func (mux *ServeMux) DumpTree(w io.Writer) error {
+	return mux.root().dump(w, 0)
+}
+
+// dump writes n and its children to w.
+func (n *node) dump(w io.Writer, depth int) error {
+	var label string
+	switch n.typ {
+	case typStatic:
+		label = n.name
+		if n.ext != "" {
+			label += "{." + n.ext + "}"
+		}
+	case typWild:
+		label = "{" + strings.TrimPrefix(n.name+" "+n.typ, " ") + "}..."
+	default:
+		label = "{" + strings.TrimPrefix(n.name+" "+n.typ, " ") + "}"
+	}
+	line := strings.Repeat("  ", depth) + label
+	if len(n.handlers) > 0 {
+		methods := n.methods()
+		sort.Strings(methods)
+		line += " [" + strings.Join(methods, ",") + "]"
+	}
+	_, err := io.WriteString(w, line+"\n")
+	if err != nil {
+		return err
+	}
+	for _, child := range n.sortedChildren() {
+		err = child.dump(w, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedChildren returns pointers to the children of n sorted by name.
+func (n *node) sortedChildren() []*node {
+	children := make([]*node, 0, len(n.child)+2)
+	for i := range n.child {
+		children = append(children, &n.child[i])
+	}
+	if n.slash != nil {
+		children = append(children, n.slash)
+	}
+	if n.subtree != nil {
+		children = append(children, n.subtree)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].name < children[j].name
+	})
+	return children
+}
+
+// routes appends information about every handler registered on n and its
+// children to routes.
+func (n *node) routes(routes *[]RouteInfo) {
+	for _, h := range n.handlers {
+		ep := h.ep
+		info := RouteInfo{
+			Method:   h.method,
+			Pattern:  "/" + ep.route,
+			Handler:  ep.handler,
+			Meta:     ep.meta,
+			Name:     ep.name,
+			Subtree:  ep.subtree,
+			Version:  ep.version,
+			Consumes: ep.consumes,
+			Priority: ep.priority,
+			Gone:     ep.gone,
+		}
+		if d := ep.deprecation; d != nil {
+			info.Deprecated, info.Sunset = true, d.sunset
+			if d.successor != nil {
+				info.Successor = "/" + d.successor.route
+			}
+		}
+		for _, alias := range ep.aliases {
+			if alias != info.Pattern {
+				info.Aliases = append(info.Aliases, alias)
+			}
+		}
+		route, ext := trimExt(ep.route)
+		for remain := route; remain != ""; {
+			var part string
+			part, remain = nextPart(remain)
+			if part == "" {
+				continue
+			}
+			name, typ := parseParam(part)
+			if typ == typStatic {
+				continue
+			}
+			if name != "" {
+				info.ParamNames = append(info.ParamNames, name)
+				info.ParamTypes = append(info.ParamTypes, typ)
+			}
+			if typ == typWild {
+				info.HasWildcard = true
+			}
+		}
+		if ext != "" {
+			info.ParamNames = append(info.ParamNames, ext)
+			info.ParamTypes = append(info.ParamTypes, typString)
+		}
+		*routes = append(*routes, info)
+	}
+	if n.slash != nil {
+		n.slash.routes(routes)
+	}
+	if n.subtree != nil {
+		n.subtree.routes(routes)
+	}
+	for i := range n.child {
+		n.child[i].routes(routes)
+	}
+}
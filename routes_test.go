@@ -0,0 +1,474 @@
+package mux_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+func TestRoutes(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodPost, "/user/{id uint}", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/user/{id uint}", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/files/{p path}", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/user/{id uint}/edit/", http.NotFoundHandler()),
+	)
+	m.Handle(http.MethodDelete, "/user/{id uint}", http.NotFoundHandler())
+
+	want := []string{
+		"GET /",
+		"GET /files/{p path}",
+		"DELETE /user/{id uint}",
+		"GET /user/{id uint}",
+		"POST /user/{id uint}",
+		"GET /user/{id uint}/edit/",
+	}
+	var got []string
+	for _, route := range m.Routes() {
+		if route.Handler == nil {
+			t.Errorf("Route %s %s has no handler", route.Method, route.Pattern)
+		}
+		got = append(got, route.Method+" "+route.Pattern)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected routes:\nwant=%q,\n got=%q", want, got)
+	}
+
+	wantParams := []string{
+		"[] false",
+		"[p] true",
+		"[id] false",
+		"[id] false",
+		"[id] false",
+		"[id] false",
+	}
+	got = got[:0]
+	for _, route := range m.Routes() {
+		got = append(got, fmt.Sprintf("%v %t", route.ParamNames, route.HasWildcard))
+	}
+	if !reflect.DeepEqual(got, wantParams) {
+		t.Errorf("Unexpected route params:\nwant=%q,\n got=%q", wantParams, got)
+	}
+
+	got = got[:0]
+	errStop := errors.New("stop")
+	err := m.Walk(func(route mux.RouteInfo) error {
+		got = append(got, route.Method+" "+route.Pattern)
+		if len(got) == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("Unexpected error from Walk: want=%v, got=%v", errStop, err)
+	}
+	if !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("Unexpected routes walked:\nwant=%q,\n got=%q", want[:2], got)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	var scope interface{}
+	m := mux.New(
+		mux.HandleFunc(http.MethodGet, "/orders/{id uint}", func(w http.ResponseWriter, r *http.Request) {
+			scope = mux.Metadata(r)["scope"]
+		}, mux.Meta("scope", "orders:read"), mux.Meta("op", "getOrder")),
+		mux.Handle(http.MethodPost, "/orders/{id uint}", http.NotFoundHandler()),
+	)
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+	if scope != "orders:read" {
+		t.Errorf("Unexpected metadata in handler: want=%q, got=%v", "orders:read", scope)
+	}
+
+	routes := m.Routes()
+	want := map[string]interface{}{"scope": "orders:read", "op": "getOrder"}
+	if !reflect.DeepEqual(routes[0].Meta, want) {
+		t.Errorf("Unexpected metadata for %s %s: want=%v, got=%v", routes[0].Method, routes[0].Pattern, want, routes[0].Meta)
+	}
+	if routes[1].Meta != nil {
+		t.Errorf("Unexpected metadata for %s %s: %v", routes[1].Method, routes[1].Pattern, routes[1].Meta)
+	}
+
+	if meta := mux.Metadata(httptest.NewRequest(http.MethodGet, "/orders/1", nil)); meta != nil {
+		t.Errorf("Did not expect metadata on unrouted request, got %v", meta)
+	}
+}
+
+type shardKey struct{}
+
+func TestWithValue(t *testing.T) {
+	var shard, id interface{}
+	middleware := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			shard = r.Context().Value(shardKey{})
+			h.ServeHTTP(w, r)
+		})
+	}
+	h := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = mux.Param(r, "id").Value
+		if v := r.Context().Value(shardKey{}); v != shard {
+			t.Errorf("Middleware and handler disagree: %v, %v", shard, v)
+		}
+	}))
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/eu/{id uint}", h, mux.WithValue(shardKey{}, "global"), mux.WithValue(shardKey{}, "eu")),
+		mux.Handle(http.MethodGet, "/us/{id uint}", h),
+	)
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/eu/1", nil))
+	if shard != "eu" || id != uint64(1) {
+		t.Errorf("Unexpected context: want=%q %d, got=%v %v", "eu", 1, shard, id)
+	}
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/us/2", nil))
+	if shard != nil || id != uint64(2) {
+		t.Errorf("Unexpected context: want=%v %d, got=%v %v", nil, 2, shard, id)
+	}
+
+	for _, key := range []interface{}{nil, []string{}, mux.Step{}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected WithValue to panic for key %#v", key)
+				}
+			}()
+			mux.WithValue(key, "v")
+		}()
+	}
+}
+
+func TestRouteName(t *testing.T) {
+	var name string
+	var named bool
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, named = mux.RouteName(r)
+	})
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/user/{id uint}/edit", h, mux.Name("user_edit")),
+		mux.Handle(http.MethodPost, "/user/{id uint}/edit", h, mux.Name("user_edit")),
+		mux.Alias(http.MethodGet, []string{"/old", "/new"}, h, mux.Name("moved")),
+		mux.Handle(http.MethodGet, "/", h),
+	)
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/user/1/edit", nil))
+	if !named || name != "user_edit" {
+		t.Errorf("Unexpected name in handler: want=%q, got=%q (%t)", "user_edit", name, named)
+	}
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if named {
+		t.Errorf("Did not expect a name for an unnamed route, got %q", name)
+	}
+	if _, ok := mux.RouteName(httptest.NewRequest(http.MethodGet, "/", nil)); ok {
+		t.Errorf("Did not expect a name for an unrouted request")
+	}
+
+	names := make(map[string]string)
+	for _, route := range m.Routes() {
+		names[route.Method+" "+route.Pattern] = route.Name
+	}
+	want := map[string]string{
+		"GET /":                     "",
+		"GET /new":                  "moved",
+		"GET /old":                  "moved",
+		"GET /user/{id uint}/edit":  "user_edit",
+		"POST /user/{id uint}/edit": "user_edit",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Unexpected names in routes: want=%v, got=%v", want, names)
+	}
+
+	defer func() {
+		err, _ := recover().(error)
+		want := &mux.NameError{Name: "user_edit", Pattern: "/user/{id uint}", Existing: "/user/{id uint}/edit"}
+		if !reflect.DeepEqual(err, want) {
+			t.Errorf("Unexpected error: want=%v, got=%v", want, err)
+		}
+	}()
+	m.Handle(http.MethodGet, "/user/{id uint}", h, mux.Name("user_edit"))
+}
+
+func TestMatch(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/users/{id uint}/{name string}", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/static/{p path}", http.NotFoundHandler()),
+		mux.AutoHead(true),
+	)
+
+	h, pattern, params, ok := m.Match(http.MethodGet, "/users/5/sam")
+	if !ok || h == nil {
+		t.Fatalf("Expected route to match")
+	}
+	if pattern != "/users/{id uint}/{name string}" {
+		t.Errorf("Unexpected pattern: want=%q, got=%q", "/users/{id uint}/{name string}", pattern)
+	}
+	want := []mux.ParamInfo{
+		{Value: uint64(5), Raw: "5", Name: "id", Type: "uint"},
+		{Value: "sam", Raw: "sam", Name: "name", Type: "string"},
+	}
+	if len(params) != len(want) {
+		t.Fatalf("Unexpected params: want=%+v, got=%+v", want, params)
+	}
+	for i, p := range params {
+		if p.Value != want[i].Value || p.Raw != want[i].Raw || p.Name != want[i].Name || p.Type != want[i].Type {
+			t.Errorf("Unexpected param %d: want=%+v, got=%+v", i, want[i], p)
+		}
+	}
+
+	if _, pattern, _, ok := m.Match(http.MethodHead, "/static/a/b"); !ok || pattern != "/static/{p path}" {
+		t.Errorf("Expected HEAD to match GET route, got pattern=%q, ok=%t", pattern, ok)
+	}
+
+	for _, tc := range []struct {
+		method string
+		path   string
+	}{
+		{method: http.MethodPost, path: "/users/5/sam"},
+		{method: http.MethodGet, path: "/users/five/sam"},
+		{method: http.MethodGet, path: "/users/5"},
+		{method: http.MethodGet, path: "/users//5/sam"},
+		{method: http.MethodGet, path: "users/5/sam"},
+	} {
+		if _, _, _, ok := m.Match(tc.method, tc.path); ok {
+			t.Errorf("Did not expect %s %s to match", tc.method, tc.path)
+		}
+	}
+}
+
+var lookupTests = [...]struct {
+	method  string
+	path    string
+	kind    mux.MatchKind
+	pattern string
+	params  int
+	allowed []string
+}{
+	0: {method: http.MethodGet, path: "/users/5", kind: mux.KindMatched, pattern: "/users/{id uint}", params: 1, allowed: []string{"GET", "OPTIONS", "POST", "TRACE"}},
+	1: {method: http.MethodDelete, path: "/users/5", kind: mux.KindMethodNotAllowed, pattern: "/users/{id uint}", params: 1, allowed: []string{"GET", "OPTIONS", "POST", "TRACE"}},
+	2: {method: http.MethodOptions, path: "/users/5", kind: mux.KindMatched, pattern: "/users/{id uint}", params: 1, allowed: []string{"GET", "OPTIONS", "POST", "TRACE"}},
+	3: {method: http.MethodGet, path: "/users/five", kind: mux.KindNotFound},
+	4: {method: http.MethodGet, path: "/users//5", kind: mux.KindRedirect},
+	5: {method: http.MethodGet, path: "/Users/5", kind: mux.KindRedirect},
+	6: {method: http.MethodGet, path: "/", kind: mux.KindMatched, pattern: "/", allowed: []string{"GET", "OPTIONS", "TRACE"}},
+}
+
+func TestLookup(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/users/{id uint}", http.NotFoundHandler()),
+		mux.Handle(http.MethodPost, "/users/{id uint}", http.NotFoundHandler()),
+		mux.RedirectFixedPath(0),
+		mux.Trace(true),
+	)
+	for i, tc := range lookupTests {
+		t.Run(fmt.Sprintf("%d:%s_%s", i, tc.method, tc.path), func(t *testing.T) {
+			res, _ := m.Lookup(httptest.NewRequest(tc.method, tc.path, nil))
+			if res.Kind != tc.kind {
+				t.Errorf("Unexpected kind: want=%v, got=%v", tc.kind, res.Kind)
+			}
+			if res.Handler == nil {
+				t.Errorf("Expected non-nil handler")
+			}
+			if res.Pattern != tc.pattern {
+				t.Errorf("Unexpected pattern: want=%q, got=%q", tc.pattern, res.Pattern)
+			}
+			if len(res.Params) != tc.params {
+				t.Errorf("Unexpected params: want %d, got %+v", tc.params, res.Params)
+			}
+			if !reflect.DeepEqual(res.Allowed, tc.allowed) {
+				t.Errorf("Unexpected allowed methods: want=%v, got=%v", tc.allowed, res.Allowed)
+			}
+		})
+	}
+}
+
+func TestWalkTree(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/", http.NotFoundHandler()),
+		mux.Handle(http.MethodPost, "/users", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/users", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/users/{id uint}/posts", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/about", http.NotFoundHandler()),
+	)
+
+	want := []string{
+		"0 / [GET]",
+		"1 /about [GET]",
+		"1 /users [GET POST]",
+		"3 /users/{id uint}/posts [GET]",
+	}
+	var got []string
+	err := m.WalkTree(func(pattern string, methods []string, depth int) error {
+		got = append(got, fmt.Sprintf("%d %s %v", depth, pattern, methods))
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Unexpected error from WalkTree: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected nodes walked:\nwant=%q,\n got=%q", want, got)
+	}
+
+	errStop := errors.New("stop")
+	got = got[:0]
+	err = m.WalkTree(func(pattern string, methods []string, depth int) error {
+		got = append(got, pattern)
+		return errStop
+	})
+	if err != errStop || len(got) != 1 {
+		t.Errorf("Expected WalkTree to stop after the first node, got err=%v and %q", err, got)
+	}
+}
+
+func TestOpenAPIPaths(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/user/{id uint}", http.NotFoundHandler(), mux.Meta("summary", "Get a user"), mux.Meta("operationId", "getUser")),
+		mux.Handle(http.MethodDelete, "/user/{id uint}", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/files/{int}/{p path}", http.NotFoundHandler()),
+		mux.Handle("PURGE", "/cache", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/docs/{$}", http.NotFoundHandler()),
+	)
+
+	b, err := json.Marshal(m.OpenAPIPaths())
+	if err != nil {
+		t.Fatalf("Error marshaling paths: %v", err)
+	}
+	const want = `{` +
+		`"/docs/":{"get":{"responses":{"default":{"description":"Default response"}}}},` +
+		`"/files/{param1}/{p}":{"parameters":[` +
+		`{"name":"param1","in":"path","required":true,"schema":{"type":"integer","format":"int64"}},` +
+		`{"name":"p","in":"path","description":"The remainder of the path, which may contain slashes.","required":true,"schema":{"type":"string"}}],` +
+		`"get":{"responses":{"default":{"description":"Default response"}}}},` +
+		`"/user/{id}":{"parameters":[{"name":"id","in":"path","required":true,"schema":{"type":"integer","format":"int64","minimum":0}}],` +
+		`"get":{"operationId":"getUser","summary":"Get a user","responses":{"default":{"description":"Default response"}}},` +
+		`"delete":{"responses":{"default":{"description":"Default response"}}}}` +
+		`}`
+	if string(b) != want {
+		t.Errorf("Unexpected paths:\nwant=%s,\n got=%s", want, b)
+	}
+}
+
+func TestDumpTree(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/", http.NotFoundHandler()),
+		mux.Handle(http.MethodPost, "/user/{id uint}", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/user/{id uint}", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/user/{id uint}/edit", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/files/{p path}", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/about/{}", http.NotFoundHandler()),
+	)
+
+	const want = `/ [GET]
+  about
+    {string} [GET]
+  files
+    {p path}... [GET]
+  user
+    {id uint} [GET,POST]
+      edit [GET]
+`
+	var buf strings.Builder
+	err := m.DumpTree(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error dumping tree: %v", err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("Unexpected tree:\nwant=\n%s\ngot=\n%s", want, got)
+	}
+}
+
+func TestAlias(t *testing.T) {
+	var patterns []string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		patterns = append(patterns, mux.Pattern(r))
+	})
+	m := mux.New(
+		mux.Alias(http.MethodGet, []string{"/docs/{p path}", "/documentation/{p path}"}, h),
+	)
+	for _, path := range []string{"/docs/a", "/documentation/a"} {
+		m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	want := []string{"/docs/{p path}", "/documentation/{p path}"}
+	if !reflect.DeepEqual(patterns, want) {
+		t.Errorf("Unexpected patterns: want=%q, got=%q", want, patterns)
+	}
+
+	routes := m.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("Unexpected number of routes: want=2, got=%d", len(routes))
+	}
+	if !reflect.DeepEqual(routes[0].Aliases, want[1:]) || !reflect.DeepEqual(routes[1].Aliases, want[:1]) {
+		t.Errorf("Unexpected aliases: %q, %q", routes[0].Aliases, routes[1].Aliases)
+	}
+}
+
+func TestAliasConflict(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/documentation/{id int}", http.NotFoundHandler()),
+	)
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("Expected conflicting alias to panic")
+			}
+		}()
+		mux.Alias(http.MethodGet, []string{"/docs/{p path}", "/documentation/{p path}"}, http.NotFoundHandler())(m)
+	}()
+	if routes := m.Routes(); len(routes) != 1 {
+		t.Errorf("Expected no aliases to be registered, got %+v", routes)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected conflicting alias to panic")
+		}
+	}()
+	mux.New(
+		mux.Alias(http.MethodGet, []string{"/docs/{p path}", "/docs/{p path}"}, http.NotFoundHandler()),
+	)
+}
+
+func TestPatternNoRoute(t *testing.T) {
+	if p := mux.Pattern(httptest.NewRequest(http.MethodGet, "/", nil)); p != "" {
+		t.Errorf("Expected no pattern for request that was not routed, got %q", p)
+	}
+}
+
+func TestStats(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/user/{id uint}", http.NotFoundHandler()),
+		mux.Handle(http.MethodPost, "/user/{id uint}", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/user/{id uint}/edit/", http.NotFoundHandler()),
+		mux.Handle(http.MethodGet, "/files/{p path}", http.NotFoundHandler()),
+	)
+	// The trailing slash of /user/{id uint}/edit/ is not a path component.
+	want := mux.Stats{
+		Routes:         5,
+		Nodes:          7,
+		MaxDepth:       3,
+		WildcardRoutes: 1,
+		VariableNodes:  2,
+		Methods:        map[string]int{http.MethodGet: 4, http.MethodPost: 1},
+	}
+	if got := m.Stats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected stats:\nwant=%+v,\n got=%+v", want, got)
+	}
+
+	m.Remove(http.MethodGet, "/files/{p path}")
+	want.Routes--
+	want.Nodes -= 2
+	want.WildcardRoutes--
+	want.VariableNodes--
+	want.Methods[http.MethodGet]--
+	if got := m.Stats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected stats after removing a route:\nwant=%+v,\n got=%+v", want, got)
+	}
+}
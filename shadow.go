@@ -0,0 +1,140 @@
+package mux
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// defShadowBodyLimit is the default size in bytes of the largest request body
+// that is copied to a shadow handler.
+const defShadowBodyLimit = 1 << 20
+
+// shadow is a handler that is sent a copy of a sample of the requests routed to
+// an endpoint.
+type shadow struct {
+	h    http.Handler
+	rate float64
+}
+
+// Shadow mirrors a sample of the requests routed to the route to h, for example
+// to compare a rewritten handler against the current one using production
+// traffic.
+// sampleRate is the fraction of requests that are mirrored, from 0 for none to
+// 1 for all of them.
+//
+// h is called in a new goroutine after the route has been matched with a copy
+// of the request and a ResponseWriter that discards the response, so it never
+// affects the response sent to the client.
+// The context of the copy is not canceled when the client disconnects or the
+// original request completes, and the request body is buffered so that both
+// handlers can read it.
+// Requests with a body larger than the limit set using ShadowBodyLimit are not
+// mirrored.
+// If h panics, the panic is recovered and discarded.
+//
+// If Observe is used, the observe function is also called for each mirrored
+// request with the Shadow field of the Observation set.
+func Shadow(h http.Handler, sampleRate float64) RouteOption {
+	return func(ep *endpoint) {
+		ep.shadow = &shadow{h: h, rate: sampleRate}
+	}
+}
+
+// ShadowBodyLimit sets the size in bytes of the largest request body that is
+// buffered so that the request can be mirrored to a handler set using Shadow.
+// If n is 0, only requests without a body are mirrored. The default is 1 MiB.
+func ShadowBodyLimit(n int64) Option {
+	return func(mux *ServeMux) {
+		mux.shadowBodyLimit = n
+	}
+}
+
+// shadowed mirrors r to the shadow handler of the route that it was routed to,
+// if any, and returns the request to pass to the primary handler.
+func (mux *ServeMux) shadowed(r *http.Request, res MatchResult) *http.Request {
+	if res.Kind != KindMatched {
+		return r
+	}
+	rc := routeFrom(r)
+	if rc == nil || rc.ep == nil || rc.ep.shadow == nil {
+		return r
+	}
+	return mux.startShadow(rc.ep, r, res)
+}
+
+// startShadow mirrors r, which was routed to ep, to the shadow handler of ep if
+// the request is sampled.
+// If the body of r is buffered, the returned request must be used in place of
+// r when calling the primary handler.
+func (mux *ServeMux) startShadow(ep *endpoint, r *http.Request, res MatchResult) *http.Request {
+	if rand.Float64() >= ep.shadow.rate {
+		return r
+	}
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		var buf bytes.Buffer
+		_, err := io.CopyN(&buf, r.Body, mux.shadowBodyLimit+1)
+		body = buf.Bytes()
+		// The primary handler must still see the whole body, including any part
+		// that was read before the limit was exceeded or an error occurred.
+		primary := *r
+		primary.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+		r = &primary
+		if err != io.EOF || int64(len(body)) > mux.shadowBodyLimit {
+			return r
+		}
+	}
+
+	mirror := r.Clone(detachedContext{r.Context()})
+	mirror.Body = http.NoBody
+	if body != nil {
+		mirror.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	go mux.serveShadow(ep.shadow.h, mirror, res)
+	return r
+}
+
+// serveShadow serves r using the shadow handler h and discards the response
+// and any panic.
+func (mux *ServeMux) serveShadow(h http.Handler, r *http.Request, res MatchResult) {
+	defer func() {
+		recover()
+	}()
+	w := discardWriter{header: make(http.Header)}
+	if mux.observe == nil {
+		h.ServeHTTP(w, r)
+		return
+	}
+	res.Handler = h
+	mux.serveObserved(w, r, res, time.Now(), true)
+}
+
+// readCloser combines a Reader and the Closer of the body it replaces.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// discardWriter is an http.ResponseWriter that discards the response.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w discardWriter) Header() http.Header         { return w.header }
+func (w discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w discardWriter) WriteHeader(int)             {}
+
+// detachedContext is a context that carries the values of its parent but is
+// never canceled and has no deadline.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
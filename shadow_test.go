@@ -0,0 +1,105 @@
+package mux_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"code.soquee.net/mux"
+)
+
+func TestShadow(t *testing.T) {
+	type mirrored struct {
+		body string
+		id   string
+		err  error
+	}
+	got := make(chan mirrored, 1)
+	var mu sync.Mutex
+	var observations []mux.Observation
+	m := mux.New(
+		mux.Handle(http.MethodPost, "/orders/{id uint}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			w.WriteHeader(testCode)
+			w.Write(body)
+		}), mux.Shadow(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			// Wait for the original request to be canceled.
+			time.Sleep(10 * time.Millisecond)
+			got <- mirrored{body: string(body), id: mux.Param(r, "id").Raw, err: r.Context().Err()}
+			w.WriteHeader(http.StatusTeapot)
+		}), 1)),
+		mux.Handle(http.MethodPost, "/panic", codeHandler(t, testCode), mux.Shadow(panicHandler(), 1)),
+		mux.Handle(http.MethodPost, "/never", codeHandler(t, testCode), mux.Shadow(failHandler(t), 0)),
+		mux.Handle(http.MethodPost, "/large", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			w.Write(body)
+		}), mux.Shadow(failHandler(t), 1)),
+		mux.ShadowBodyLimit(8),
+		mux.Observe(func(o mux.Observation) {
+			mu.Lock()
+			defer mu.Unlock()
+			observations = append(observations, o)
+		}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/orders/12", strings.NewReader("order")).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	cancel()
+	if rec.Code != testCode || rec.Body.String() != "order" {
+		t.Errorf("Unexpected primary response: want=%d %q, got=%d %q", testCode, "order", rec.Code, rec.Body.String())
+	}
+	select {
+	case mirror := <-got:
+		if mirror.body != "order" || mirror.id != "12" || mirror.err != nil {
+			t.Errorf("Unexpected mirrored request: body=%q id=%q err=%v", mirror.body, mirror.id, mirror.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Shadow handler was not called")
+	}
+
+	for _, path := range []string{"/panic", "/never"} {
+		rec = httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		if rec.Code != testCode {
+			t.Errorf("Unexpected code for %s: want=%d, got=%d", path, testCode, rec.Code)
+		}
+	}
+
+	// Bodies over the limit are not mirrored but are still passed to the
+	// primary handler in full.
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/large", strings.NewReader("0123456789")))
+	if body := rec.Body.String(); body != "0123456789" {
+		t.Errorf("Unexpected primary body: want=%q, got=%q", "0123456789", body)
+	}
+
+	// The observation for the mirrored order request is recorded once its
+	// handler returns.
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		var shadows int
+		for _, o := range observations {
+			if o.Shadow && o.Pattern == "/orders/{id uint}" && o.Status == http.StatusTeapot {
+				shadows++
+			}
+		}
+		total := len(observations)
+		mu.Unlock()
+		if shadows == 1 && total >= 5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected one shadow observation, got %d of %d", shadows, total)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,57 @@
+//go:build go1.21
+// +build go1.21
+
+package mux
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// Logger configures the ServeMux to log requests that did not match a route,
+// requests that matched a route but not its methods, and requests that were
+// redirected to a canonical path, at the given level.
+// Requests that matched a route are not logged; use Observe to record them.
+//
+// Not found records include the method and path of the request, method not
+// allowed records also include the pattern that matched the path, and
+// redirect records include the original path and the location of the
+// redirect.
+// If l is nil, nothing is logged.
+// Logger is only available when building with Go 1.21 or later.
+func Logger(l *slog.Logger, level slog.Level) Option {
+	return func(mux *ServeMux) {
+		if l == nil {
+			mux.logRoute = nil
+			return
+		}
+		mux.logRoute = func(w http.ResponseWriter, r *http.Request, res MatchResult) {
+			var msg string
+			switch res.Kind {
+			case KindNotFound:
+				msg = "mux: not found"
+			case KindMethodNotAllowed:
+				msg = "mux: method not allowed"
+			case KindRedirect:
+				msg = "mux: redirect"
+			default:
+				return
+			}
+			ctx := r.Context()
+			if !l.Enabled(ctx, level) {
+				return
+			}
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.EscapedPath()),
+			}
+			switch res.Kind {
+			case KindMethodNotAllowed:
+				attrs = append(attrs, slog.String("pattern", res.Pattern))
+			case KindRedirect:
+				attrs = append(attrs, slog.String("location", w.Header().Get("Location")))
+			}
+			l.LogAttrs(ctx, level, msg, attrs...)
+		}
+	}
+}
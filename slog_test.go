@@ -0,0 +1,82 @@
+//go:build go1.21
+// +build go1.21
+
+package mux_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var loggerTests = [...]struct {
+	method string
+	path   string
+	want   map[string]interface{}
+}{
+	0: {method: http.MethodGet, path: "/user/1"},
+	1: {method: http.MethodGet, path: "/nope", want: map[string]interface{}{
+		"level": "WARN", "msg": "mux: not found", "method": "GET", "path": "/nope",
+	}},
+	2: {method: http.MethodPost, path: "/user/1", want: map[string]interface{}{
+		"level": "WARN", "msg": "mux: method not allowed", "method": "POST", "path": "/user/1", "pattern": "/user/{id uint}",
+	}},
+	3: {method: http.MethodGet, path: "/user/../user/1", want: map[string]interface{}{
+		"level": "WARN", "msg": "mux: redirect", "method": "GET", "path": "/user/../user/1", "location": "/user/1",
+	}},
+	4: {method: http.MethodGet, path: "/user/1/", want: map[string]interface{}{
+		"level": "WARN", "msg": "mux: redirect", "method": "GET", "path": "/user/1/", "location": "/user/1",
+	}},
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/user/{id uint}", codeHandler(t, testCode)),
+		mux.RedirectTrailingSlash(http.StatusMovedPermanently),
+		mux.Logger(logger, slog.LevelWarn),
+	)
+	for i, tc := range loggerTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			buf.Reset()
+			m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, tc.path, nil))
+			if tc.want == nil {
+				if buf.Len() != 0 {
+					t.Errorf("Did not expect a log record, got %s", buf.String())
+				}
+				return
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Error decoding log record %q: %v", buf.String(), err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Unexpected log record: want=%v, got=%v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	m := mux.New(mux.Logger(slog.New(slog.NewTextHandler(&buf, nil)), slog.LevelDebug))
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if buf.Len() != 0 {
+		t.Errorf("Did not expect a record below the level of the handler, got %s", buf.String())
+	}
+}
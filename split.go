@@ -0,0 +1,121 @@
+package mux
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+)
+
+// The arms of a route registered using Split, as reported by Arm and in the
+// Arm field of an Observation.
+const (
+	ArmA = "a"
+	ArmB = "b"
+)
+
+// SplitControl adjusts the share of requests sent to the second handler of a
+// route registered using Split while the ServeMux is serving requests.
+// It is safe for concurrent use.
+type SplitControl struct {
+	// ratio holds the bits of the float64 ratio so that it can be accessed
+	// atomically.
+	ratio uint64
+}
+
+// Ratio returns the fraction of requests that are currently sent to the
+// second handler.
+func (c *SplitControl) Ratio() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&c.ratio))
+}
+
+// SetRatio sets the fraction of requests that are sent to the second handler,
+// from 0 for none to 1 for all of them.
+func (c *SplitControl) SetRatio(ratio float64) {
+	atomic.StoreUint64(&c.ratio, math.Float64bits(ratio))
+}
+
+// split is the second handler of an endpoint registered using Split.
+type split struct {
+	b   http.Handler
+	ctl *SplitControl
+}
+
+// Split registers a route that sends a fraction of the requests it matches,
+// given by ratio, to b and the rest to a, for example to gradually roll out a
+// new implementation of an endpoint.
+// The ratio can be changed while the ServeMux is serving requests using the
+// returned SplitControl.
+//
+// By default each request is assigned to a handler at random.
+// To consistently send the requests of the same client to the same handler,
+// use SplitKey.
+// The handler that served a request is reported by Arm and in the Arm field of
+// the Observation passed to the function configured using Observe.
+func Split(method, pattern string, a, b http.Handler, ratio float64, opts ...RouteOption) (Option, *SplitControl) {
+	ctl := &SplitControl{}
+	ctl.SetRatio(ratio)
+	opts = append(opts[:len(opts):len(opts)], func(ep *endpoint) {
+		ep.split = &split{b: b, ctl: ctl}
+	})
+	return Handle(method, pattern, a, opts...), ctl
+}
+
+// SplitKey makes the assignment of requests to the handlers of a route
+// registered using Split sticky: requests for which key returns the same
+// non-empty string are always sent to the same handler, as long as the ratio
+// does not change.
+// For example, key may return the value of a session cookie or of a header
+// that identifies the client.
+// Requests for which key returns the empty string are assigned at random.
+// SplitKey has no effect on routes that were not registered using Split.
+func SplitKey(key func(r *http.Request) string) RouteOption {
+	return func(ep *endpoint) {
+		ep.splitKey = key
+	}
+}
+
+// useB reports whether r, which was routed to ep, should be sent to the second
+// handler of the split.
+func (ep *endpoint) useB(r *http.Request) bool {
+	ratio := ep.split.ctl.Ratio()
+	var k string
+	if ep.splitKey != nil {
+		k = ep.splitKey(r)
+	}
+	if k == "" {
+		return rand.Float64() < ratio
+	}
+	h := fnv.New64a()
+	h.Write([]byte(ep.route))
+	h.Write([]byte{0})
+	h.Write([]byte(k))
+	return float64(h.Sum64())/math.MaxUint64 < ratio
+}
+
+// splitArm selects the handler of the split endpoint ep that serves r and
+// returns its arm along with the handler to use in place of h, which was
+// resolved for the first handler.
+func (mux *ServeMux) splitArm(ep *endpoint, n *node, r *http.Request, h http.Handler) (string, http.Handler) {
+	if !ep.useB(r) {
+		return ArmA, h
+	}
+	if _, ok := n.handlers.get(r.Method); !ok && r.Method == http.MethodHead {
+		// The request is handled by the GET endpoint.
+		return ArmB, headHandler(ep.split.b)
+	}
+	return ArmB, ep.split.b
+}
+
+// Arm returns the arm of the route registered using Split that r was routed
+// to, either ArmA or ArmB.
+// If r was not routed by a ServeMux to a route registered using Split, Arm
+// returns the empty string.
+func Arm(r *http.Request) string {
+	rc := routeFrom(r)
+	if rc == nil {
+		return ""
+	}
+	return rc.arm
+}
@@ -0,0 +1,103 @@
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+func TestSplit(t *testing.T) {
+	armHandler := func(code int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Arm", mux.Arm(r))
+			w.WriteHeader(code)
+		})
+	}
+	var mu sync.Mutex
+	arms := make(map[string]int)
+	split, ctl := mux.Split(http.MethodGet, "/search", armHandler(testCode), armHandler(testStatusCode), 0,
+		mux.SplitKey(func(r *http.Request) string {
+			return r.Header.Get("User")
+		}),
+	)
+	m := mux.New(
+		split,
+		mux.Handle(http.MethodGet, "/plain", armHandler(testCode)),
+		mux.AutoHead(true),
+		mux.Observe(func(o mux.Observation) {
+			mu.Lock()
+			defer mu.Unlock()
+			arms[o.Arm]++
+		}),
+	)
+	serve := func(method, path, user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if user != "" {
+			req.Header.Set("User", user)
+		}
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 10; i++ {
+		if rec := serve(http.MethodGet, "/search", strconv.Itoa(i)); rec.Code != testCode || rec.Header().Get("Arm") != mux.ArmA {
+			t.Fatalf("Unexpected response with ratio 0: code=%d, arm=%q", rec.Code, rec.Header().Get("Arm"))
+		}
+	}
+	ctl.SetRatio(1)
+	if ratio := ctl.Ratio(); ratio != 1 {
+		t.Errorf("Unexpected ratio: want=1, got=%v", ratio)
+	}
+	if rec := serve(http.MethodGet, "/search", ""); rec.Code != testStatusCode || rec.Header().Get("Arm") != mux.ArmB {
+		t.Errorf("Unexpected response with ratio 1: code=%d, arm=%q", rec.Code, rec.Header().Get("Arm"))
+	}
+	if rec := serve(http.MethodHead, "/search", ""); rec.Code != testStatusCode {
+		t.Errorf("Unexpected HEAD response with ratio 1: code=%d", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/plain", ""); rec.Header().Get("Arm") != "" {
+		t.Errorf("Unexpected arm for route without split: %q", rec.Header().Get("Arm"))
+	}
+
+	// Requests with the same key are always sent to the same arm.
+	ctl.SetRatio(0.5)
+	seen := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		user := strconv.Itoa(i)
+		first := serve(http.MethodGet, "/search", user).Header().Get("Arm")
+		for j := 0; j < 3; j++ {
+			if arm := serve(http.MethodGet, "/search", user).Header().Get("Arm"); arm != first {
+				t.Fatalf("User %s flapped between arms: %q and %q", user, first, arm)
+			}
+		}
+		seen[first]++
+	}
+	if seen[mux.ArmA] == 0 || seen[mux.ArmB] == 0 {
+		t.Errorf("Expected users to be split between both arms, got %v", seen)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if arms[mux.ArmA] == 0 || arms[mux.ArmB] == 0 || arms[""] != 1 {
+		t.Errorf("Unexpected arms in observations: %v", arms)
+	}
+}
+
+func TestSplitWithParam(t *testing.T) {
+	split, _ := mux.Split(http.MethodGet, "/items/{id string}", failHandler(t), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if arm := mux.Arm(mux.WithParam(r, "id", "2")); arm != mux.ArmB {
+			t.Errorf("Expected WithParam to keep the arm: want=%q, got=%q", mux.ArmB, arm)
+		}
+		w.WriteHeader(testCode)
+	}), 1)
+	m := mux.New(split)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/1", nil))
+	if rec.Code != testCode {
+		t.Errorf("Unexpected code: want=%d, got=%d", testCode, rec.Code)
+	}
+}
@@ -0,0 +1,113 @@
+package mux
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// FromStdPattern converts a pattern in the syntax used by http.ServeMux in Go
+// 1.22 and later (for example "GET /users/{id}/files/{p...}") into a method and
+// a pattern that can be registered with this package.
+//
+// Wildcards such as {id} are converted to string parameters and wildcards that
+// match the remainder of the path such as {p...} are converted to path
+// parameters.
+// In net/http a pattern that ends in a slash matches every path below it, so
+// if the returned pattern ends in a slash it should be registered using
+// Subtree to preserve its meaning.
+// Unlike http.ServeMux, GET patterns are not used for HEAD requests unless the
+// AutoHead option is used.
+//
+// Patterns without a method and patterns with a host are not supported.
+// If the pattern cannot be converted the returned error is a *PatternError.
+func FromStdPattern(std string) (method, pattern string, err error) {
+	method, path, ok := cut(std, " ")
+	if !ok {
+		return "", "", &PatternError{Pattern: std, Reason: "patterns without a method are not supported"}
+	}
+	path = strings.TrimLeft(path, " \t")
+	if !validMethod(method) {
+		return "", "", &PatternError{Pattern: std, Reason: "invalid method " + method}
+	}
+	if !strings.HasPrefix(path, "/") {
+		return "", "", &PatternError{Pattern: std, Reason: "patterns with a host are not supported"}
+	}
+
+	var b strings.Builder
+	for remain := path[1:]; remain != ""; {
+		var part string
+		part, remain = nextPart(remain)
+		b.WriteByte('/')
+		if !strings.HasPrefix(part, "{") {
+			if strings.ContainsAny(part, "{}") {
+				return "", "", &PatternError{Pattern: std, Reason: "wildcards must be a full path segment"}
+			}
+			unescaped, err := url.PathUnescape(part)
+			if err != nil || strings.Contains(unescaped, "/") {
+				return "", "", &PatternError{Pattern: std, Reason: "invalid escape in path segment " + part}
+			}
+			b.WriteString(unescaped)
+			continue
+		}
+		if !strings.HasSuffix(part, "}") {
+			return "", "", &PatternError{Pattern: std, Reason: "wildcards must be a full path segment"}
+		}
+		name := part[1 : len(part)-1]
+		typ := typString
+		switch {
+		case name == "$":
+			if remain != "" {
+				return "", "", &PatternError{Pattern: std, Reason: "{$} must be the last segment"}
+			}
+			b.WriteString("{$}")
+			continue
+		case strings.HasSuffix(name, "..."):
+			if remain != "" || hasSlash(path) {
+				return "", "", &PatternError{Pattern: std, Reason: "{" + name + "} wildcards must be the last segment"}
+			}
+			name = strings.TrimSuffix(name, "...")
+			typ = typWild
+		}
+		if name == "" {
+			return "", "", &PatternError{Pattern: std, Reason: "wildcards must be named"}
+		}
+		b.WriteString("{" + name + " " + typ + "}")
+	}
+	if b.Len() == 0 || hasSlash(path) {
+		b.WriteByte('/')
+	}
+
+	pattern = b.String()
+	if err := ValidatePattern(pattern); err != nil {
+		return "", "", err
+	}
+	return strings.ToUpper(method), pattern, nil
+}
+
+// HandleStd registers the handler for a pattern in the syntax used by
+// http.ServeMux in Go 1.22 and later, for example "GET /users/{id}".
+// The pattern is converted using FromStdPattern and patterns that end in a
+// slash are registered using Subtree so that, like in net/http, they match
+// every path below them.
+// If the pattern cannot be converted or registered, HandleStd panics.
+func HandleStd(std string, h http.Handler, opts ...RouteOption) Option {
+	method, pattern, err := FromStdPattern(std)
+	if err != nil {
+		panic(err)
+	}
+	if hasSlash(pattern) {
+		return Subtree(method, pattern, h, opts...)
+	}
+	return Handle(method, pattern, h, opts...)
+}
+
+// cut slices s around the first instance of sep.
+// It is the same as strings.Cut, which is not available in all supported
+// versions of Go.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
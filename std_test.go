@@ -0,0 +1,92 @@
+package mux_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var fromStdTests = [...]struct {
+	std     string
+	method  string
+	pattern string
+	err     bool
+}{
+	0:  {std: "GET /", method: "GET", pattern: "/"},
+	1:  {std: "GET /users/{id}", method: "GET", pattern: "/users/{id string}"},
+	2:  {std: "post /users/{id}/files/{p...}", method: "POST", pattern: "/users/{id string}/files/{p path}"},
+	3:  {std: "GET /images/", method: "GET", pattern: "/images/"},
+	4:  {std: "GET  /a%20b", method: "GET", pattern: "/a b"},
+	5:  {std: "/users", err: true},
+	6:  {std: "GET example.com/users", err: true},
+	7:  {std: "GET /users/{$}", method: "GET", pattern: "/users/{$}"},
+	8:  {std: "GET /files/{p...}/x", err: true},
+	9:  {std: "GET /files/{p...}/", err: true},
+	10: {std: "GET /users/x{id}", err: true},
+	11: {std: "GET /users/{}", err: true},
+	12: {std: "GET /a//b", err: true},
+	13: {std: "G(ET /a", err: true},
+	14: {std: "GET /a%2Fb", err: true},
+	15: {std: "GET /users/{$}/x", err: true},
+}
+
+func TestFromStdPattern(t *testing.T) {
+	for i, tc := range fromStdTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			method, pattern, err := mux.FromStdPattern(tc.std)
+			if tc.err {
+				var patternErr *mux.PatternError
+				if !errors.As(err, &patternErr) {
+					t.Errorf("Expected PatternError for %q, got=%v", tc.std, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error converting %q: %v", tc.std, err)
+			}
+			if method != tc.method {
+				t.Errorf("Unexpected method: want=%q, got=%q", tc.method, method)
+			}
+			if pattern != tc.pattern {
+				t.Errorf("Unexpected pattern: want=%q, got=%q", tc.pattern, pattern)
+			}
+		})
+	}
+}
+
+func TestHandleStd(t *testing.T) {
+	m := mux.New(
+		mux.HandleStd("GET /users/{id}", codeHandler(t, 201)),
+		mux.HandleStd("GET /files/{p...}", codeHandler(t, 202)),
+		mux.HandleStd("GET /static/", codeHandler(t, 203)),
+		mux.HandleStd("GET /docs/{$}", codeHandler(t, 204)),
+	)
+	for _, tc := range []struct {
+		path string
+		code int
+	}{
+		{path: "/users/me", code: 201},
+		{path: "/files/a/b", code: 202},
+		{path: "/static/", code: 203},
+		{path: "/static/css/main.css", code: 203},
+		{path: "/docs/", code: 204},
+		{path: "/docs/x", code: 404},
+	} {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.code {
+			t.Errorf("Unexpected code for %s: want=%d, got=%d", tc.path, tc.code, rec.Code)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected HandleStd to panic for unsupported pattern")
+		}
+	}()
+	mux.HandleStd("/users", failHandler(t))
+}
@@ -0,0 +1,194 @@
+//go:build go1.22
+// +build go1.22
+
+package mux
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// stdPlaceholder is the value substituted for wildcards when looking up the
+// handler registered for a pattern on an http.ServeMux.
+const stdPlaceholder = "_mux_placeholder_"
+
+// FromStdMux converts routes registered on an http.ServeMux into options that
+// register the same handlers on a ServeMux, for example to migrate a service
+// one route at a time.
+// Because http.ServeMux does not list its routes, the patterns to convert must
+// be given exactly as they were registered.
+// The handler for each pattern is looked up on m and registered unchanged so
+// that it can be found in the output of Routes.
+// Lookups rely on m using the pattern syntax introduced in Go 1.22, which is
+// disabled by GODEBUG=httpmuxgo121=1 and in programs whose main module
+// declares an earlier version of Go.
+//
+// Patterns are converted using FromStdPattern and, as with HandleStd, patterns
+// that end in a slash are registered using Subtree.
+// Patterns without a method or with a host cannot be represented and patterns
+// that are not registered on m are reported as errors.
+// The options for every pattern that could be converted are returned along
+// with an error that joins a *PatternError for each one that could not.
+func FromStdMux(m *http.ServeMux, patterns []string) ([]Option, error) {
+	var opts []Option
+	var errs []error
+	for _, std := range patterns {
+		method, pattern, err := FromStdPattern(std)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		h, ok := stdHandler(m, std, method)
+		if !ok {
+			errs = append(errs, &PatternError{Pattern: std, Reason: "not registered on the http.ServeMux"})
+			continue
+		}
+		if hasSlash(pattern) {
+			opts = append(opts, Subtree(method, pattern, h))
+			continue
+		}
+		opts = append(opts, Handle(method, pattern, h))
+	}
+	return opts, errors.Join(errs...)
+}
+
+// stdHandler returns the handler registered on m for the pattern std, which
+// must have a method and no host, by looking up a request that only that
+// pattern matches.
+func stdHandler(m *http.ServeMux, std, method string) (http.Handler, bool) {
+	_, path, _ := cut(std, " ")
+	path = strings.TrimLeft(path, " \t")
+
+	var escaped strings.Builder
+	for remain := path[1:]; ; {
+		var part string
+		part, remain = nextPart(remain)
+		escaped.WriteByte('/')
+		switch {
+		case part == "{$}":
+		case strings.HasPrefix(part, "{"):
+			escaped.WriteString(stdPlaceholder)
+		default:
+			escaped.WriteString(part)
+		}
+		if remain == "" {
+			break
+		}
+	}
+	if hasSlash(path) && path != "/" && !strings.HasSuffix(path, "{$}") {
+		escaped.WriteByte('/')
+	}
+	unescaped, err := url.PathUnescape(escaped.String())
+	if err != nil {
+		return nil, false
+	}
+	r := &http.Request{
+		Method: method,
+		URL:    &url.URL{Path: unescaped, RawPath: escaped.String()},
+	}
+	h, pattern := m.Handler(r)
+	return h, pattern == std
+}
+
+// StdPatterns returns the pattern of every route registered on the ServeMux in
+// the syntax used by http.ServeMux in Go 1.22 and later, for example
+// "GET /users/{id}", in the same order as the routes are returned by Routes.
+// Each pattern is only returned once, even if several routes share it because
+// they differ only by version or media type.
+//
+// Typed parameters are converted to wildcards such as {id} and path parameters
+// to wildcards such as {p...}, so the patterns may match requests that the
+// routes would not.
+// Routes registered using Subtree end in a slash and other routes that end in a
+// slash end in {$}.
+// The patterns can be converted back using FromStdPattern or FromStdMux, which
+// turn the parameters into string parameters.
+//
+// Routes that cannot be expressed, for example because they have unnamed
+// parameters or a format extension, are left out.
+// The patterns of every other route are returned along with an error that joins
+// a *PatternError for each pattern that was left out.
+func (mux *ServeMux) StdPatterns() ([]string, error) {
+	var patterns []string
+	var errs []error
+	seen := make(map[string]bool)
+	for _, route := range mux.Routes() {
+		path, err := toStdPath(route.Pattern, route.Subtree)
+		if err != nil {
+			if !seen[route.Pattern] {
+				errs = append(errs, err)
+			}
+			seen[route.Pattern] = true
+			continue
+		}
+		std := route.Method + " " + path
+		if !seen[std] {
+			patterns = append(patterns, std)
+		}
+		seen[std] = true
+	}
+	return patterns, errors.Join(errs...)
+}
+
+// toStdPath converts pattern into the path of an http.ServeMux pattern.
+func toStdPath(pattern string, subtree bool) (string, error) {
+	route, ext := trimExt(pattern[1:])
+	if ext != "" {
+		return "", &PatternError{Pattern: pattern, Reason: "format extensions cannot be expressed as wildcards"}
+	}
+	var b strings.Builder
+	names := make(map[string]bool)
+	for remain := route; remain != ""; {
+		var part string
+		part, remain = nextPart(remain)
+		b.WriteByte('/')
+		name, typ := parseParam(part)
+		switch typ {
+		case typStatic:
+			b.WriteString(url.PathEscape(name))
+			continue
+		case typEnd:
+			b.WriteString("{$}")
+			continue
+		case typInt, typUint, typFloat, typString, typWild:
+		default:
+			return "", &PatternError{Pattern: pattern, Reason: fmt.Sprintf("parameters of type %q cannot be expressed as wildcards", typ)}
+		}
+		switch {
+		case name == "":
+			return "", &PatternError{Pattern: pattern, Reason: "unnamed parameters cannot be expressed as wildcards"}
+		case !validStdName(name):
+			return "", &PatternError{Pattern: pattern, Reason: fmt.Sprintf("parameter name %q is not a valid wildcard name", name)}
+		case names[name]:
+			return "", &PatternError{Pattern: pattern, Reason: fmt.Sprintf("duplicate parameter name %q", name)}
+		}
+		names[name] = true
+		if typ == typWild {
+			b.WriteString("{" + name + "...}")
+			continue
+		}
+		b.WriteString("{" + name + "}")
+	}
+	if hasSlash(pattern) {
+		b.WriteByte('/')
+		if !subtree {
+			b.WriteString("{$}")
+		}
+	}
+	return b.String(), nil
+}
+
+// validStdName reports whether name can be used as the name of a wildcard in an
+// http.ServeMux pattern.
+func validStdName(name string) bool {
+	for i, c := range name {
+		if !unicode.IsLetter(c) && c != '_' && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return name != ""
+}
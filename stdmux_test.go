@@ -0,0 +1,179 @@
+//go:build go1.22
+// +build go1.22
+
+// The module declares an older version of Go, so http.ServeMux must be told to
+// use the pattern syntax introduced in Go 1.22.
+//go:debug httpmuxgo121=0
+
+package mux_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var fromStdMuxTests = [...]struct {
+	method string
+	path   string
+	code   int
+}{
+	0: {method: http.MethodGet, path: "/users/12", code: 201},
+	1: {method: http.MethodPost, path: "/users", code: 202},
+	2: {method: http.MethodGet, path: "/files/a/b.txt", code: 203},
+	3: {method: http.MethodGet, path: "/static/css/site.css", code: 204},
+	4: {method: http.MethodGet, path: "/", code: 205},
+	5: {method: http.MethodGet, path: "/docs/", code: 206},
+	6: {method: http.MethodGet, path: "/docs/intro", code: http.StatusNotFound},
+}
+
+func TestFromStdMux(t *testing.T) {
+	users := &stdHandler{code: 201}
+	std := http.NewServeMux()
+	std.Handle("GET /users/{id}", users)
+	std.Handle("POST /users", codeHandler(t, 202))
+	std.Handle("GET /files/{p...}", codeHandler(t, 203))
+	std.Handle("GET /static/", codeHandler(t, 204))
+	std.Handle("GET /{$}", codeHandler(t, 205))
+	std.Handle("GET /docs/{$}", codeHandler(t, 206))
+	std.Handle("/legacy", failHandler(t))
+	std.Handle("example.com/", failHandler(t))
+
+	opts, err := mux.FromStdMux(std, []string{
+		"GET /users/{id}",
+		"POST /users",
+		"GET /files/{p...}",
+		"GET /static/",
+		"GET /{$}",
+		"GET /docs/{$}",
+		"/legacy",
+		"example.com/",
+		"DELETE /users/{id}",
+	})
+	var perr *mux.PatternError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Expected a pattern error, got %v", err)
+	}
+	for _, pattern := range []string{"/legacy", "example.com/", "DELETE /users/{id}"} {
+		if !containsPattern(err, pattern) {
+			t.Errorf("Expected an error for %q, got %v", pattern, err)
+		}
+	}
+
+	m := mux.New(opts...)
+	for i, tc := range fromStdMuxTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+		})
+	}
+
+	var found bool
+	for _, route := range m.Routes() {
+		if route.Pattern == "/users/{id string}" {
+			found = true
+			if route.Handler != users {
+				t.Errorf("Expected the handler to be preserved, got %v", route.Handler)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected /users/{id string} to be registered")
+	}
+}
+
+// stdHandler is a comparable handler so that tests can check handler
+// identity.
+type stdHandler struct {
+	code int
+}
+
+func (h *stdHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(h.code)
+}
+
+// containsPattern reports whether err wraps a *PatternError for pattern.
+func containsPattern(err error, pattern string) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			if containsPattern(err, pattern) {
+				return true
+			}
+		}
+		return false
+	}
+	var perr *mux.PatternError
+	return errors.As(err, &perr) && perr.Pattern == pattern
+}
+
+func TestStdPatterns(t *testing.T) {
+	h := http.NotFoundHandler()
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/", h),
+		mux.Handle(http.MethodGet, "/users/{id uint}", h),
+		mux.Handle(http.MethodPut, "/users/{id uint}", h),
+		mux.Handle(http.MethodGet, "/users/{id uint}/posts/{slug string}", h),
+		mux.Handle(http.MethodGet, "/users/{id uint}/files/{p path:int}", h),
+		mux.Handle(http.MethodGet, "/me", h, mux.Version("1")),
+		mux.Handle(http.MethodGet, "/me", h, mux.Version("2")),
+		mux.Subtree(http.MethodGet, "/static/", h),
+		mux.Handle(http.MethodGet, "/docs/", h),
+		mux.Handle(http.MethodGet, "/a b", h),
+		mux.Handle(http.MethodGet, "/report.{.format}", h),
+		mux.Handle(http.MethodGet, "/tags/{}", h),
+		mux.Handle(http.MethodPost, "/tags/{}", h),
+		mux.Handle(http.MethodGet, "/orgs/{org-id int}", h),
+	)
+	patterns, err := m.StdPatterns()
+	want := []string{
+		"GET /{$}",
+		"GET /a%20b",
+		"GET /docs/{$}",
+		"GET /me",
+		"GET /static/",
+		"GET /users/{id}",
+		"PUT /users/{id}",
+		"GET /users/{id}/files/{p...}",
+		"GET /users/{id}/posts/{slug}",
+	}
+	if strings.Join(patterns, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected patterns:\nwant=%q\ngot=%q", want, patterns)
+	}
+	for _, pattern := range []string{"/report.{.format}", "/tags/{}", "/orgs/{org-id int}"} {
+		if !containsPattern(err, pattern) {
+			t.Errorf("Expected an error for %q, got %v", pattern, err)
+		}
+	}
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 3 {
+		t.Errorf("Expected one error for each pattern, got %d: %v", n, err)
+	}
+
+	// Every pattern must be accepted by http.ServeMux and converted back to the
+	// same pattern by FromStdMux.
+	std := http.NewServeMux()
+	for _, pattern := range patterns {
+		std.Handle(pattern, h)
+	}
+	opts, err := mux.FromStdMux(std, patterns)
+	if err != nil {
+		t.Fatalf("Unexpected error importing patterns: %v", err)
+	}
+	roundTrip, err := mux.New(opts...).StdPatterns()
+	if err != nil {
+		t.Fatalf("Unexpected error exporting imported patterns: %v", err)
+	}
+	sort.Strings(patterns)
+	sort.Strings(roundTrip)
+	if strings.Join(roundTrip, "\n") != strings.Join(patterns, "\n") {
+		t.Errorf("Patterns changed after a round trip:\nwant=%q\ngot=%q", patterns, roundTrip)
+	}
+}
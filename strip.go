@@ -0,0 +1,58 @@
+package mux
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ctxOriginalURL is a type used as the context key when storing the URL of a
+// request before it was rewritten by StripParams.
+type ctxOriginalURL struct{}
+
+// StripParams returns a handler that serves requests by rewriting the request
+// path to the value of the named parameter of type path, with a leading slash,
+// and invoking h.
+// This allows handlers that expect paths relative to where they are mounted,
+// such as http.FileServer or other routers, to be registered with a pattern
+// ending in a wildcard (for example /static/{p path}).
+// Both r.URL.Path and r.URL.RawPath are rewritten so that the encoding of the
+// remainder of the path is preserved, and an empty remainder results in the
+// path /.
+//
+// The URL of the request before it was rewritten can be retrieved using
+// OriginalURL.
+// If the request does not have a parameter of type path with the given name,
+// StripParams replies with a 404 Not Found error.
+func StripParams(name string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pinfo := Param(r, name)
+		if pinfo.Type != typWild {
+			http.NotFound(w, r)
+			return
+		}
+		ctx := r.Context()
+		if _, ok := ctx.Value(ctxOriginalURL{}).(*url.URL); !ok {
+			ctx = context.WithValue(ctx, ctxOriginalURL{}, r.URL)
+		}
+		r2 := r.WithContext(ctx)
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = "/" + pinfo.Raw
+		r2.URL.RawPath = ""
+		if pinfo.Encoded != escapeComponent(pinfo.Raw, false) {
+			r2.URL.RawPath = "/" + pinfo.Encoded
+		}
+		h.ServeHTTP(w, r2)
+	})
+}
+
+// OriginalURL returns the URL of r before its path was rewritten by the first
+// handler returned from StripParams that it passed through.
+// If r was not rewritten, r.URL is returned.
+func OriginalURL(r *http.Request) *url.URL {
+	if u, ok := r.Context().Value(ctxOriginalURL{}).(*url.URL); ok {
+		return u
+	}
+	return r.URL
+}
@@ -0,0 +1,62 @@
+package mux_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var stripParamsTests = [...]struct {
+	path string
+	want string
+}{
+	0: {path: "/mount/a/b.txt", want: "/a/b.txt /a/b.txt /mount/a/b.txt"},
+	1: {path: "/mount/a%2Fb/c%20d", want: "/a/b/c d /a%2Fb/c%20d /mount/a%2Fb/c%20d"},
+	2: {path: "/mount/dir/", want: "/dir/ /dir/ /mount/dir/"},
+	3: {path: "/mount/", want: "/ / /mount/"},
+	4: {path: "/mount/%E2%9C%93", want: "/✓ /%E2%9C%93 /mount/%E2%9C%93"},
+}
+
+func TestStripParams(t *testing.T) {
+	h := mux.StripParams("p", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s %s", r.URL.Path, r.URL.EscapedPath(), mux.OriginalURL(r).EscapedPath())
+	}))
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/mount/{p path}", h),
+		mux.EmptyWildcards(true),
+	)
+	for i, tc := range stripParamsTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if body := rec.Body.String(); body != tc.want {
+				t.Errorf("Unexpected paths: want=%q, got=%q", tc.want, body)
+			}
+		})
+	}
+}
+
+func TestStripParamsMissing(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/user/{p string}", mux.StripParams("p", failHandler(t))),
+		mux.Handle(http.MethodGet, "/files/{p path}", mux.StripParams("q", failHandler(t))),
+	)
+	for _, path := range []string{"/user/me", "/files/a"} {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Unexpected code for %s: want=%d, got=%d", path, http.StatusNotFound, rec.Code)
+		}
+	}
+}
+
+func TestOriginalURL(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/a", nil)
+	if u := mux.OriginalURL(req); u != req.URL {
+		t.Errorf("Expected the URL of a request that was not rewritten, got %v", u)
+	}
+}
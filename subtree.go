@@ -0,0 +1,72 @@
+package mux
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Subtree registers the handler for every path below the given pattern, which
+// must end in a slash.
+// For example, the pattern /images/ matches /images/, /images/logo.png, and
+// /images/icons/new.svg.
+// The pattern may contain route parameters, but it may not end in a wildcard.
+//
+// Routes registered below a subtree take precedence over it: if
+// /images/{id uint} is also registered, a request for /images/1 is routed to
+// that handler while a request for /images/logo.png is still routed to the
+// subtree.
+// The part of the path below the subtree can be retrieved using Remainder.
+//
+// If a subtree handler already exists for pattern and method, Subtree panics.
+func Subtree(method, r string, h http.Handler, opts ...RouteOption) Option {
+	if !validMethod(method) {
+		panic(&MethodError{Method: method, Pattern: r})
+	}
+	method = strings.ToUpper(method)
+	if err := ValidatePattern(r); err != nil {
+		panic(err)
+	}
+	if !hasSlash(r) {
+		panic(&PatternError{Pattern: r, Reason: "subtree patterns must end in a slash"})
+	}
+	r = r[1:]
+	segments := parsePattern(r)
+	if len(segments) > 0 && segments[len(segments)-1].typ == typWild {
+		panic(&PatternError{Pattern: "/" + r, Reason: "subtree patterns must not end in a wildcard"})
+	}
+
+	ep := &endpoint{
+		handler:  h,
+		route:    r,
+		segments: segments,
+		subtree:  true,
+	}
+	for _, o := range opts {
+		o(ep)
+	}
+
+	return func(mux *ServeMux) {
+		mux.update(func(root *node) {
+			register(root, []string{method}, r, ep)
+		})
+		mux.didRegister([]string{method}, "/"+r, h)
+	}
+}
+
+// Remainder returns the part of the request path below the subtree that r was
+// routed to, including the leading slash.
+// For example, if a handler was registered using Subtree for /images/, a
+// request for /images/icons/new.svg has the remainder /icons/new.svg and a
+// request for /images/ has the remainder /.
+// If the ServeMux was configured with UseEscapedPath, the remainder is
+// escaped.
+//
+// If r was not routed to a handler registered using Subtree, Remainder returns
+// the empty string.
+func Remainder(r *http.Request) string {
+	rc := routeFrom(r)
+	if rc == nil || rc.ep == nil || !rc.ep.subtree {
+		return ""
+	}
+	return "/" + rc.rest
+}
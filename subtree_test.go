@@ -0,0 +1,168 @@
+package mux_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+func remainderHandler(code int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+		fmt.Fprint(w, mux.Remainder(r))
+	}
+}
+
+var subtreeTests = [...]struct {
+	method    string
+	path      string
+	code      int
+	remainder string
+}{
+	0:  {method: "GET", path: "/images/", code: 201, remainder: "/"},
+	1:  {method: "GET", path: "/images/logo.png", code: 201, remainder: "/logo.png"},
+	2:  {method: "GET", path: "/images/icons/new.svg", code: 201, remainder: "/icons/new.svg"},
+	3:  {method: "GET", path: "/images/1", code: 202},
+	4:  {method: "GET", path: "/images/1/", code: 201, remainder: "/1/"},
+	5:  {method: "GET", path: "/images", code: 201, remainder: "/images"},
+	6:  {method: "GET", path: "/docs/a/b", code: 203},
+	7:  {method: "GET", path: "/docs/a/c", code: 201, remainder: "/docs/a/c"},
+	8:  {method: "GET", path: "/docs/a", code: 201, remainder: "/docs/a"},
+	9:  {method: "POST", path: "/docs/a/b", code: 405},
+	10: {method: "GET", path: "/users/5/files/a/b", code: 204, remainder: "/a/b"},
+	11: {method: "GET", path: "/users/x/files/a", code: 201, remainder: "/users/x/files/a"},
+	12: {method: "GET", path: "/", code: 201, remainder: "/"},
+	13: {method: "POST", path: "/images/logo.png", code: 405},
+}
+
+func TestSubtree(t *testing.T) {
+	m := mux.New(
+		mux.Subtree("GET", "/", remainderHandler(201)),
+		mux.Subtree("GET", "/images/", remainderHandler(201)),
+		mux.Handle("GET", "/images/{id uint}", remainderHandler(202)),
+		mux.Handle("GET", "/docs/a/b", remainderHandler(203)),
+		mux.Subtree("GET", "/users/{id uint}/files/", remainderHandler(204)),
+	)
+	for i, tc := range subtreeTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected code for %s %s: want=%d, got=%d", tc.method, tc.path, tc.code, rec.Code)
+			}
+			if tc.code >= 200 && tc.code < 300 {
+				if body := rec.Body.String(); body != tc.remainder {
+					t.Errorf("Unexpected remainder: want=%q, got=%q", tc.remainder, body)
+				}
+			}
+		})
+	}
+}
+
+func TestSubtreeParams(t *testing.T) {
+	var id mux.ParamInfo
+	var p string
+	var err error
+	m := mux.New(
+		mux.Subtree("GET", "/users/{id uint}/files/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id = mux.Param(r, "id")
+			p, err = mux.Path(r)
+		})),
+	)
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/5/files/a/b", nil))
+	if id.Value != uint64(5) {
+		t.Errorf("Unexpected parameter value: want=%d, got=%v", 5, id.Value)
+	}
+	if err != nil {
+		t.Fatalf("Unexpected error from Path: %v", err)
+	}
+	if p != "/users/5/files/a/b" {
+		t.Errorf("Unexpected path: want=%q, got=%q", "/users/5/files/a/b", p)
+	}
+}
+
+func TestSubtreeRoutes(t *testing.T) {
+	m := mux.New(
+		mux.Handle("GET", "/images/", failHandler(t)),
+		mux.Subtree("GET", "/images/", failHandler(t)),
+		mux.Options(nil),
+	)
+	routes := m.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("Unexpected number of routes: want=2, got=%d", len(routes))
+	}
+	var subtrees int
+	for _, route := range routes {
+		if route.Subtree {
+			subtrees++
+		}
+	}
+	if subtrees != 1 {
+		t.Errorf("Unexpected number of subtree routes: want=1, got=%d", subtrees)
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("HEAD", "/images/", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected exact route to take precedence over subtree: want=%d, got=%d", http.StatusMethodNotAllowed, rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/images", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected subtree not to match its parent: want=%d, got=%d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestSubtreeInvalid(t *testing.T) {
+	for _, pattern := range []string{"/images", "/files/{p path}/", "images/", "//"} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("Expected Subtree to panic for %q", pattern)
+				}
+			}()
+			mux.Subtree("GET", pattern, failHandler(t))
+		}()
+	}
+}
+
+func TestSubtreePriority(t *testing.T) {
+	m := mux.New(
+		mux.Subtree("GET", "/images/", remainderHandler(201), mux.Priority(1)),
+		mux.Handle("GET", "/images/{id uint}", remainderHandler(202)),
+		mux.Handle("POST", "/images/{id uint}", remainderHandler(203), mux.Priority(2)),
+		mux.Subtree("GET", "/docs/", remainderHandler(204)),
+		mux.Handle("GET", "/docs/{id uint}", remainderHandler(205), mux.Priority(-1)),
+	)
+	for _, tc := range []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{method: "GET", path: "/images/1", code: 201},
+		{method: "GET", path: "/images/logo.png", code: 201},
+		{method: "POST", path: "/images/1", code: 203},
+		{method: "GET", path: "/docs/1", code: 204},
+	} {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != tc.code {
+			t.Errorf("Unexpected code for %s %s: want=%d, got=%d", tc.method, tc.path, tc.code, rec.Code)
+		}
+	}
+
+	const want = "the enclosing subtree has priority 1, which is higher than 0"
+	if s := m.Explain("GET", "/images/1").String(); !strings.Contains(s, want) {
+		t.Errorf("Expected explanation to contain %q, got:\n%s", want, s)
+	}
+	for _, route := range m.Routes() {
+		if route.Pattern == "/images/" && route.Priority != 1 {
+			t.Errorf("Unexpected priority for %s %s: want=1, got=%d", route.Method, route.Pattern, route.Priority)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package mux
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Limits on the work done by SuggestingNotFound for each request so that a
+// long path cannot make it expensive.
+const (
+	// maxSuggestParts is the number of path components that are compared to
+	// the routes.
+	maxSuggestParts = 16
+	// maxSuggestPartLen is the number of bytes of each path component that are
+	// compared to the static components of the routes.
+	maxSuggestPartLen = 32
+)
+
+// SuggestingNotFound returns a NotFound handler for use during development
+// that lists up to maxSuggestions of the registered patterns that are most
+// similar to the request path, for example to suggest /docs/installation for a
+// request for /docs/instalation.
+// Static components of the patterns are compared to the path using their edit
+// distance while route parameters match any component that can be parsed as
+// their type.
+// The list is written as HTML if the Accept header prefers it, or as plain text
+// otherwise.
+//
+// Because the suggestions reveal the routes that are registered, the handler
+// must be explicitly configured using the NotFound option and should not be
+// used in production.
+// Only the first components of long paths are compared to the routes.
+func SuggestingNotFound(maxSuggestions int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var suggestions []string
+		var nearest NearestMatch
+		var ok bool
+		if info := notFoundFrom(r); info != nil {
+			suggestions = suggest(info.root, r.URL.EscapedPath(), maxSuggestions)
+			nearest, ok = info.nearest, info.ok
+		}
+
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		accept := r.Header.Get("Accept")
+		if accept != "" && quality(accept, "text/html") > quality(accept, "text/plain") {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusNotFound)
+			renderSuggestionsHTML(w, suggestions)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, "404 page not found\n")
+		if ok {
+			fmt.Fprintf(w, "\nMatched %s before failing: %s\n", nearest.Prefix, nearest.Reason)
+		}
+		if len(suggestions) > 0 {
+			io.WriteString(w, "\nDid you mean:\n")
+			for _, s := range suggestions {
+				fmt.Fprintf(w, "\t%s\n", s)
+			}
+		}
+	})
+}
+
+func renderSuggestionsHTML(w io.Writer, suggestions []string) {
+	io.WriteString(w, "<!DOCTYPE html>\n<html><head><title>404 page not found</title></head>\n<body>\n<h1>404 page not found</h1>\n")
+	if len(suggestions) > 0 {
+		io.WriteString(w, "<p>Did you mean:</p>\n<ul>\n")
+		for _, s := range suggestions {
+			fmt.Fprintf(w, "<li>%s</li>\n", html.EscapeString(s))
+		}
+		io.WriteString(w, "</ul>\n")
+	}
+	io.WriteString(w, "</body></html>\n")
+}
+
+// suggest returns up to max of the patterns registered on root that are most
+// similar to path, most similar first.
+// Patterns that differ from path by more than half of its length are never
+// suggested.
+func suggest(root *node, path string, max int) []string {
+	if max <= 0 {
+		return nil
+	}
+	var parts []string
+	var size int
+	for part, remain := nextPart(strings.TrimPrefix(path, "/")); part != "" && len(parts) < maxSuggestParts; part, remain = nextPart(remain) {
+		if len(part) > maxSuggestPartLen {
+			part = part[:maxSuggestPartLen]
+		}
+		parts = append(parts, part)
+		size += len(part)
+	}
+
+	type candidate struct {
+		pattern string
+		score   int
+	}
+	var routes []RouteInfo
+	root.routes(&routes)
+	seen := make(map[string]bool, len(routes))
+	var candidates []candidate
+	for _, route := range routes {
+		if seen[route.Pattern] {
+			continue
+		}
+		seen[route.Pattern] = true
+		score := suggestScore(parsePattern(route.Pattern[1:]), parts, route.Subtree)
+		if score*2 > size {
+			continue
+		}
+		candidates = append(candidates, candidate{pattern: route.Pattern, score: score})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score < candidates[j].score
+		}
+		return candidates[i].pattern < candidates[j].pattern
+	})
+	if len(candidates) > max {
+		candidates = candidates[:max]
+	}
+	suggestions := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		suggestions = append(suggestions, c.pattern)
+	}
+	return suggestions
+}
+
+// suggestScore returns how different the components of a path are from the
+// segments of a route: the edit distance of each static segment from the
+// corresponding component, 1 for each parameter that the component cannot be
+// parsed as, and the length of any components or static segments that only
+// one of them has.
+// If subtree is true, any components after the segments match.
+func suggestScore(segments []segment, parts []string, subtree bool) int {
+	var score int
+	for i, seg := range segments {
+		switch {
+		case seg.typ == typWild && i < len(parts):
+			return score
+		case i >= len(parts) && seg.typ == typStatic:
+			score += len(seg.name)
+		case i >= len(parts):
+			score++
+		case seg.typ == typStatic:
+			score += editDistance(seg.name, parts[i])
+		case !validParam(seg.typ, parts[i]):
+			score++
+		}
+	}
+	if subtree {
+		return score
+	}
+	for i := len(segments); i < len(parts); i++ {
+		score += len(parts[i])
+	}
+	return score
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,62 @@
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var suggestTests = [...]struct {
+	path    string
+	accept  string
+	want    []string
+	exclude []string
+}{
+	0: {path: "/docs/instalation", want: []string{"Did you mean:\n\t/docs/installation\n", "Matched /docs before failing: no_child"}},
+	1: {path: "/user/12/setings", want: []string{"\t/user/{id uint}/settings\n"}, exclude: []string{"/docs"}},
+	2: {path: "/completely/unrelated", exclude: []string{"Did you mean"}},
+	3: {path: "/docs/instalation", accept: "text/html", want: []string{"<li>/docs/installation</li>"}},
+	4: {path: "/docs/instalation/" + strings.Repeat("a/", 10000), exclude: []string{"Did you mean"}},
+	5: {path: "/dcos/guides/1", want: []string{"\t/docs/guides/{id uint}\n"}, exclude: []string{"/docs/installation"}},
+}
+
+func TestSuggestingNotFound(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/docs/installation", failHandler(t)),
+		mux.Handle(http.MethodGet, "/docs/guides/{id uint}", failHandler(t)),
+		mux.Handle(http.MethodGet, "/user/{id uint}/settings", failHandler(t)),
+		mux.Handle(http.MethodPost, "/user/{id uint}/settings", failHandler(t)),
+		mux.NotFound(mux.SuggestingNotFound(2)),
+	)
+	for i, tc := range suggestTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, req)
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("Unexpected status code: want=%d, got=%d", http.StatusNotFound, rec.Code)
+			}
+			body := rec.Body.String()
+			for _, want := range tc.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("Expected body to contain %q, got:\n%s", want, body)
+				}
+			}
+			for _, exclude := range tc.exclude {
+				if strings.Contains(body, exclude) {
+					t.Errorf("Did not expect body to contain %q, got:\n%s", exclude, body)
+				}
+			}
+			if strings.Count(body, "/user/{id uint}/settings") > 1 {
+				t.Errorf("Expected each pattern to be suggested once, got:\n%s", body)
+			}
+		})
+	}
+}
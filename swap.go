@@ -0,0 +1,41 @@
+package mux
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Swapper is an HTTP handler that dispatches requests to a ServeMux that can be
+// replaced atomically while requests are being served, for example when routes
+// are reloaded from a configuration source.
+// Requests are routed entirely by the ServeMux that was stored when they began:
+// in-flight requests never observe a ServeMux stored after they started.
+type Swapper struct {
+	mux atomic.Value
+}
+
+// NewSwapper returns a Swapper that dispatches requests to mux.
+func NewSwapper(mux *ServeMux) *Swapper {
+	s := &Swapper{}
+	s.Store(mux)
+	return s
+}
+
+// Store replaces the ServeMux used for new requests.
+// If mux is nil, Store panics.
+func (s *Swapper) Store(mux *ServeMux) {
+	if mux == nil {
+		panic("mux: Store called with nil ServeMux")
+	}
+	s.mux.Store(mux)
+}
+
+// Load returns the ServeMux currently used for new requests.
+func (s *Swapper) Load() *ServeMux {
+	return s.mux.Load().(*ServeMux)
+}
+
+// ServeHTTP dispatches the request to the current ServeMux.
+func (s *Swapper) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Load().ServeHTTP(w, r)
+}
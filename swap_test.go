@@ -0,0 +1,62 @@
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+func TestSwapper(t *testing.T) {
+	first := mux.New(mux.Handle(http.MethodGet, "/", codeHandler(t, 201)))
+	s := mux.NewSwapper(first)
+
+	serve := func() int {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Code
+	}
+	if code := serve(); code != 201 {
+		t.Errorf("Unexpected code before swap: want=201, got=%d", code)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if code := serve(); code != 201 && code != 202 {
+					t.Errorf("Unexpected code during swap: %d", code)
+					return
+				}
+			}
+		}()
+	}
+	second := mux.New(mux.Handle(http.MethodGet, "/", codeHandler(t, 202)))
+	for i := 0; i < 50; i++ {
+		if i%2 == 0 {
+			s.Store(second)
+		} else {
+			s.Store(first)
+		}
+	}
+	s.Store(second)
+	close(done)
+	wg.Wait()
+
+	if s.Load() != second {
+		t.Errorf("Expected Load to return the last stored ServeMux")
+	}
+	if code := serve(); code != 202 {
+		t.Errorf("Unexpected code after swap: want=202, got=%d", code)
+	}
+}
@@ -0,0 +1,24 @@
+package mux
+
+import (
+	"html/template"
+)
+
+// TemplateFuncs returns functions for use in templates that render the paths
+// of routes registered on mux.
+//
+// The function "url" takes the name of a route, which was set using the Name
+// option, followed by pairs of parameter names and values and renders the
+// escaped path in the same way as PathFor, for example:
+//
+//	<a href="{{ url "user_edit" "id" .User.ID }}">Edit</a>
+//
+// If the path cannot be rendered, for example because a parameter is missing
+// or has a value of the wrong type, execution of the template stops with an
+// error.
+// The functions may be used by templates that are executed concurrently.
+func TemplateFuncs(mux *ServeMux) template.FuncMap {
+	return template.FuncMap{
+		"url": mux.PathFor,
+	}
+}
@@ -0,0 +1,76 @@
+package mux
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RequireTLS causes requests that match the route but were not made over TLS
+// to be rejected before the handler is called.
+// Requests that use a safe method (GET, HEAD, OPTIONS, or TRACE) are
+// redirected to the same URL with the https scheme and the given status code.
+// Requests that use any other method receive a 403 Forbidden response instead
+// since the request body has already been sent without encryption.
+// If code is 0, http.StatusPermanentRedirect is used.
+//
+// By default only the TLS field of the request is checked.
+// If TLS is terminated by a proxy, TrustForwardedProto can be used to trust a
+// header set by the proxy instead.
+func RequireTLS(code int) RouteOption {
+	if code == 0 {
+		code = http.StatusPermanentRedirect
+	}
+	return func(ep *endpoint) {
+		ep.tlsCode = code
+	}
+}
+
+// TrustForwardedProto causes requests where the named header (normally
+// X-Forwarded-Proto) is "https" to be treated as though they were made over TLS
+// by routes registered with RequireTLS.
+// Clients can set any header they like, so this must only be used if every
+// request passes through a proxy that overwrites the header.
+// If header is empty, no header is trusted, which is the default.
+func TrustForwardedProto(header string) Option {
+	return func(mux *ServeMux) {
+		mux.protoHeader = header
+	}
+}
+
+// isTLS reports whether r was made over TLS, either directly or according to
+// the trusted protocol header.
+func (mux *ServeMux) isTLS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return mux.protoHeader != "" && strings.EqualFold(r.Header.Get(mux.protoHeader), "https")
+}
+
+// insecure returns the handler to use for a request to a route that requires
+// TLS when r was not made over TLS and reports whether it is a redirect.
+func (mux *ServeMux) insecure(r *http.Request, code int) (http.Handler, bool) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+	default:
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		}), false
+	}
+
+	u := *r.URL
+	u.Scheme = "https"
+	u.Host = r.Host
+	if u.Host == "" {
+		u.Host = r.URL.Host
+	}
+	// The port used for plain HTTP is never the one used for HTTPS, so fall back
+	// to the default.
+	if host, _, err := net.SplitHostPort(u.Host); err == nil {
+		u.Host = host
+		if strings.Contains(host, ":") {
+			u.Host = "[" + host + "]"
+		}
+	}
+	return http.RedirectHandler(u.String(), code), true
+}
@@ -0,0 +1,72 @@
+package mux_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var requireTLSTests = [...]struct {
+	method   string
+	url      string
+	tls      bool
+	proto    string
+	code     int
+	location string
+}{
+	0: {method: http.MethodGet, url: "http://example.com/login?next=%2F", code: http.StatusFound, location: "https://example.com/login?next=%2F"},
+	1: {method: http.MethodGet, url: "http://example.com:8080/login", code: http.StatusFound, location: "https://example.com/login"},
+	2: {method: http.MethodPost, url: "http://example.com/login", code: http.StatusForbidden},
+	3: {method: http.MethodGet, url: "https://example.com/login", tls: true, code: testCode},
+	4: {method: http.MethodPost, url: "https://example.com/login", tls: true, code: testCode},
+	5: {method: http.MethodGet, url: "http://example.com/login", proto: "https", code: testCode},
+	6: {method: http.MethodPost, url: "http://example.com/login", proto: "http", code: http.StatusForbidden},
+	7: {method: http.MethodGet, url: "http://example.com/", code: testCode},
+}
+
+func TestRequireTLS(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/", codeHandler(t, testCode)),
+		mux.Handle(http.MethodGet, "/login", codeHandler(t, testCode), mux.RequireTLS(http.StatusFound)),
+		mux.Handle(http.MethodPost, "/login", codeHandler(t, testCode), mux.RequireTLS(http.StatusFound)),
+		mux.TrustForwardedProto("X-Forwarded-Proto"),
+	)
+	for i, tc := range requireTLSTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, nil)
+			if !tc.tls {
+				req.TLS = nil
+			} else if req.TLS == nil {
+				req.TLS = &tls.ConnectionState{}
+			}
+			if tc.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tc.proto)
+			}
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, req)
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+			if loc := rec.Header().Get("Location"); loc != tc.location {
+				t.Errorf("Unexpected location: want=%q, got=%q", tc.location, loc)
+			}
+		})
+	}
+}
+
+func TestRequireTLSUntrustedProto(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodPost, "/login", failHandler(t), mux.RequireTLS(0)),
+	)
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Unexpected status code: want=%d, got=%d", http.StatusForbidden, rec.Code)
+	}
+}
@@ -0,0 +1,101 @@
+package mux
+
+import (
+	"net/http"
+)
+
+// Version registers the route for a single version of an API.
+// The same method and pattern may be registered several times as long as each
+// registration has a distinct version, and the version used for each request
+// is selected using the function configured with VersionFunc.
+// A route registered without a version handles every version and may not be
+// registered alongside versioned routes for the same method and pattern.
+func Version(v string) RouteOption {
+	return func(ep *endpoint) {
+		ep.version = v
+	}
+}
+
+// VersionFunc sets the function used to determine the API version requested by
+// r when it matches a route that was registered using Version.
+// If f returns the empty string, the version configured with DefaultVersion is
+// used.
+// By default the value of the Accept-Version header is used.
+func VersionFunc(f func(r *http.Request) string) Option {
+	return func(mux *ServeMux) {
+		if f == nil {
+			f = acceptVersion
+		}
+		mux.versionFunc = f
+	}
+}
+
+// DefaultVersion sets the version used for requests that do not specify one.
+func DefaultVersion(v string) Option {
+	return func(mux *ServeMux) {
+		mux.defaultVersion = v
+	}
+}
+
+// VersionNotAcceptable sets the handler to call when a request matches a route
+// that was registered using Version, but not for the version that was
+// requested.
+//
+// By default, http.Error with http.StatusNotAcceptable is used.
+func VersionNotAcceptable(h http.Handler) Option {
+	return func(mux *ServeMux) {
+		mux.notAcceptable = h
+	}
+}
+
+// acceptVersion is the default version function.
+func acceptVersion(r *http.Request) string {
+	return r.Header.Get("Accept-Version")
+}
+
+// versionOf returns the API version requested by r.
+func (mux *ServeMux) versionOf(r *http.Request) string {
+	if v := mux.versionFunc(r); v != "" {
+		return v
+	}
+	return mux.defaultVersion
+}
+
+// selectVariant replaces the endpoint of res, which was registered using
+// Version or Consumes, with the one for the given method, version, and media
+// type.
+// If there is no endpoint for the version the not acceptable handler is used,
+// and if there is none for the media type the unsupported media type handler
+// is used.
+// Endpoints that are disabled for r are skipped.
+func (mux *ServeMux) selectVariant(res result, method, version, mediaType string, r *http.Request) result {
+	head := false
+	if _, ok := res.node.handlers.enabled(method, r); !ok {
+		// HEAD requests may be handled by the GET endpoint.
+		method, head = http.MethodGet, true
+	}
+	var ep *endpoint
+	res.h = mux.notAcceptable
+	for _, h := range res.node.handlers {
+		if h.method != method || h.ep.version != "" && h.ep.version != version || h.ep.disabled(r) {
+			continue
+		}
+		res.h = mux.unsupported
+		if h.ep.consumes == "" || h.ep.consumes == mediaType {
+			ep = h.ep
+			break
+		}
+	}
+	if ep == nil {
+		res.ep = nil
+		return res
+	}
+	if !ep.allows(res.params) {
+		return result{kind: kindMiss}
+	}
+	res.ep, res.h = ep, ep.handler
+	if head {
+		res.h = headHandler(ep.handler)
+	}
+	return res
+}
@@ -0,0 +1,103 @@
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"code.soquee.net/mux"
+)
+
+var versionTests = [...]struct {
+	method  string
+	path    string
+	version string
+	code    int
+}{
+	0: {method: http.MethodGet, path: "/things/1", version: "1", code: 201},
+	1: {method: http.MethodGet, path: "/things/1", version: "2", code: 202},
+	2: {method: http.MethodGet, path: "/things/1", code: 201},
+	3: {method: http.MethodGet, path: "/things/1", version: "3", code: http.StatusNotAcceptable},
+	4: {method: http.MethodHead, path: "/things/1", version: "2", code: 202},
+	5: {method: http.MethodPost, path: "/things/1", version: "3", code: 203},
+	6: {method: http.MethodDelete, path: "/things/1", version: "1", code: http.StatusMethodNotAllowed},
+	7: {method: http.MethodGet, path: "/things/x", version: "1", code: http.StatusNotFound},
+}
+
+func TestVersion(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/things/{id uint}", codeHandler(t, 201), mux.Version("1")),
+		mux.Handle(http.MethodGet, "/things/{id uint}", codeHandler(t, 202), mux.Version("2")),
+		mux.Handle(http.MethodPost, "/things/{id uint}", codeHandler(t, 203)),
+		mux.DefaultVersion("1"),
+		mux.AutoHead(true),
+	)
+	for i, tc := range versionTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.version != "" {
+				req.Header.Set("Accept-Version", tc.version)
+			}
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, req)
+			if rec.Code != tc.code {
+				t.Errorf("Unexpected status code: want=%d, got=%d", tc.code, rec.Code)
+			}
+		})
+	}
+
+	routes := m.Routes()
+	if len(routes) != 3 || routes[0].Version != "1" || routes[1].Version != "2" || routes[2].Version != "" {
+		t.Errorf("Unexpected routes: %+v", routes)
+	}
+	if h, _, _, ok := m.Match(http.MethodGet, "/things/1"); !ok || h == nil {
+		t.Errorf("Expected default version to match")
+	}
+}
+
+func TestVersionFunc(t *testing.T) {
+	m := mux.New(
+		mux.Handle(http.MethodGet, "/things", codeHandler(t, 201), mux.Version("1")),
+		mux.Handle(http.MethodGet, "/things", codeHandler(t, 202), mux.Version("2")),
+		mux.VersionFunc(func(r *http.Request) string {
+			accept := r.Header.Get("Accept")
+			accept = strings.TrimPrefix(accept, "application/vnd.foo.v")
+			return strings.TrimSuffix(accept, "+json")
+		}),
+		mux.VersionNotAcceptable(codeHandler(t, http.StatusBadRequest)),
+	)
+	for _, tc := range []struct {
+		accept string
+		code   int
+	}{
+		{accept: "application/vnd.foo.v1+json", code: 201},
+		{accept: "application/vnd.foo.v2+json", code: 202},
+		{accept: "", code: http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/things", nil)
+		req.Header.Set("Accept", tc.accept)
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("Unexpected status code for %q: want=%d, got=%d", tc.accept, tc.code, rec.Code)
+		}
+	}
+}
+
+func TestVersionConflict(t *testing.T) {
+	for i, versions := range [][2]string{{"1", "1"}, {"", "1"}, {"1", ""}, {"", ""}} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("Expected registering versions %q to panic", versions)
+				}
+			}()
+			mux.New(
+				mux.Handle(http.MethodGet, "/things", failHandler(t), mux.Version(versions[0])),
+				mux.Handle(http.MethodGet, "/things", failHandler(t), mux.Version(versions[1])),
+			)
+		})
+	}
+}